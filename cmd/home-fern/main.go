@@ -6,11 +6,14 @@ import (
 	"net/http"
 	"os"
 
+	"home-fern/internal/acme"
 	"home-fern/internal/awslib"
 	"home-fern/internal/core"
 	"home-fern/internal/datastore"
 	"home-fern/internal/dbfcns"
+	"home-fern/internal/domains"
 	"home-fern/internal/kms"
+	"home-fern/internal/resolver"
 	"home-fern/internal/route53"
 	"home-fern/internal/ssm"
 	"home-fern/internal/tfstate"
@@ -28,6 +31,8 @@ func main() {
 		flag.String("data-path", ".home-fern-data", "Path to data store folder.")
 	listenAddrPtr :=
 		flag.String("listen-addr", ":9080", "Address and port to listen on.")
+	dnsAddrPtr :=
+		flag.String("dns-addr", "", "Address and port for the DNS server; disabled when empty.")
 	webPathPtr :=
 		flag.String("web-path", "./web/dist/home-fern-web/browser", "Path to web files.")
 	flag.Parse()
@@ -52,7 +57,7 @@ func main() {
 	}
 	kmsCredentials := awslib.NewCredentialsProvider(awslib.ServiceKms, fernConfig.Region, credentials)
 
-	kmssvc := kms.NewService(fernConfig.Keys, fernConfig.Region, core.ZeroAccountId)
+	kmssvc := kms.NewService(fernConfig.Keys, fernConfig.KmsRootKey, fernConfig.Region, core.ZeroAccountId, ds)
 
 	kmsApi := kms.NewKmsApi(kmssvc, kmsCredentials)
 
@@ -62,19 +67,39 @@ func main() {
 
 	ssmApi := ssm.NewParameterApi(ssmsvc, ssmCredentials)
 
-	r53svc := route53.NewService(&fernConfig.DnsDefaults, ds)
+	r53svc := route53.NewService(&fernConfig.DnsDefaults, ds, kmssvc)
 
 	route53Credentials := awslib.NewCredentialsProvider(awslib.ServiceRoute53, fernConfig.Region, credentials)
 
 	route53Api := route53.NewRoute53Api(r53svc, route53Credentials)
 
+	resolversvc := resolver.NewService(fernConfig, core.ZeroAccountId, ds)
+
+	resolverCredentials := awslib.NewCredentialsProvider(awslib.ServiceRoute53Resolver, fernConfig.Region, credentials)
+
+	resolverApi := resolver.NewResolverApi(resolversvc, resolverCredentials)
+
+	domainssvc := domains.NewService(fernConfig, ds, r53svc)
+
+	domainsCredentials := awslib.NewCredentialsProvider(awslib.ServiceRoute53Domains, fernConfig.Region, credentials)
+
+	domainsApi := domains.NewDomainsApi(domainssvc, domainsCredentials)
+
+	acmesvc := acme.NewService(&fernConfig.DnsDefaults.Acme, ds, r53svc)
+
+	acmeApi := acme.NewAcmeApi(acmesvc)
+
 	basicProvider := core.NewBasicCredentialsProvider(fernConfig.Region, fernConfig.Credentials)
 
 	stateApi := tfstate.NewStateApi(*dataPathPtr + "/tfstate")
 
 	var dbApi = dbfcns.Api{
+		Kms:         kmssvc,
 		Ssm:         ssmsvc,
 		Route53:     r53svc,
+		Resolver:    resolversvc,
+		Domains:     domainssvc,
+		Acme:        acmesvc,
 		TfState:     stateApi,
 		Credentials: basicProvider,
 	}
@@ -84,6 +109,12 @@ func main() {
 	// DB Functions
 	router.HandleFunc("/db/keys/{service}",
 		basicProvider.WithBasicAuth(dbApi.Keys)).Methods("GET")
+	router.HandleFunc("/db/export/route53/{zoneId}.zone",
+		basicProvider.WithBasicAuth(dbApi.ExportZoneFile)).Methods("GET")
+	router.HandleFunc("/db/route53/{zoneId}/changes",
+		basicProvider.WithBasicAuth(dbApi.ZoneChanges)).Methods("GET")
+	router.HandleFunc("/db/route53/{zoneId}/rollback/{changeId}",
+		basicProvider.WithBasicAuth(dbApi.RollbackZone)).Methods("POST")
 	router.HandleFunc("/db/export/{service}",
 		basicProvider.WithBasicAuth(dbApi.Export)).Methods("GET")
 	router.HandleFunc("/db/import/{service}",
@@ -100,6 +131,18 @@ func main() {
 	// Route53
 	router.HandleFunc("/route53/2013-04-01/hostedzonesbyname",
 		route53Credentials.WithSigV4(route53Api.ListHostedZonesByName)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/hostedzonesbyvpc",
+		route53Credentials.WithSigV4(route53Api.ListHostedZonesByVPC)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/hostedzone/{id}/associatevpc",
+		route53Credentials.WithSigV4(route53Api.AssociateVPCWithHostedZone)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/hostedzone/{id}/disassociatevpc",
+		route53Credentials.WithSigV4(route53Api.DisassociateVPCFromHostedZone)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/hostedzone/{id}/dnssec",
+		route53Credentials.WithSigV4(route53Api.GetDNSSEC)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/hostedzone/{id}/enable-dnssec",
+		route53Credentials.WithSigV4(route53Api.EnableHostedZoneDNSSEC)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/hostedzone/{id}/disable-dnssec",
+		route53Credentials.WithSigV4(route53Api.DisableHostedZoneDNSSEC)).Methods("POST")
 	router.HandleFunc("/route53/2013-04-01/hostedzone/{id}/rrset",
 		route53Credentials.WithSigV4(route53Api.ListResourceRecordSets)).Methods("GET")
 	router.HandleFunc("/route53/2013-04-01/hostedzone/{id}/rrset{slash:/?}",
@@ -118,11 +161,107 @@ func main() {
 		route53Credentials.WithSigV4(route53Api.GetHostedZoneCount)).Methods("GET")
 	router.HandleFunc("/route53/2013-04-01/change/{id}",
 		route53Credentials.WithSigV4(route53Api.GetChange)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/healthcheck/{id}/status",
+		route53Credentials.WithSigV4(route53Api.GetHealthCheckStatus)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/healthcheck/{id}",
+		route53Credentials.WithSigV4(route53Api.UpdateHealthCheck)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/healthcheck/{id}",
+		route53Credentials.WithSigV4(route53Api.DeleteHealthCheck)).Methods("DELETE")
+	router.HandleFunc("/route53/2013-04-01/healthcheck/{id}",
+		route53Credentials.WithSigV4(route53Api.GetHealthCheck)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/healthcheck{slash:/?}",
+		route53Credentials.WithSigV4(route53Api.CreateHealthCheck)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/healthcheck",
+		route53Credentials.WithSigV4(route53Api.ListHealthChecks)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/delegationset/{id}",
+		route53Credentials.WithSigV4(route53Api.DeleteReusableDelegationSet)).Methods("DELETE")
+	router.HandleFunc("/route53/2013-04-01/delegationset/{id}",
+		route53Credentials.WithSigV4(route53Api.GetReusableDelegationSet)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/delegationset{slash:/?}",
+		route53Credentials.WithSigV4(route53Api.CreateReusableDelegationSet)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/delegationset",
+		route53Credentials.WithSigV4(route53Api.ListReusableDelegationSets)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/keysigningkey{slash:/?}",
+		route53Credentials.WithSigV4(route53Api.CreateKeySigningKey)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/keysigningkey/{id}/{name}/activate",
+		route53Credentials.WithSigV4(route53Api.ActivateKeySigningKey)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/keysigningkey/{id}/{name}/deactivate",
+		route53Credentials.WithSigV4(route53Api.DeactivateKeySigningKey)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/keysigningkey/{id}/{name}",
+		route53Credentials.WithSigV4(route53Api.DeleteKeySigningKey)).Methods("DELETE")
+	router.HandleFunc("/route53/2013-04-01/queryloggingconfig/{id}",
+		route53Credentials.WithSigV4(route53Api.DeleteQueryLoggingConfig)).Methods("DELETE")
+	router.HandleFunc("/route53/2013-04-01/queryloggingconfig/{id}",
+		route53Credentials.WithSigV4(route53Api.GetQueryLoggingConfig)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/queryloggingconfig{slash:/?}",
+		route53Credentials.WithSigV4(route53Api.CreateQueryLoggingConfig)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/queryloggingconfig",
+		route53Credentials.WithSigV4(route53Api.ListQueryLoggingConfigs)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicy/{id}/{version}",
+		route53Credentials.WithSigV4(route53Api.UpdateTrafficPolicyComment)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicy/{id}/{version}",
+		route53Credentials.WithSigV4(route53Api.DeleteTrafficPolicy)).Methods("DELETE")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicy/{id}/{version}",
+		route53Credentials.WithSigV4(route53Api.GetTrafficPolicy)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicy/{id}",
+		route53Credentials.WithSigV4(route53Api.CreateTrafficPolicyVersion)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicy{slash:/?}",
+		route53Credentials.WithSigV4(route53Api.CreateTrafficPolicy)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicies/{id}/versions",
+		route53Credentials.WithSigV4(route53Api.ListTrafficPolicyVersions)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicies",
+		route53Credentials.WithSigV4(route53Api.ListTrafficPolicies)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstance/{id}",
+		route53Credentials.WithSigV4(route53Api.UpdateTrafficPolicyInstance)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstance/{id}",
+		route53Credentials.WithSigV4(route53Api.DeleteTrafficPolicyInstance)).Methods("DELETE")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstance/{id}",
+		route53Credentials.WithSigV4(route53Api.GetTrafficPolicyInstance)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstance{slash:/?}",
+		route53Credentials.WithSigV4(route53Api.CreateTrafficPolicyInstance)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstances/hostedzone",
+		route53Credentials.WithSigV4(route53Api.ListTrafficPolicyInstancesByHostedZone)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstances/trafficpolicy",
+		route53Credentials.WithSigV4(route53Api.ListTrafficPolicyInstancesByPolicy)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstances",
+		route53Credentials.WithSigV4(route53Api.ListTrafficPolicyInstances)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/trafficpolicyinstancecount",
+		route53Credentials.WithSigV4(route53Api.GetTrafficPolicyInstanceCount)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/cidrcollection/{id}/cidrblocks",
+		route53Credentials.WithSigV4(route53Api.ListCidrBlocks)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/cidrcollection/{id}",
+		route53Credentials.WithSigV4(route53Api.ChangeCidrCollection)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/cidrcollection/{id}",
+		route53Credentials.WithSigV4(route53Api.DeleteCidrCollection)).Methods("DELETE")
+	router.HandleFunc("/route53/2013-04-01/cidrcollection/{id}",
+		route53Credentials.WithSigV4(route53Api.ListCidrLocations)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/cidrcollection{slash:/?}",
+		route53Credentials.WithSigV4(route53Api.CreateCidrCollection)).Methods("POST")
+	router.HandleFunc("/route53/2013-04-01/cidrcollection",
+		route53Credentials.WithSigV4(route53Api.ListCidrCollections)).Methods("GET")
+	router.HandleFunc("/route53/2013-04-01/testdnsanswer",
+		route53Credentials.WithSigV4(route53Api.TestDNSAnswer)).Methods("GET")
 	router.HandleFunc("/route53/2013-04-01/tags/{resourceType}/{resourceId}",
 		route53Credentials.WithSigV4(route53Api.ListTagsForResource)).Methods("GET")
 	router.HandleFunc("/route53/2013-04-01/tags/{resourceType}/{resourceId}",
 		route53Credentials.WithSigV4(route53Api.ChangeTagsForResource)).Methods("POST")
 
+	// Route53 Resolver
+	router.HandleFunc("/route53resolver{slash:/?}",
+		resolverCredentials.WithSigV4(resolverApi.Handle)).Methods("POST")
+
+	// Route53 Domains
+	router.HandleFunc("/route53domains{slash:/?}",
+		domainsCredentials.WithSigV4(domainsApi.Handle)).Methods("POST")
+
+	// ACME DNS-01 challenges, acme-dns compatible
+	router.HandleFunc("/acme/register",
+		basicProvider.WithBasicAuth(acmeApi.Register)).Methods("POST")
+	router.HandleFunc("/acme/update",
+		acmeApi.Update).Methods("POST")
+	router.HandleFunc("/acme/health",
+		acmeApi.Health).Methods("GET")
+
 	// TF State
 	router.HandleFunc("/tfstate/{project}",
 		basicProvider.WithBasicAuth(stateApi.GetState)).Methods("GET")
@@ -135,6 +274,19 @@ func main() {
 	router.HandleFunc("/tfstate/{project}/unlock",
 		basicProvider.WithBasicAuth(stateApi.UnlockState)).Methods("UNLOCK")
 
+	go r53svc.RunHealthChecks()
+	go acmesvc.RunCleanup()
+	go kmssvc.RunKeyDeletion()
+	go kmssvc.RunKeyRotation()
+
+	if *dnsAddrPtr != "" {
+		dnsServer := route53.NewDnsServer(r53svc, resolversvc, *dnsAddrPtr)
+		go func() {
+			log.Printf("DNS listening on %s", *dnsAddrPtr)
+			log.Fatal(dnsServer.ListenAndServe())
+		}()
+	}
+
 	router.PathPrefix("/").Handler(http.FileServer(http.Dir(*webPathPtr)))
 
 	log.Printf("Listening on %s", *listenAddrPtr)
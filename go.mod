@@ -1,13 +1,17 @@
 module home-fern
 
-go 1.24
+go 1.24.0
 
 require (
 	github.com/aws/aws-sdk-go-v2 v1.36.3
 	github.com/aws/aws-sdk-go-v2/service/kms v1.38.3
 	github.com/aws/aws-sdk-go-v2/service/route53 v1.51.0
+	github.com/aws/aws-sdk-go-v2/service/route53domains v1.29.1
+	github.com/aws/aws-sdk-go-v2/service/route53resolver v1.35.4
 	github.com/aws/aws-sdk-go-v2/service/ssm v1.58.1
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.1
 	github.com/gorilla/mux v1.8.1
+	github.com/miekg/dns v1.1.72
 	go.etcd.io/bbolt v1.3.8
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -16,5 +20,9 @@ require (
 	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.34 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.34 // indirect
 	github.com/aws/smithy-go v1.22.3 // indirect
-	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/mod v0.31.0 // indirect
+	golang.org/x/net v0.48.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+	golang.org/x/tools v0.40.0 // indirect
 )
@@ -0,0 +1,97 @@
+package acme
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+// Api serves the acme-dns HTTP API, so acme-dns clients such as cert-manager, lego and
+// acme.sh can answer DNS-01 challenges with records in home-fern hosted zones.
+type Api struct {
+	service *Service
+}
+
+func NewAcmeApi(service *Service) *Api {
+
+	return &Api{service: service}
+}
+
+func (api *Api) Health(w http.ResponseWriter, r *http.Request) {
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *Api) Register(w http.ResponseWriter, r *http.Request) {
+
+	log.Println("AcmeRegister", r.RemoteAddr)
+
+	// the body is optional
+	var request RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, ErrMalformedJson)
+		return
+	}
+
+	response, err := api.service.Register(&request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusCreated, response)
+}
+
+func (api *Api) Update(w http.ResponseWriter, r *http.Request) {
+
+	log.Println("AcmeUpdate", r.RemoteAddr)
+
+	var request UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, ErrMalformedJson)
+		return
+	}
+
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	response, err := api.service.Update(
+		r.Header.Get("X-Api-User"), r.Header.Get("X-Api-Key"), net.ParseIP(host), &request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+// writeError answers with the acme-dns error code of err.
+func writeError(w http.ResponseWriter, err error) {
+
+	for _, sentinel := range []error{ErrBadSubdomain, ErrBadTxt, ErrBadDomain, ErrInvalidAllowFrom, ErrMalformedJson} {
+		if errors.Is(err, sentinel) {
+			log.Println("Error:", err)
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": sentinel.Error()})
+			return
+		}
+	}
+
+	if errors.Is(err, ErrForbidden) {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": ErrForbidden.Error()})
+		return
+	}
+
+	log.Println("Error:", err)
+	writeJson(w, http.StatusInternalServerError, map[string]string{"error": "db_error"})
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error:", err)
+	}
+}
@@ -0,0 +1,85 @@
+package acme
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"home-fern/internal/core"
+	"home-fern/internal/datastore"
+	"io"
+
+	"go.etcd.io/bbolt"
+)
+
+const RegistrationPrefix = "/registration/"
+
+type dataStore struct {
+	ds *datastore.Datastore
+}
+
+func newDataStore(ds *datastore.Datastore) *dataStore {
+	return &dataStore{ds: ds}
+}
+
+func (ds *dataStore) logKeys(w io.Writer) error {
+	return ds.ds.LogKeys(datastore.Acme, w)
+}
+
+func (ds *dataStore) findRegistrations() ([]RegistrationData, error) {
+	var result []RegistrationData
+
+	err := ds.ds.View(datastore.Acme, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(RegistrationPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var reg RegistrationData
+			if err := json.Unmarshal(v, &reg); err != nil {
+				return fmt.Errorf("failed to unmarshal registration: %w", err)
+			}
+			result = append(result, reg)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []RegistrationData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find registrations: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getRegistration(username string) (*RegistrationData, error) {
+	var result RegistrationData
+
+	err := ds.ds.View(datastore.Acme, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(RegistrationPrefix + username))
+		if v == nil {
+			return core.ErrNotFound
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) || errors.Is(err, core.ErrNotFound) {
+			return nil, core.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get registration %s: %w", username, err)
+	}
+	return &result, nil
+}
+
+func (ds *dataStore) putRegistration(reg *RegistrationData, overwrite bool) error {
+	data := []datastore.PutData{{
+		Key:       RegistrationPrefix + reg.Username,
+		Data:      reg,
+		Overwrite: overwrite,
+	}}
+	err := ds.ds.PutKeys(datastore.Acme, data)
+	if err != nil {
+		return fmt.Errorf("failed to put registration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package acme
+
+import "errors"
+
+// The messages are the error codes of acme-dns, which clients may look for.
+var (
+	ErrForbidden        = errors.New("forbidden")
+	ErrBadSubdomain     = errors.New("bad_subdomain")
+	ErrBadTxt           = errors.New("bad_txt")
+	ErrBadDomain        = errors.New("bad_domain")
+	ErrInvalidAllowFrom = errors.New("invalid_allowfrom_cidr")
+	ErrMalformedJson    = errors.New("malformed_json_payload")
+)
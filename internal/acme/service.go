@@ -0,0 +1,283 @@
+package acme
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"home-fern/internal/core"
+	"home-fern/internal/datastore"
+	"home-fern/internal/route53"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultTtl  = 3600
+	cleanupTick = time.Minute
+
+	// maxChallenges is the number of TXT values kept per registration, like acme-dns, so a
+	// certificate for a name and its wildcard can be validated together.
+	maxChallenges = 2
+)
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	txtPattern  = regexp.MustCompile(`^[A-Za-z0-9_\-]{43}$`)
+)
+
+type Service struct {
+	dataStore *dataStore
+	route53   *route53.Service
+	domain    string
+	ttl       time.Duration
+
+	// mu serializes the changes to the challenge records
+	mu sync.Mutex
+}
+
+func NewService(config *core.DnsAcme, ds *datastore.Datastore, r53 *route53.Service) *Service {
+
+	ttl := config.Ttl
+	if ttl <= 0 {
+		ttl = defaultTtl
+	}
+
+	return &Service{
+		dataStore: newDataStore(ds),
+		route53:   r53,
+		domain:    strings.TrimSuffix(strings.ToLower(config.Domain), "."),
+		ttl:       time.Duration(ttl) * time.Second,
+	}
+}
+
+// Register creates an account which can update the TXT records of one name: _acme-challenge
+// of the requested domain, or a subdomain of the configured acme domain to CNAME to.
+func (s *Service) Register(request *RegisterRequest) (*RegisterResponse, error) {
+
+	for _, cidr := range request.AllowFrom {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidAllowFrom, cidr)
+		}
+	}
+
+	subdomain := newUuid()
+
+	var fullDomain string
+	if request.Domain != "" {
+		domain := strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(request.Domain), "."), "*.")
+		if _, ok := dns.IsDomainName(domain); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrBadDomain, request.Domain)
+		}
+		fullDomain = "_acme-challenge." + domain
+	} else if s.domain != "" {
+		fullDomain = subdomain + "." + s.domain
+	} else {
+		return nil, fmt.Errorf("%w: a domain is required, no acme domain is configured", ErrBadDomain)
+	}
+
+	if _, err := s.route53.AcmeChallengeZone(fullDomain); err != nil {
+		if errors.Is(err, route53.ErrNoSuchHostedZone) {
+			return nil, fmt.Errorf("%w: no hosted zone contains %s", ErrBadDomain, fullDomain)
+		}
+		return nil, err
+	}
+
+	password := newPassword()
+
+	reg := RegistrationData{
+		Username:     newUuid(),
+		PasswordHash: hashPassword(password),
+		Subdomain:    subdomain,
+		FullDomain:   fullDomain,
+		AllowFrom:    request.AllowFrom,
+	}
+
+	if err := s.dataStore.putRegistration(&reg, false); err != nil {
+		return nil, err
+	}
+
+	allowFrom := reg.AllowFrom
+	if allowFrom == nil {
+		allowFrom = []string{}
+	}
+
+	return &RegisterResponse{
+		AllowFrom:  allowFrom,
+		FullDomain: reg.FullDomain,
+		Password:   password,
+		Subdomain:  reg.Subdomain,
+		Username:   reg.Username,
+	}, nil
+}
+
+// Update adds a TXT value to the challenge record of the account, replacing its oldest value
+// when it already has maxChallenges.
+func (s *Service) Update(
+	username string, password string, clientIP net.IP, request *UpdateRequest) (*UpdateResponse, error) {
+
+	if !uuidPattern.MatchString(request.Subdomain) {
+		return nil, ErrBadSubdomain
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	regs, err := s.dataStore.findRegistrations()
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(regs, func(reg RegistrationData) bool { return reg.Username == username })
+	if i < 0 || !authenticated(&regs[i], password, clientIP) || regs[i].Subdomain != request.Subdomain {
+		return nil, ErrForbidden
+	}
+
+	if !txtPattern.MatchString(request.Txt) {
+		return nil, ErrBadTxt
+	}
+
+	reg := &regs[i]
+	previous := challengeValues(regs, reg.FullDomain)
+	reg.Challenges = append(reg.Challenges, ChallengeData{Txt: request.Txt, Updated: time.Now().UTC()})
+	if len(reg.Challenges) > maxChallenges {
+		reg.Challenges = reg.Challenges[len(reg.Challenges)-maxChallenges:]
+	}
+
+	if err := s.route53.PutAcmeChallenge(reg.FullDomain, previous, challengeValues(regs, reg.FullDomain)); err != nil {
+		return nil, err
+	}
+
+	if err := s.dataStore.putRegistration(reg, true); err != nil {
+		return nil, err
+	}
+
+	return &UpdateResponse{Txt: request.Txt}, nil
+}
+
+// RunCleanup removes the challenge values older than the TTL. It doesn't return.
+func (s *Service) RunCleanup() {
+
+	ticker := time.NewTicker(cleanupTick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.cleanup(time.Now()); err != nil {
+			log.Println("Error:", err)
+		}
+	}
+}
+
+func (s *Service) cleanup(now time.Time) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	regs, err := s.dataStore.findRegistrations()
+	if err != nil {
+		return err
+	}
+
+	var changed []int
+	previous := make(map[string][]string)
+	for i := range regs {
+		kept := slices.DeleteFunc(slices.Clone(regs[i].Challenges), func(c ChallengeData) bool {
+			return now.Sub(c.Updated) >= s.ttl
+		})
+		if len(kept) < len(regs[i].Challenges) {
+			if _, ok := previous[regs[i].FullDomain]; !ok {
+				previous[regs[i].FullDomain] = challengeValues(regs, regs[i].FullDomain)
+			}
+			regs[i].Challenges = kept
+			changed = append(changed, i)
+		}
+	}
+
+	// the records go first, a failed cleanup is retried at the next tick
+	written := make(map[string]bool)
+	for _, i := range changed {
+		if written[regs[i].FullDomain] {
+			continue
+		}
+		if err := s.route53.PutAcmeChallenge(regs[i].FullDomain, previous[regs[i].FullDomain],
+			challengeValues(regs, regs[i].FullDomain)); err != nil {
+			return err
+		}
+		written[regs[i].FullDomain] = true
+	}
+
+	for _, i := range changed {
+		if err := s.dataStore.putRegistration(&regs[i], true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) LogKeys(writer io.Writer) error {
+	return s.dataStore.logKeys(writer)
+}
+
+// challengeValues returns the TXT values of all the registrations of fullDomain, several
+// accounts may validate the same name.
+func challengeValues(regs []RegistrationData, fullDomain string) []string {
+
+	var result []string
+	for _, reg := range regs {
+		if reg.FullDomain != fullDomain {
+			continue
+		}
+		for _, c := range reg.Challenges {
+			if !slices.Contains(result, c.Txt) {
+				result = append(result, c.Txt)
+			}
+		}
+	}
+
+	return result
+}
+
+func authenticated(reg *RegistrationData, password string, clientIP net.IP) bool {
+
+	if subtle.ConstantTimeCompare([]byte(hashPassword(password)), []byte(reg.PasswordHash)) != 1 {
+		return false
+	}
+
+	return len(reg.AllowFrom) == 0 || core.IPInList(reg.AllowFrom, clientIP)
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// newPassword returns 40 random characters, the length of acme-dns passwords.
+func newPassword() string {
+
+	b := make([]byte, 30)
+	_, _ = rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newUuid() string {
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
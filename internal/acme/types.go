@@ -0,0 +1,41 @@
+package acme
+
+import "time"
+
+// RegistrationData is an acme-dns account, its password is kept as a SHA-256 hash.
+type RegistrationData struct {
+	Username     string
+	PasswordHash string
+	Subdomain    string
+	FullDomain   string
+	AllowFrom    []string        `json:",omitempty"`
+	Challenges   []ChallengeData `json:",omitempty"`
+}
+
+type ChallengeData struct {
+	Txt     string
+	Updated time.Time
+}
+
+// RegisterRequest is the body of /acme/register, Domain is a home-fern addition.
+type RegisterRequest struct {
+	AllowFrom []string `json:"allowfrom"`
+	Domain    string   `json:"domain"`
+}
+
+type RegisterResponse struct {
+	AllowFrom  []string `json:"allowfrom"`
+	FullDomain string   `json:"fulldomain"`
+	Password   string   `json:"password"`
+	Subdomain  string   `json:"subdomain"`
+	Username   string   `json:"username"`
+}
+
+type UpdateRequest struct {
+	Subdomain string `json:"subdomain"`
+	Txt       string `json:"txt"`
+}
+
+type UpdateResponse struct {
+	Txt string `json:"txt"`
+}
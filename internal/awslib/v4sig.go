@@ -46,9 +46,11 @@ const (
 type ServiceType string
 
 const (
-	ServiceKms     ServiceType = "kms"
-	ServiceSsm     ServiceType = "ssm"
-	ServiceRoute53 ServiceType = "route53"
+	ServiceKms             ServiceType = "kms"
+	ServiceSsm             ServiceType = "ssm"
+	ServiceRoute53         ServiceType = "route53"
+	ServiceRoute53Resolver ServiceType = "route53resolver"
+	ServiceRoute53Domains  ServiceType = "route53domains"
 )
 
 type CredentialsProvider struct {
@@ -205,7 +207,8 @@ func (p *CredentialsProvider) checkV4Sig(r *http.Request) (string, ApiError) {
 // Returns SHA256 for calculating canonical-request.
 func getContentSha256Cksum(r *http.Request, stype ServiceType) string {
 
-	if stype == ServiceSsm || stype == ServiceRoute53 || stype == ServiceKms {
+	if stype == ServiceSsm || stype == ServiceRoute53 || stype == ServiceRoute53Resolver ||
+		stype == ServiceRoute53Domains || stype == ServiceKms {
 
 		payload, err := io.ReadAll(io.LimitReader(r.Body, 10*(1<<20)))
 		if err != nil {
@@ -11,18 +11,72 @@ type FernCredentials struct {
 	Username  string `yaml:"username"`
 }
 
+// FernConfig is the home-fern config. KmsRootKey is the alias name or id of the symmetric key
+// wrapping the keys made by CreateKey, the first key when it's empty.
 type FernConfig struct {
 	Region      string            `yaml:"region"`
 	Credentials []FernCredentials `yaml:"credentials"`
 	Keys        []kms.KmsKey      `yaml:"kms"`
+	KmsRootKey  string            `yaml:"kmsRootKey"`
 	DnsDefaults DnsDefaults       `yaml:"dns"`
 }
 
 type DnsDefaults struct {
-	Soa         string   `yaml:"soa"`
+	Soa            string             `yaml:"soa"`
+	NameServers    []string           `yaml:"nameServers"`
+	TsigKeys       []DnsTsigKey       `yaml:"tsigKeys"`
+	Zones          []DnsZoneConfig    `yaml:"zones"`
+	DelegationSets []DnsDelegationSet `yaml:"delegationSets"`
+	Vpcs           []DnsVpc           `yaml:"vpcs"`
+	LogGroups      []DnsLogGroup      `yaml:"logGroups"`
+	Acme           DnsAcme            `yaml:"acme"`
+}
+
+// DnsTsigKey is a shared secret (base64) used to sign DNS messages, see RFC 8945.
+type DnsTsigKey struct {
+	Name      string `yaml:"name"`
+	Algorithm string `yaml:"algorithm"`
+	Secret    string `yaml:"secret"`
+}
+
+// DnsZoneConfig holds the per zone DNS settings; zones are matched by name.
+type DnsZoneConfig struct {
+	Name          string   `yaml:"name"`
+	AllowTransfer []string `yaml:"allowTransfer"`
+	TransferKeys  []string `yaml:"transferKeys"`
+	Notify        []string `yaml:"notify"`
+	UpdateKeys    []string `yaml:"updateKeys"`
+}
+
+// DnsDelegationSet is a group of name servers for reusable delegation sets. A delegation set
+// gets the group whose name is its CallerReference, or the CallerReference's prefix before a '-'.
+type DnsDelegationSet struct {
+	Name        string   `yaml:"name"`
 	NameServers []string `yaml:"nameServers"`
 }
 
+// DnsVpc stands in for an AWS VPC: private hosted zones associated with it are visible to
+// DNS clients in its CIDRs. Region is optional and restricts the VPCRegion accepted for it.
+type DnsVpc struct {
+	Id     string   `yaml:"id"`
+	Region string   `yaml:"region"`
+	Cidrs  []string `yaml:"cidrs"`
+}
+
+// DnsLogGroup stands in for a CloudWatch Logs log group receiving Route53 query logs.
+// The lines are appended to Path, or written to home-fern's log when Path is empty.
+type DnsLogGroup struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+// DnsAcme configures the acme-dns compatible API. Registrations without a domain of their own
+// get a subdomain of Domain, and challenge records are removed Ttl seconds after their update.
+type DnsAcme struct {
+	Domain string `yaml:"domain"`
+	Ttl    int    `yaml:"ttl"`
+}
+
 type ResourceTag struct {
 	Key   string
 	Value string
@@ -2,6 +2,7 @@ package core
 
 import (
 	"math/rand"
+	"net"
 	"time"
 )
 
@@ -35,3 +36,24 @@ func GenerateRandomString(length int) string {
 	// Convert the byte slice to a string and return it.
 	return string(b)
 }
+
+// IPInList matches ip against a list of addresses and CIDR blocks.
+func IPInList(list []string, ip net.IP) bool {
+
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range list {
+
+		if _, block, err := net.ParseCIDR(entry); err == nil {
+			if block.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
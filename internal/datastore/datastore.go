@@ -14,8 +14,12 @@ import (
 type BucketName string
 
 const (
-	Ssm     BucketName = "Ssm"
-	Route53 BucketName = "Route53"
+	Ssm      BucketName = "Ssm"
+	Route53  BucketName = "Route53"
+	Resolver BucketName = "Resolver"
+	Acme     BucketName = "Acme"
+	Domains  BucketName = "Domains"
+	Kms      BucketName = "Kms"
 )
 
 var (
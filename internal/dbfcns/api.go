@@ -2,15 +2,22 @@ package dbfcns
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"home-fern/internal/acme"
 	"home-fern/internal/awslib"
 	"home-fern/internal/core"
+	"home-fern/internal/domains"
+	"home-fern/internal/kms"
+	"home-fern/internal/resolver"
 	"home-fern/internal/route53"
 	"home-fern/internal/ssm"
 	"home-fern/internal/tfstate"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,9 +26,16 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// zoneFileContentType is the media type of RFC 1035 master files, see RFC 4027.
+const zoneFileContentType = "text/dns"
+
 type Api struct {
+	Kms         *kms.Service
 	Ssm         *ssm.Service
 	Route53     *route53.Service
+	Resolver    *resolver.Service
+	Domains     *domains.Service
+	Acme        *acme.Service
 	TfState     *tfstate.StateApi
 	Credentials *core.BasicCredentialsProvider
 }
@@ -37,13 +51,25 @@ func (api *Api) Keys(w http.ResponseWriter, r *http.Request) {
 	var loggers = map[string]core.DatabaseDumper{}
 
 	if service == "all" {
+		loggers["kms"] = api.Kms
 		loggers["ssm"] = api.Ssm
 		loggers["route53"] = api.Route53
+		loggers["resolver"] = api.Resolver
+		loggers["domains"] = api.Domains
+		loggers["acme"] = api.Acme
 		loggers["tfstate"] = api.TfState
+	} else if service == "kms" {
+		loggers["kms"] = api.Kms
 	} else if service == "ssm" {
 		loggers["ssm"] = api.Ssm
 	} else if service == "route53" {
 		loggers["route53"] = api.Route53
+	} else if service == "resolver" {
+		loggers["resolver"] = api.Resolver
+	} else if service == "domains" {
+		loggers["domains"] = api.Domains
+	} else if service == "acme" {
+		loggers["acme"] = api.Acme
 	} else if service == "tfstate" {
 		loggers["tfstate"] = api.TfState
 	} else {
@@ -99,6 +125,65 @@ func (api *Api) Import(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (api *Api) ExportZoneFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var buf bytes.Buffer
+	name, err := api.Route53.ExportZoneFile(vars["zoneId"], &buf)
+	if err != nil {
+		if errors.Is(err, route53.ErrNoSuchHostedZone) {
+			http.Error(w, "Hosted zone not found", http.StatusNotFound)
+			return
+		}
+		log.Println("Error:", err)
+		http.Error(w, "An error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", zoneFileContentType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"%szone\"", name))
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (api *Api) ZoneChanges(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	changes, err := api.Route53.ListZoneChanges(vars["zoneId"])
+	if err != nil {
+		writeRoute53Error(w, err)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, changes)
+}
+
+func (api *Api) RollbackZone(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	change, err := api.Route53.RollbackHostedZone(vars["zoneId"], vars["changeId"])
+	if err != nil {
+		writeRoute53Error(w, err)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, change)
+}
+
+func writeRoute53Error(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, route53.ErrNoSuchHostedZone):
+		http.Error(w, "Hosted zone not found", http.StatusNotFound)
+	case errors.Is(err, core.ErrNotFound):
+		http.Error(w, "Change not found", http.StatusNotFound)
+	case errors.Is(err, route53.ErrInvalidInput), errors.Is(err, route53.ErrInvalidChangeBatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Println("Error:", err)
+		http.Error(w, "An error occurred", http.StatusInternalServerError)
+	}
+}
+
 func (api *Api) exportSsm(w http.ResponseWriter) {
 	parameters, err := api.Ssm.GetAllParameters()
 	if err != nil {
@@ -211,6 +296,11 @@ func (api *Api) importSsm(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *Api) importRoute53(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == zoneFileContentType {
+		api.importRoute53ZoneFile(w, r)
+		return
+	}
+
 	var zones []route53.HostedZoneExport
 	if err := json.NewDecoder(r.Body).Decode(&zones); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -229,6 +319,23 @@ func (api *Api) importRoute53(w http.ResponseWriter, r *http.Request) {
 	awslib.WriteSuccessResponseJSON(w, map[string]interface{}{"failures": failures})
 }
 
+func (api *Api) importRoute53ZoneFile(w http.ResponseWriter, r *http.Request) {
+	overwrite := r.Method == http.MethodPut
+
+	failures, err := api.Route53.ImportZoneFile(r.Body, r.URL.Query().Get("origin"), overwrite)
+	if err != nil {
+		if errors.Is(err, route53.ErrInvalidInput) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Println("Error:", err)
+		http.Error(w, "An error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, map[string]interface{}{"failures": failures})
+}
+
 func (api *Api) importAll(w http.ResponseWriter, r *http.Request) {
 	requestUser := r.Context().Value(awslib.RequestUser)
 	if requestUser == nil {
@@ -0,0 +1,217 @@
+package domains
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"home-fern/internal/awslib"
+	"log"
+	"net/http"
+	"strings"
+
+	awsdomains "github.com/aws/aws-sdk-go-v2/service/route53domains"
+)
+
+type Api struct {
+	service     *Service
+	credentials *awslib.CredentialsProvider
+}
+
+func NewDomainsApi(service *Service, credentials *awslib.CredentialsProvider) *Api {
+
+	return &Api{service: service, credentials: credentials}
+}
+
+func (api *Api) Handle(w http.ResponseWriter, r *http.Request) {
+
+	requestUser := r.Context().Value(awslib.RequestUser)
+	if requestUser == nil {
+		awslib.WriteAwsError(w, http.StatusInternalServerError, awslib.AwsErrorResponse{Code: "InternalFailure", Message: "An internal error occurred."})
+		return
+	}
+
+	creds, _ := api.credentials.FindCredentials(fmt.Sprintf("%v", requestUser))
+
+	amztarget := r.Header.Get("X-Amz-Target")
+
+	awslib.LogEndpoint(r, amztarget, creds)
+
+	if amztarget == "Route53Domains_v20140515.DeleteTagsForDomain" {
+		api.deleteTagsForDomain(w, r)
+	} else if amztarget == "Route53Domains_v20140515.GetDomainDetail" {
+		api.getDomainDetail(w, r)
+	} else if amztarget == "Route53Domains_v20140515.GetOperationDetail" {
+		api.getOperationDetail(w, r)
+	} else if amztarget == "Route53Domains_v20140515.ListDomains" {
+		api.listDomains(w, r)
+	} else if amztarget == "Route53Domains_v20140515.ListTagsForDomain" {
+		api.listTagsForDomain(w, r)
+	} else if amztarget == "Route53Domains_v20140515.RegisterDomain" {
+		api.registerDomain(w, r)
+	} else if amztarget == "Route53Domains_v20140515.UpdateDomainNameservers" {
+		api.updateDomainNameservers(w, r)
+	} else if amztarget == "Route53Domains_v20140515.UpdateTagsForDomain" {
+		api.updateTagsForDomain(w, r)
+	} else {
+		log.Println("Unknown Target:", amztarget)
+		awslib.WriteAwsError(w, http.StatusBadRequest, awslib.AwsErrorResponse{Code: "ValidationException", Message: "Unknown operation"})
+	}
+}
+
+func (api *Api) deleteTagsForDomain(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.DeleteTagsForDomainInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.DeleteTagsForDomain(&request); err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) getDomainDetail(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.GetDomainDetailInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GetDomainDetail(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) getOperationDetail(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.GetOperationDetailInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GetOperationDetail(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) listDomains(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.ListDomainsInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ListDomains(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) listTagsForDomain(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.ListTagsForDomainInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ListTagsForDomain(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) registerDomain(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.RegisterDomainInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.RegisterDomain(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) updateDomainNameservers(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.UpdateDomainNameserversInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.UpdateDomainNameservers(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) updateTagsForDomain(w http.ResponseWriter, r *http.Request) {
+	var request awsdomains.UpdateTagsForDomainInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.UpdateTagsForDomain(&request); err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func translateError(err error) (int, awslib.AwsErrorResponse) {
+	if errors.Is(err, ErrInvalidInput) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidInput", Message: errorMessage(err, ErrInvalidInput)}
+	}
+	if errors.Is(err, ErrUnsupportedTLD) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "UnsupportedTLD", Message: errorMessage(err, ErrUnsupportedTLD)}
+	}
+
+	return http.StatusInternalServerError, awslib.AwsErrorResponse{Code: "InternalFailure", Message: "An internal error occurred."}
+}
+
+// errorMessage returns the detail a service error carries after its sentinel.
+func errorMessage(err error, sentinel error) string {
+	return strings.TrimPrefix(err.Error(), sentinel.Error()+": ")
+}
@@ -0,0 +1,129 @@
+package domains
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"home-fern/internal/datastore"
+	"io"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	DomainPrefix    = "/domain/"
+	OperationPrefix = "/operation/"
+)
+
+type dataStore struct {
+	ds *datastore.Datastore
+}
+
+func newDataStore(ds *datastore.Datastore) *dataStore {
+	return &dataStore{ds: ds}
+}
+
+func (ds *dataStore) logKeys(w io.Writer) error {
+	return ds.ds.LogKeys(datastore.Domains, w)
+}
+
+func (ds *dataStore) findDomains() ([]DomainData, error) {
+	var result []DomainData
+
+	err := ds.ds.View(datastore.Domains, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(DomainPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var domain DomainData
+			if err := json.Unmarshal(v, &domain); err != nil {
+				return fmt.Errorf("failed to unmarshal domain: %w", err)
+			}
+			result = append(result, domain)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []DomainData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find domains: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getDomain(name string) (*DomainData, error) {
+	var result DomainData
+
+	err := ds.ds.View(datastore.Domains, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(DomainPrefix + name))
+		if v == nil {
+			return domainNotFound(name)
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, domainNotFound(name)
+		}
+		if errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get domain %s: %w", name, err)
+	}
+	return &result, nil
+}
+
+// putDomain stores a domain and the operation which changed it.
+func (ds *dataStore) putDomain(domain *DomainData, op *OperationData, overwrite bool) error {
+	data := []datastore.PutData{{
+		Key:       DomainPrefix + domain.DomainName,
+		Data:      domain,
+		Overwrite: overwrite,
+	}}
+	if op != nil {
+		data = append(data, datastore.PutData{Key: OperationPrefix + op.OperationId, Data: op})
+	}
+
+	err := ds.ds.PutKeys(datastore.Domains, data)
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) {
+			return fmt.Errorf("%w: Domain %s is already registered", ErrInvalidInput, domain.DomainName)
+		}
+		return fmt.Errorf("failed to put domain: %w", err)
+	}
+	return nil
+}
+
+func (ds *dataStore) getOperation(id string) (*OperationData, error) {
+	var result OperationData
+
+	err := ds.ds.View(datastore.Domains, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(OperationPrefix + id))
+		if v == nil {
+			return operationNotFound(id)
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, operationNotFound(id)
+		}
+		if errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get operation %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+func domainNotFound(name string) error {
+	return fmt.Errorf("%w: Domain %s not found in account", ErrInvalidInput, name)
+}
+
+func operationNotFound(id string) error {
+	return fmt.Errorf("%w: Operation %s not found", ErrInvalidInput, id)
+}
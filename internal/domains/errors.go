@@ -0,0 +1,8 @@
+package domains
+
+import "errors"
+
+var (
+	ErrInvalidInput   = errors.New("the requested item is not acceptable")
+	ErrUnsupportedTLD = errors.New("the top-level domain is not supported")
+)
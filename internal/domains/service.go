@@ -0,0 +1,319 @@
+package domains
+
+import (
+	"cmp"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"home-fern/internal/core"
+	"home-fern/internal/datastore"
+	"home-fern/internal/route53"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsdomains "github.com/aws/aws-sdk-go-v2/service/route53domains"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53domains/types"
+)
+
+const (
+	defaultMaxItems = 20
+	maxMaxItems     = 100
+)
+
+// hostnamePattern matches the domain and name server names a registry accepts: letters, digits
+// and hyphens, at least two labels.
+var hostnamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Service is a stand-in registrar: domains are registered at once, and delegated to the name
+// servers of the public hosted zone of the same name.
+type Service struct {
+	dataStore   *dataStore
+	route53     *route53.Service
+	nameServers []string
+}
+
+func NewService(fernConfig *core.FernConfig, ds *datastore.Datastore, r53 *route53.Service) *Service {
+
+	return &Service{
+		dataStore:   newDataStore(ds),
+		route53:     r53,
+		nameServers: fernConfig.DnsDefaults.NameServers,
+	}
+}
+
+func (s *Service) DeleteTagsForDomain(request *awsdomains.DeleteTagsForDomainInput) error {
+
+	domain, err := s.dataStore.getDomain(domainName(request.DomainName))
+	if err != nil {
+		return err
+	}
+
+	domain.Tags = slices.DeleteFunc(domain.Tags, func(tag awstypes.Tag) bool {
+		return slices.Contains(request.TagsToDelete, aws.ToString(tag.Key))
+	})
+
+	return s.dataStore.putDomain(domain, nil, true)
+}
+
+func (s *Service) GetDomainDetail(request *awsdomains.GetDomainDetailInput) (*DomainDetailOutput, error) {
+
+	domain, err := s.dataStore.getDomain(domainName(request.DomainName))
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.toDomainDetail(), nil
+}
+
+func (s *Service) GetOperationDetail(request *awsdomains.GetOperationDetailInput) (*OperationData, error) {
+
+	return s.dataStore.getOperation(aws.ToString(request.OperationId))
+}
+
+func (s *Service) ListDomains(request *awsdomains.ListDomainsInput) (*ListDomainsOutput, error) {
+
+	domains, err := s.dataStore.findDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range request.FilterConditions {
+		keep, err := filterCondition(f)
+		if err != nil {
+			return nil, err
+		}
+		domains = slices.DeleteFunc(domains, func(d DomainData) bool { return !keep(&d) })
+	}
+
+	slices.SortFunc(domains, func(a, b DomainData) int { return strings.Compare(a.DomainName, b.DomainName) })
+	if sort := request.SortCondition; sort != nil {
+		if sort.Name == awstypes.ListDomainsAttributeNameExpiry {
+			slices.SortStableFunc(domains, func(a, b DomainData) int { return cmp.Compare(a.ExpirationDate, b.ExpirationDate) })
+		}
+		if sort.SortOrder == awstypes.SortOrderDesc {
+			slices.Reverse(domains)
+		}
+	}
+
+	// the marker is the index of the first domain of the page
+	start := 0
+	if marker := aws.ToString(request.Marker); marker != "" {
+		start, err = strconv.Atoi(marker)
+		if err != nil || start < 0 || start > len(domains) {
+			return nil, fmt.Errorf("%w: Marker %s is not valid", ErrInvalidInput, marker)
+		}
+	}
+
+	limit := defaultMaxItems
+	if request.MaxItems != nil && *request.MaxItems > 0 {
+		limit = min(int(*request.MaxItems), maxMaxItems)
+	}
+
+	result := ListDomainsOutput{Domains: []DomainSummaryData{}}
+	for i := start; i < len(domains) && i < start+limit; i++ {
+		result.Domains = append(result.Domains, domains[i].toDomainSummary())
+	}
+	if start+limit < len(domains) {
+		result.NextPageMarker = aws.String(strconv.Itoa(start + limit))
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListTagsForDomain(request *awsdomains.ListTagsForDomainInput) (*ListTagsForDomainOutput, error) {
+
+	domain, err := s.dataStore.getDomain(domainName(request.DomainName))
+	if err != nil {
+		return nil, err
+	}
+
+	result := ListTagsForDomainOutput{TagList: domain.Tags}
+	if result.TagList == nil {
+		result.TagList = []awstypes.Tag{}
+	}
+
+	return &result, nil
+}
+
+// RegisterDomain registers a domain at once. It's delegated to the name servers of the public
+// hosted zone of the same name, or to the default name servers when there isn't one.
+func (s *Service) RegisterDomain(request *awsdomains.RegisterDomainInput) (*OperationOutput, error) {
+
+	name, err := validDomainName(request.DomainName)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.AdminContact == nil || request.RegistrantContact == nil || request.TechContact == nil {
+		return nil, fmt.Errorf("%w: AdminContact, RegistrantContact and TechContact are required", ErrInvalidInput)
+	}
+
+	years := aws.ToInt32(request.DurationInYears)
+	if years < 1 || years > 10 {
+		return nil, fmt.Errorf("%w: DurationInYears must be between 1 and 10", ErrInvalidInput)
+	}
+
+	nameServers, err := s.route53.ZoneNameServers(name)
+	if errors.Is(err, route53.ErrNoSuchHostedZone) {
+		nameServers = s.nameServers
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	domain := DomainData{
+		DomainName:        name,
+		AdminContact:      request.AdminContact,
+		RegistrantContact: request.RegistrantContact,
+		TechContact:       request.TechContact,
+		BillingContact:    request.BillingContact,
+		AdminPrivacy:      aws.ToBool(defaultTrue(request.PrivacyProtectAdminContact)),
+		RegistrantPrivacy: aws.ToBool(defaultTrue(request.PrivacyProtectRegistrantContact)),
+		TechPrivacy:       aws.ToBool(defaultTrue(request.PrivacyProtectTechContact)),
+		BillingPrivacy:    aws.ToBool(defaultTrue(request.PrivacyProtectBillingContact)),
+		AutoRenew:         aws.ToBool(defaultTrue(request.AutoRenew)),
+		StatusList:        []string{transferLockStatus},
+		CreationDate:      epoch(now),
+		ExpirationDate:    epoch(now.AddDate(int(years), 0, 0)),
+		UpdatedDate:       epoch(now),
+	}
+
+	for _, ns := range nameServers {
+		domain.Nameservers = append(domain.Nameservers, awstypes.Nameserver{Name: aws.String(strings.TrimSuffix(ns, "."))})
+	}
+
+	op := newOperation(name, awstypes.OperationTypeRegisterDomain, now)
+	if err := s.dataStore.putDomain(&domain, op, false); err != nil {
+		return nil, err
+	}
+
+	return &OperationOutput{OperationId: op.OperationId}, nil
+}
+
+func (s *Service) UpdateDomainNameservers(request *awsdomains.UpdateDomainNameserversInput) (*OperationOutput, error) {
+
+	domain, err := s.dataStore.getDomain(domainName(request.DomainName))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(request.Nameservers) == 0 {
+		return nil, fmt.Errorf("%w: at least one name server is required", ErrInvalidInput)
+	}
+
+	for _, ns := range request.Nameservers {
+		if !hostnamePattern.MatchString(domainName(ns.Name)) {
+			return nil, fmt.Errorf("%w: %s is not a valid name server", ErrInvalidInput, aws.ToString(ns.Name))
+		}
+	}
+
+	now := time.Now().UTC()
+	domain.Nameservers = request.Nameservers
+	domain.UpdatedDate = epoch(now)
+
+	op := newOperation(domain.DomainName, awstypes.OperationTypeUpdateNameserver, now)
+	if err := s.dataStore.putDomain(domain, op, true); err != nil {
+		return nil, err
+	}
+
+	return &OperationOutput{OperationId: op.OperationId}, nil
+}
+
+func (s *Service) UpdateTagsForDomain(request *awsdomains.UpdateTagsForDomainInput) error {
+
+	domain, err := s.dataStore.getDomain(domainName(request.DomainName))
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range request.TagsToUpdate {
+		domain.Tags = slices.DeleteFunc(domain.Tags, func(t awstypes.Tag) bool {
+			return aws.ToString(t.Key) == aws.ToString(tag.Key)
+		})
+		domain.Tags = append(domain.Tags, tag)
+	}
+
+	return s.dataStore.putDomain(domain, nil, true)
+}
+
+func (s *Service) LogKeys(writer io.Writer) error {
+	return s.dataStore.logKeys(writer)
+}
+
+// filterCondition returns whether a domain passes a ListDomains filter.
+func filterCondition(f awstypes.FilterCondition) (func(*DomainData) bool, error) {
+
+	switch {
+	case f.Name == awstypes.ListDomainsAttributeNameDomainName && f.Operator == awstypes.OperatorBeginsWith:
+		return func(d *DomainData) bool {
+			return slices.ContainsFunc(f.Values, func(v string) bool { return strings.HasPrefix(d.DomainName, strings.ToLower(v)) })
+		}, nil
+
+	case f.Name == awstypes.ListDomainsAttributeNameExpiry &&
+		(f.Operator == awstypes.OperatorLe || f.Operator == awstypes.OperatorGe) && len(f.Values) == 1:
+		limit, err := strconv.ParseFloat(f.Values[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is not a valid expiry", ErrInvalidInput, f.Values[0])
+		}
+		return func(d *DomainData) bool {
+			if f.Operator == awstypes.OperatorLe {
+				return d.ExpirationDate <= limit
+			}
+			return d.ExpirationDate >= limit
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: filter %s %s is not supported", ErrInvalidInput, f.Name, f.Operator)
+}
+
+func validDomainName(name *string) (string, error) {
+
+	result := domainName(name)
+	if !strings.Contains(result, ".") {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedTLD, aws.ToString(name))
+	}
+	if !hostnamePattern.MatchString(result) || len(result) > 253 {
+		return "", fmt.Errorf("%w: %s is not a valid domain name", ErrInvalidInput, aws.ToString(name))
+	}
+
+	return result, nil
+}
+
+// domainName returns a domain name the way Route 53 Domains writes them, without the final dot.
+func domainName(name *string) string {
+	return strings.TrimSuffix(strings.ToLower(aws.ToString(name)), ".")
+}
+
+func defaultTrue(b *bool) *bool {
+	if b == nil {
+		return aws.Bool(true)
+	}
+	return b
+}
+
+func epoch(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func newOperation(domainName string, opType awstypes.OperationType, now time.Time) *OperationData {
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return &OperationData{
+		OperationId:     fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]),
+		DomainName:      domainName,
+		Status:          awstypes.OperationStatusSuccessful,
+		Type:            opType,
+		SubmittedDate:   epoch(now),
+		LastUpdatedDate: epoch(now),
+	}
+}
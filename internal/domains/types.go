@@ -0,0 +1,109 @@
+package domains
+
+import (
+	"slices"
+
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53domains/types"
+)
+
+const transferLockStatus = "clientTransferProhibited"
+
+// DomainData is a registered domain, the dates are seconds since the epoch.
+type DomainData struct {
+	DomainName        string
+	AdminContact      *awstypes.ContactDetail
+	RegistrantContact *awstypes.ContactDetail
+	TechContact       *awstypes.ContactDetail
+	BillingContact    *awstypes.ContactDetail `json:",omitempty"`
+	AdminPrivacy      bool
+	RegistrantPrivacy bool
+	TechPrivacy       bool
+	BillingPrivacy    bool
+	AutoRenew         bool
+	Nameservers       []awstypes.Nameserver
+	StatusList        []string
+	CreationDate      float64
+	ExpirationDate    float64
+	UpdatedDate       float64
+	Tags              []awstypes.Tag `json:",omitempty"`
+}
+
+type OperationData struct {
+	OperationId     string
+	DomainName      string
+	Status          awstypes.OperationStatus
+	Type            awstypes.OperationType
+	SubmittedDate   float64
+	LastUpdatedDate float64
+}
+
+type DomainDetailOutput struct {
+	AdminContact      *awstypes.ContactDetail
+	AdminPrivacy      bool
+	AutoRenew         bool
+	BillingContact    *awstypes.ContactDetail `json:",omitempty"`
+	BillingPrivacy    bool
+	CreationDate      float64
+	DomainName        string
+	ExpirationDate    float64
+	Nameservers       []awstypes.Nameserver
+	RegistrantContact *awstypes.ContactDetail
+	RegistrantPrivacy bool
+	RegistrarName     string
+	StatusList        []string
+	TechContact       *awstypes.ContactDetail
+	TechPrivacy       bool
+	UpdatedDate       float64
+}
+
+type DomainSummaryData struct {
+	AutoRenew    bool
+	DomainName   string
+	Expiry       float64
+	TransferLock bool
+}
+
+type ListDomainsOutput struct {
+	Domains        []DomainSummaryData
+	NextPageMarker *string `json:",omitempty"`
+}
+
+type OperationOutput struct {
+	OperationId string
+}
+
+type ListTagsForDomainOutput struct {
+	TagList []awstypes.Tag
+}
+
+func (d *DomainData) toDomainDetail() *DomainDetailOutput {
+
+	return &DomainDetailOutput{
+		AdminContact:      d.AdminContact,
+		AdminPrivacy:      d.AdminPrivacy,
+		AutoRenew:         d.AutoRenew,
+		BillingContact:    d.BillingContact,
+		BillingPrivacy:    d.BillingPrivacy,
+		CreationDate:      d.CreationDate,
+		DomainName:        d.DomainName,
+		ExpirationDate:    d.ExpirationDate,
+		Nameservers:       d.Nameservers,
+		RegistrantContact: d.RegistrantContact,
+		RegistrantPrivacy: d.RegistrantPrivacy,
+		RegistrarName:     "home-fern",
+		StatusList:        d.StatusList,
+		TechContact:       d.TechContact,
+		TechPrivacy:       d.TechPrivacy,
+		UpdatedDate:       d.UpdatedDate,
+	}
+}
+
+func (d *DomainData) toDomainSummary() DomainSummaryData {
+
+	return DomainSummaryData{
+		AutoRenew:    d.AutoRenew,
+		DomainName:   d.DomainName,
+		Expiry:       d.ExpirationDate,
+		TransferLock: slices.Contains(d.StatusList, transferLockStatus),
+	}
+}
@@ -0,0 +1,184 @@
+package kms
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+var aliasNamePattern = regexp.MustCompile(`^alias/[a-zA-Z0-9/_-]{1,250}$`)
+
+// CreateAlias names a key. Config keys have the aliases of the config, which don't change.
+func (s *Service) CreateAlias(request *awskms.CreateAliasInput) error {
+
+	name, err := s.validAliasName(request.AliasName)
+	if err != nil {
+		return err
+	}
+
+	key, err := s.aliasTarget(request.TargetKeyId)
+	if err != nil {
+		return err
+	}
+
+	now := epoch(time.Now())
+	alias := AliasData{
+		AliasName:       name,
+		TargetKeyId:     key.KeyId,
+		CreationDate:    now,
+		LastUpdatedDate: now,
+	}
+
+	return s.dataStore.putAlias(&alias, false)
+}
+
+// UpdateAlias points an alias to another key of the same key usage.
+func (s *Service) UpdateAlias(request *awskms.UpdateAliasInput) error {
+
+	name := aws.ToString(request.AliasName)
+	if s.configAlias(name) != nil {
+		return fmt.Errorf("%w: alias %s is from the config", ErrUnsupportedOperation, name)
+	}
+
+	alias, err := s.dataStore.getAlias(name)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.keyData(alias.TargetKeyId)
+	if err != nil {
+		return err
+	}
+
+	key, err := s.aliasTarget(request.TargetKeyId)
+	if err != nil {
+		return err
+	}
+
+	if key.KeyUsage != current.KeyUsage || key.isSymmetric() != current.isSymmetric() {
+		return fmt.Errorf("%w: key %s has another key type or key usage than key %s", ErrValidation, key.KeyId, current.KeyId)
+	}
+
+	alias.TargetKeyId = key.KeyId
+	alias.LastUpdatedDate = epoch(time.Now())
+
+	return s.dataStore.putAlias(alias, true)
+}
+
+func (s *Service) DeleteAlias(request *awskms.DeleteAliasInput) error {
+
+	name := aws.ToString(request.AliasName)
+	if s.configAlias(name) != nil {
+		return fmt.Errorf("%w: alias %s is from the config", ErrUnsupportedOperation, name)
+	}
+
+	if _, err := s.dataStore.getAlias(name); err != nil {
+		return err
+	}
+
+	return s.dataStore.deleteAlias(name)
+}
+
+// ListAliases lists the aliases of the config and the aliases made by CreateAlias, by name,
+// all of them or the aliases of a key.
+func (s *Service) ListAliases(request *awskms.ListAliasesInput) (*ListAliasesOutput, error) {
+
+	aliases, err := s.dataStore.findAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range s.keys {
+		aliases = append(aliases, *s.configAlias("alias/" + s.keys[i].Alias))
+	}
+
+	if request.KeyId != nil {
+		key, err := s.keyData(aws.ToString(request.KeyId))
+		if err != nil {
+			return nil, err
+		}
+		aliases = slices.DeleteFunc(aliases, func(alias AliasData) bool { return alias.TargetKeyId != key.KeyId })
+	}
+
+	slices.SortFunc(aliases, func(a, b AliasData) int { return strings.Compare(a.AliasName, b.AliasName) })
+
+	aliases, next, err := page(aliases, func(alias AliasData) string { return alias.AliasName }, request.Marker, request.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ListAliasesOutput{Aliases: []AliasListEntryOutput{}, NextMarker: next, Truncated: next != nil}
+	for _, alias := range aliases {
+		result.Aliases = append(result.Aliases, AliasListEntryOutput{
+			AliasArn:        s.arn(alias.AliasName),
+			AliasName:       alias.AliasName,
+			TargetKeyId:     alias.TargetKeyId,
+			CreationDate:    alias.CreationDate,
+			LastUpdatedDate: alias.LastUpdatedDate,
+		})
+	}
+
+	return &result, nil
+}
+
+// configAlias returns the alias of a config key, or nil.
+func (s *Service) configAlias(name string) *AliasData {
+
+	for _, key := range s.keys {
+		if "alias/"+key.Alias == name {
+			return &AliasData{
+				AliasName:       name,
+				TargetKeyId:     key.KeyId,
+				CreationDate:    s.started,
+				LastUpdatedDate: s.started,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) validAliasName(aliasName *string) (string, error) {
+
+	name := aws.ToString(aliasName)
+	if !aliasNamePattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidAliasName, name)
+	}
+	if strings.HasPrefix(name, "alias/aws/") {
+		return "", fmt.Errorf("%w: alias/aws/ is reserved for AWS managed keys", ErrInvalidAliasName)
+	}
+	if s.configAlias(name) != nil {
+		return "", fmt.Errorf("%w: alias %s", ErrAlreadyExists, name)
+	}
+
+	return name, nil
+}
+
+// aliasTarget returns the key an alias may point to, named by key id or key ARN.
+func (s *Service) aliasTarget(targetKeyId *string) (*KeyData, error) {
+
+	id, err := keyIdOf(aws.ToString(targetKeyId))
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(id, "alias/") {
+		return nil, fmt.Errorf("%w: TargetKeyId must be a key id or key ARN", ErrValidation)
+	}
+
+	key, err := s.keyData(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.KeyState == types.KeyStatePendingDeletion {
+		return nil, fmt.Errorf("%w: key %s is pending deletion", ErrInvalidState, key.KeyId)
+	}
+
+	return key, nil
+}
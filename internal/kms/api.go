@@ -2,6 +2,7 @@ package kms
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"home-fern/internal/awslib"
 	"log"
@@ -23,6 +24,16 @@ func NewKmsApi(service *Service, credentials *awslib.CredentialsProvider) *Api {
 /*
 o encrypt
 o decrypt
+o generate data key
+o generate data key without plaintext
+o generate data key pair
+o generate data key pair without plaintext
+o re-encrypt
+o create, describe, list, enable, disable, schedule and cancel deletion of keys
+o key policies, descriptions and tags
+o key rotation, automatic and on demand
+o create, update, delete and list aliases
+o sign, verify and get public key of asymmetric keys
 */
 
 func (api *Api) Handle(w http.ResponseWriter, r *http.Request) {
@@ -46,18 +57,485 @@ func (api *Api) Handle(w http.ResponseWriter, r *http.Request) {
 	} else if amztarget == "TrentService.Decrypt" {
 
 		api.decrypt(w, r)
+
+	} else if amztarget == "TrentService.GenerateDataKey" {
+
+		api.generateDataKey(w, r)
+
+	} else if amztarget == "TrentService.GenerateDataKeyWithoutPlaintext" {
+
+		api.generateDataKeyWithoutPlaintext(w, r)
+
+	} else if amztarget == "TrentService.GenerateDataKeyPair" {
+
+		api.generateDataKeyPair(w, r)
+
+	} else if amztarget == "TrentService.GenerateDataKeyPairWithoutPlaintext" {
+
+		api.generateDataKeyPairWithoutPlaintext(w, r)
+
+	} else if amztarget == "TrentService.ReEncrypt" {
+
+		api.reEncrypt(w, r)
+
+	} else if amztarget == "TrentService.CancelKeyDeletion" {
+
+		api.cancelKeyDeletion(w, r)
+
+	} else if amztarget == "TrentService.CreateAlias" {
+
+		api.createAlias(w, r)
+
+	} else if amztarget == "TrentService.CreateKey" {
+
+		api.createKey(w, r)
+
+	} else if amztarget == "TrentService.DeleteAlias" {
+
+		api.deleteAlias(w, r)
+
+	} else if amztarget == "TrentService.DescribeKey" {
+
+		api.describeKey(w, r)
+
+	} else if amztarget == "TrentService.DisableKey" {
+
+		api.disableKey(w, r)
+
+	} else if amztarget == "TrentService.EnableKey" {
+
+		api.enableKey(w, r)
+
+	} else if amztarget == "TrentService.GetKeyPolicy" {
+
+		api.getKeyPolicy(w, r)
+
+	} else if amztarget == "TrentService.GetKeyRotationStatus" {
+
+		api.getKeyRotationStatus(w, r)
+
+	} else if amztarget == "TrentService.ListAliases" {
+
+		api.listAliases(w, r)
+
+	} else if amztarget == "TrentService.ListKeys" {
+
+		api.listKeys(w, r)
+
+	} else if amztarget == "TrentService.ListResourceTags" {
+
+		api.listResourceTags(w, r)
+
+	} else if amztarget == "TrentService.PutKeyPolicy" {
+
+		api.putKeyPolicy(w, r)
+
+	} else if amztarget == "TrentService.ScheduleKeyDeletion" {
+
+		api.scheduleKeyDeletion(w, r)
+
+	} else if amztarget == "TrentService.TagResource" {
+
+		api.tagResource(w, r)
+
+	} else if amztarget == "TrentService.UntagResource" {
+
+		api.untagResource(w, r)
+
+	} else if amztarget == "TrentService.UpdateAlias" {
+
+		api.updateAlias(w, r)
+
+	} else if amztarget == "TrentService.UpdateKeyDescription" {
+
+		api.updateKeyDescription(w, r)
+
+	} else if amztarget == "TrentService.EnableKeyRotation" {
+
+		api.enableKeyRotation(w, r)
+
+	} else if amztarget == "TrentService.DisableKeyRotation" {
+
+		api.disableKeyRotation(w, r)
+
+	} else if amztarget == "TrentService.RotateKeyOnDemand" {
+
+		api.rotateKeyOnDemand(w, r)
+
+	} else if amztarget == "TrentService.ListKeyRotations" {
+
+		api.listKeyRotations(w, r)
+
+	} else if amztarget == "TrentService.Sign" {
+
+		api.sign(w, r)
+
+	} else if amztarget == "TrentService.Verify" {
+
+		api.verify(w, r)
+
+	} else if amztarget == "TrentService.GetPublicKey" {
+
+		api.getPublicKey(w, r)
+
+	} else {
+
+		log.Println("Unknown operation", amztarget)
+		awslib.WriteErrorResponseJSON(w, awslib.ApiError{
+			Code:           "ValidationException",
+			Description:    "Unknown operation",
+			HTTPStatusCode: http.StatusBadRequest,
+		}, r.URL, api.credentials.Region)
+	}
+}
+
+func (api *Api) decrypt(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.DecryptInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.Decrypt(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) encrypt(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.EncryptInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.Encrypt(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) generateDataKey(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.GenerateDataKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GenerateDataKey(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) generateDataKeyWithoutPlaintext(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.GenerateDataKeyWithoutPlaintextInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GenerateDataKeyWithoutPlaintext(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) generateDataKeyPair(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.GenerateDataKeyPairInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GenerateDataKeyPair(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) generateDataKeyPairWithoutPlaintext(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.GenerateDataKeyPairWithoutPlaintextInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GenerateDataKeyPairWithoutPlaintext(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) reEncrypt(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.ReEncryptInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ReEncrypt(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) cancelKeyDeletion(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.CancelKeyDeletionInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.CancelKeyDeletion(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) createAlias(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.CreateAliasInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.CreateAlias(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) createKey(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.CreateKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.CreateKey(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) deleteAlias(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.DeleteAliasInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.DeleteAlias(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) describeKey(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.DescribeKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.DescribeKey(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) disableKey(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.DisableKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.DisableKey(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) enableKey(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.EnableKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.EnableKey(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) getKeyPolicy(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.GetKeyPolicyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GetKeyPolicy(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) getKeyRotationStatus(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.GetKeyRotationStatusInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GetKeyRotationStatus(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) listAliases(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.ListAliasesInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ListAliases(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) listKeys(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.ListKeysInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ListKeys(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
 	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
 }
 
-func (api *Api) decrypt(w http.ResponseWriter, r *http.Request) {
+func (api *Api) listResourceTags(w http.ResponseWriter, r *http.Request) {
 
-	var request awskms.DecryptInput
+	var request awskms.ListResourceTagsInput
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response, err := api.service.Decrypt(&request)
+	response, err := api.service.ListResourceTags(&request)
 	if err != nil {
 
 		log.Println("Error:", err)
@@ -68,15 +546,236 @@ func (api *Api) decrypt(w http.ResponseWriter, r *http.Request) {
 	awslib.WriteSuccessResponseJSON(w, response)
 }
 
-func (api *Api) encrypt(w http.ResponseWriter, r *http.Request) {
+func (api *Api) putKeyPolicy(w http.ResponseWriter, r *http.Request) {
 
-	var request awskms.EncryptInput
+	var request awskms.PutKeyPolicyInput
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response, err := api.service.Encrypt(&request)
+	if err := api.service.PutKeyPolicy(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) scheduleKeyDeletion(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.ScheduleKeyDeletionInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ScheduleKeyDeletion(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) tagResource(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.TagResourceInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.TagResource(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) untagResource(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.UntagResourceInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.UntagResource(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) updateAlias(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.UpdateAliasInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.UpdateAlias(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) updateKeyDescription(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.UpdateKeyDescriptionInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.UpdateKeyDescription(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) enableKeyRotation(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.EnableKeyRotationInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.EnableKeyRotation(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) disableKeyRotation(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.DisableKeyRotationInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.service.DisableKeyRotation(&request); err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, struct{}{})
+}
+
+func (api *Api) rotateKeyOnDemand(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.RotateKeyOnDemandInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.RotateKeyOnDemand(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) listKeyRotations(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.ListKeyRotationsInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ListKeyRotations(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) sign(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.SignInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.Sign(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) verify(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.VerifyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.Verify(&request)
+	if err != nil {
+
+		log.Println("Error:", err)
+		awslib.WriteErrorResponseJSON(w, translateToApiError(err), r.URL, api.credentials.Region)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) getPublicKey(w http.ResponseWriter, r *http.Request) {
+
+	var request awskms.GetPublicKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GetPublicKey(&request)
 	if err != nil {
 
 		log.Println("Error:", err)
@@ -89,20 +788,104 @@ func (api *Api) encrypt(w http.ResponseWriter, r *http.Request) {
 
 func translateToApiError(err error) awslib.ApiError {
 
-	switch err {
-	case ErrInvalidKeyId:
+	switch {
+	case errors.Is(err, ErrInvalidKeyId):
 		return awslib.ApiError{
 			Code:           "InvalidKeyIdException",
 			Description:    err.Error(),
 			HTTPStatusCode: http.StatusBadRequest,
 		}
-	case ErrInvalidCiphertextException:
+	case errors.Is(err, ErrInvalidCiphertextException):
 		return awslib.ApiError{
 			Code:           "InvalidCiphertextException",
 			Description:    err.Error(),
 			HTTPStatusCode: http.StatusBadRequest,
 		}
-	case ErrKMSInternalException:
+	case errors.Is(err, ErrIncorrectKey):
+		return awslib.ApiError{
+			Code:           "IncorrectKeyException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrInvalidKeyUsage):
+		return awslib.ApiError{
+			Code:           "InvalidKeyUsageException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrValidation):
+		return awslib.ApiError{
+			Code:           "ValidationException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrUnsupportedOperation):
+		return awslib.ApiError{
+			Code:           "UnsupportedOperationException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrNotFound):
+		return awslib.ApiError{
+			Code:           "NotFoundException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrAlreadyExists):
+		return awslib.ApiError{
+			Code:           "AlreadyExistsException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrDisabled):
+		return awslib.ApiError{
+			Code:           "DisabledException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrInvalidState):
+		return awslib.ApiError{
+			Code:           "KMSInvalidStateException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrInvalidAliasName):
+		return awslib.ApiError{
+			Code:           "InvalidAliasNameException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrInvalidMarker):
+		return awslib.ApiError{
+			Code:           "InvalidMarkerException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrMalformedPolicyDocument):
+		return awslib.ApiError{
+			Code:           "MalformedPolicyDocumentException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrTag):
+		return awslib.ApiError{
+			Code:           "TagException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrLimitExceeded):
+		return awslib.ApiError{
+			Code:           "LimitExceededException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrInvalidSignature):
+		return awslib.ApiError{
+			Code:           "KMSInvalidSignatureException",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrKMSInternalException):
 		return awslib.ApiError{
 			Code:           "KMSInternalException",
 			Description:    err.Error(),
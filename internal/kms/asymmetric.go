@@ -0,0 +1,392 @@
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+const maxRawMessageBytes = 4096
+
+var rsaSigningAlgorithms = []types.SigningAlgorithmSpec{
+	types.SigningAlgorithmSpecRsassaPssSha256,
+	types.SigningAlgorithmSpecRsassaPssSha384,
+	types.SigningAlgorithmSpecRsassaPssSha512,
+	types.SigningAlgorithmSpecRsassaPkcs1V15Sha256,
+	types.SigningAlgorithmSpecRsassaPkcs1V15Sha384,
+	types.SigningAlgorithmSpecRsassaPkcs1V15Sha512,
+}
+
+var rsaEncryptionAlgorithms = []types.EncryptionAlgorithmSpec{
+	types.EncryptionAlgorithmSpecRsaesOaepSha1,
+	types.EncryptionAlgorithmSpecRsaesOaepSha256,
+}
+
+// signingAlgorithms are the signing algorithms of the asymmetric key specs.
+var signingAlgorithms = map[types.KeySpec][]types.SigningAlgorithmSpec{
+	types.KeySpecRsa2048:       rsaSigningAlgorithms,
+	types.KeySpecRsa3072:       rsaSigningAlgorithms,
+	types.KeySpecRsa4096:       rsaSigningAlgorithms,
+	types.KeySpecEccNistP256:   {types.SigningAlgorithmSpecEcdsaSha256},
+	types.KeySpecEccNistP384:   {types.SigningAlgorithmSpecEcdsaSha384},
+	types.KeySpecEccNistP521:   {types.SigningAlgorithmSpecEcdsaSha512},
+	types.KeySpecEccSecgP256k1: {types.SigningAlgorithmSpecEcdsaSha256},
+}
+
+var signingHashes = map[types.SigningAlgorithmSpec]crypto.Hash{
+	types.SigningAlgorithmSpecRsassaPssSha256:      crypto.SHA256,
+	types.SigningAlgorithmSpecRsassaPssSha384:      crypto.SHA384,
+	types.SigningAlgorithmSpecRsassaPssSha512:      crypto.SHA512,
+	types.SigningAlgorithmSpecRsassaPkcs1V15Sha256: crypto.SHA256,
+	types.SigningAlgorithmSpecRsassaPkcs1V15Sha384: crypto.SHA384,
+	types.SigningAlgorithmSpecRsassaPkcs1V15Sha512: crypto.SHA512,
+	types.SigningAlgorithmSpecEcdsaSha256:          crypto.SHA256,
+	types.SigningAlgorithmSpecEcdsaSha384:          crypto.SHA384,
+	types.SigningAlgorithmSpecEcdsaSha512:          crypto.SHA512,
+}
+
+var oaepHashes = map[types.EncryptionAlgorithmSpec]crypto.Hash{
+	types.EncryptionAlgorithmSpecRsaesOaepSha1:   crypto.SHA1,
+	types.EncryptionAlgorithmSpecRsaesOaepSha256: crypto.SHA256,
+}
+
+// Sign signs a message, or the digest of one, with the private key of an asymmetric key. ECDSA
+// signatures are DER encoded and RSASSA-PSS salts are as long as the digest, as in AWS.
+func (s *Service) Sign(request *awskms.SignInput) (*awskms.SignOutput, error) {
+
+	key, digest, err := s.signingDigest(aws.ToString(request.KeyId), request.Message, request.MessageType, request.SigningAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := key.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", key.KeyId, ErrKMSInternalException)
+	}
+
+	var opts crypto.SignerOpts = signingHashes[request.SigningAlgorithm]
+	if isPSS(request.SigningAlgorithm) {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: signingHashes[request.SigningAlgorithm]}
+	}
+
+	signature, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("signing failed: %w", ErrKMSInternalException)
+	}
+
+	result := awskms.SignOutput{
+		KeyId:            aws.String(s.keyArn(key)),
+		Signature:        signature,
+		SigningAlgorithm: request.SigningAlgorithm,
+	}
+
+	return &result, nil
+}
+
+// Verify checks a signature of Sign; an invalid signature is an error, as in AWS.
+func (s *Service) Verify(request *awskms.VerifyInput) (*awskms.VerifyOutput, error) {
+
+	key, digest, err := s.signingDigest(aws.ToString(request.KeyId), request.Message, request.MessageType, request.SigningAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(request.Signature) == 0 {
+		return nil, fmt.Errorf("%w: Signature is required", ErrValidation)
+	}
+
+	signer, err := key.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", key.KeyId, ErrKMSInternalException)
+	}
+
+	hash := signingHashes[request.SigningAlgorithm]
+
+	var valid bool
+	switch public := signer.Public().(type) {
+	case *rsa.PublicKey:
+		if isPSS(request.SigningAlgorithm) {
+			valid = rsa.VerifyPSS(public, hash, digest, request.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(public, hash, digest, request.Signature) == nil
+		}
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(public, digest, request.Signature)
+	case *secp256k1.PublicKey:
+		valid = verifySecp256k1(public, digest, request.Signature)
+	}
+
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	result := awskms.VerifyOutput{
+		KeyId:            aws.String(s.keyArn(key)),
+		SignatureValid:   true,
+		SigningAlgorithm: request.SigningAlgorithm,
+	}
+
+	return &result, nil
+}
+
+// GetPublicKey returns the DER encoded X.509 SubjectPublicKeyInfo of an asymmetric key.
+func (s *Service) GetPublicKey(request *awskms.GetPublicKeyInput) (*awskms.GetPublicKeyOutput, error) {
+
+	key, err := s.FindKey(aws.ToString(request.KeyId))
+	if err != nil {
+		return nil, err
+	}
+
+	if key.IsSymmetric() {
+		return nil, fmt.Errorf("%w: key %s is symmetric", ErrUnsupportedOperation, key.KeyId)
+	}
+
+	signer, err := key.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", key.KeyId, ErrKMSInternalException)
+	}
+
+	public, err := marshalPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	result := awskms.GetPublicKeyOutput{
+		CustomerMasterKeySpec: types.CustomerMasterKeySpec(key.KeySpec),
+		KeyId:                 aws.String(s.keyArn(key)),
+		KeySpec:               key.KeySpec,
+		KeyUsage:              key.usage(),
+		PublicKey:             public,
+	}
+	result.EncryptionAlgorithms, result.SigningAlgorithms = keyAlgorithms(key.KeySpec, key.usage())
+
+	return &result, nil
+}
+
+// signingDigest returns the key which signs with the signing algorithm, and the digest to sign:
+// a RAW message is hashed here, a DIGEST is the hash already.
+func (s *Service) signingDigest(keyId string, message []byte, messageType types.MessageType,
+	algorithm types.SigningAlgorithmSpec) (*KmsKey, []byte, error) {
+
+	if len(message) == 0 {
+		return nil, nil, fmt.Errorf("%w: Message is required", ErrValidation)
+	}
+	if algorithm == "" {
+		return nil, nil, fmt.Errorf("%w: SigningAlgorithm is required", ErrValidation)
+	}
+
+	key, err := s.FindKey(keyId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key.usage() != types.KeyUsageTypeSignVerify {
+		return nil, nil, fmt.Errorf("%w: key %s doesn't sign", ErrInvalidKeyUsage, key.KeyId)
+	}
+	if !slices.Contains(signingAlgorithms[key.KeySpec], algorithm) {
+		return nil, nil, fmt.Errorf("%w: signing algorithm %s is not valid for key %s", ErrInvalidKeyUsage, algorithm, key.KeyId)
+	}
+
+	hash := signingHashes[algorithm]
+
+	switch messageType {
+	case types.MessageTypeRaw, "":
+		if len(message) > maxRawMessageBytes {
+			return nil, nil, fmt.Errorf("%w: Message is longer than %d bytes, sign its digest", ErrValidation, maxRawMessageBytes)
+		}
+		h := hash.New()
+		h.Write(message)
+		return key, h.Sum(nil), nil
+	case types.MessageTypeDigest:
+		if len(message) != hash.Size() {
+			return nil, nil, fmt.Errorf("%w: the digest of %s must be %d bytes", ErrValidation, algorithm, hash.Size())
+		}
+		return key, message, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: MessageType %s is not valid", ErrValidation, messageType)
+}
+
+// encryptOAEP encrypts plaintext with the public key of an RSA key. The ciphertext is bare, as
+// in AWS, it decrypts with the KeyId only.
+func encryptOAEP(key *KmsKey, plaintext []byte, algorithm types.EncryptionAlgorithmSpec) ([]byte, error) {
+
+	private, err := rsaKey(key, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := rsa.EncryptOAEP(oaepHashes[algorithm].New(), rand.Reader, &private.PublicKey, plaintext, nil)
+	if errors.Is(err, rsa.ErrMessageTooLong) {
+		return nil, fmt.Errorf("%w: Plaintext is too long for %s with key %s", ErrValidation, algorithm, key.KeyId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", ErrKMSInternalException)
+	}
+
+	return blob, nil
+}
+
+func decryptOAEP(key *KmsKey, blob []byte, algorithm types.EncryptionAlgorithmSpec) ([]byte, error) {
+
+	private, err := rsaKey(key, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := rsa.DecryptOAEP(oaepHashes[algorithm].New(), nil, private, blob, nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertextException
+	}
+
+	return plaintext, nil
+}
+
+// rsaKey returns the private key of an RSA key which encrypts with the encryption algorithm.
+func rsaKey(key *KmsKey, algorithm types.EncryptionAlgorithmSpec) (*rsa.PrivateKey, error) {
+
+	if !slices.Contains(rsaEncryptionAlgorithms, algorithm) {
+		return nil, fmt.Errorf("%w: encryption algorithm %s is not valid for key %s", ErrInvalidKeyUsage, algorithm, key.KeyId)
+	}
+
+	signer, err := key.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", key.KeyId, ErrKMSInternalException)
+	}
+
+	private, ok := signer.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: key %s is not an RSA key", ErrInvalidKeyUsage, key.KeyId)
+	}
+
+	return private, nil
+}
+
+// keyAlgorithms returns the encryption or the signing algorithms of a key.
+func keyAlgorithms(spec types.KeySpec, usage types.KeyUsageType) ([]types.EncryptionAlgorithmSpec, []types.SigningAlgorithmSpec) {
+
+	switch {
+	case spec == types.KeySpecSymmetricDefault:
+		return []types.EncryptionAlgorithmSpec{types.EncryptionAlgorithmSpecSymmetricDefault}, nil
+	case usage == types.KeyUsageTypeEncryptDecrypt:
+		return rsaEncryptionAlgorithms, nil
+	}
+
+	return nil, signingAlgorithms[spec]
+}
+
+func isPSS(algorithm types.SigningAlgorithmSpec) bool {
+
+	switch algorithm {
+	case types.SigningAlgorithmSpecRsassaPssSha256, types.SigningAlgorithmSpecRsassaPssSha384, types.SigningAlgorithmSpecRsassaPssSha512:
+		return true
+	}
+	return false
+}
+
+// newPrivateKey returns a new private key of an asymmetric key spec; the key pair specs of data
+// key pairs are key specs too.
+func newPrivateKey(spec types.KeySpec) (crypto.Signer, error) {
+
+	var private crypto.Signer
+	var err error
+
+	switch spec {
+	case types.KeySpecRsa2048:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case types.KeySpecRsa3072:
+		private, err = rsa.GenerateKey(rand.Reader, 3072)
+	case types.KeySpecRsa4096:
+		private, err = rsa.GenerateKey(rand.Reader, 4096)
+	case types.KeySpecEccNistP256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case types.KeySpecEccNistP384:
+		private, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case types.KeySpecEccNistP521:
+		private, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case types.KeySpecEccSecgP256k1:
+		private, err = newSecp256k1Key()
+	default:
+		return nil, fmt.Errorf("%w: key spec %s", ErrUnsupportedOperation, spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", ErrKMSInternalException)
+	}
+
+	return private, nil
+}
+
+// privateKeySpec returns the key spec of a private key, or "" when it has none.
+func privateKeySpec(private crypto.Signer) types.KeySpec {
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		switch private.N.BitLen() {
+		case 2048:
+			return types.KeySpecRsa2048
+		case 3072:
+			return types.KeySpecRsa3072
+		case 4096:
+			return types.KeySpecRsa4096
+		}
+	case *ecdsa.PrivateKey:
+		switch private.Curve {
+		case elliptic.P256():
+			return types.KeySpecEccNistP256
+		case elliptic.P384():
+			return types.KeySpecEccNistP384
+		case elliptic.P521():
+			return types.KeySpecEccNistP521
+		}
+	case *secp256k1Signer:
+		return types.KeySpecEccSecgP256k1
+	}
+	return ""
+}
+
+// marshalPrivateKey returns the PKCS #8 DER private key.
+func marshalPrivateKey(private crypto.Signer) ([]byte, error) {
+
+	var der []byte
+	var err error
+
+	if key, ok := private.(*secp256k1Signer); ok {
+		der, err = marshalSecp256k1PrivateKey(key)
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(private)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", ErrKMSInternalException)
+	}
+
+	return der, nil
+}
+
+// marshalPublicKey returns the X.509 SubjectPublicKeyInfo DER public key.
+func marshalPublicKey(public crypto.PublicKey) ([]byte, error) {
+
+	var der []byte
+	var err error
+
+	if key, ok := public.(*secp256k1.PublicKey); ok {
+		der, err = marshalSecp256k1PublicKey(key)
+	} else {
+		der, err = x509.MarshalPKIXPublicKey(public)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", ErrKMSInternalException)
+	}
+
+	return der, nil
+}
@@ -0,0 +1,181 @@
+package kms
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"home-fern/internal/datastore"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	ds, err := datastore.New(filepath.Join(t.TempDir(), "fern.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ds.Close() })
+
+	keys := []KmsKey{{KeyId: "844c1364-08b8-11f0-aeb7-33cf4b255e16", Alias: "aws/ssm", Key: "DkVsBYNRbORxQ6vtjUCex54YdfYfxd3c5PcP/ZruwUs="}}
+	return NewService(keys, "", "us-east-1", "000000000000", ds)
+}
+
+func TestSignVerify(t *testing.T) {
+	s := newTestService(t)
+	message := []byte("a message to sign")
+
+	for spec, algorithms := range signingAlgorithms {
+		t.Run(string(spec), func(t *testing.T) {
+			created, err := s.CreateKey(&awskms.CreateKeyInput{KeySpec: spec, KeyUsage: types.KeyUsageTypeSignVerify})
+			if err != nil {
+				t.Fatalf("CreateKey: %v", err)
+			}
+			keyId := aws.String(created.KeyMetadata.KeyId)
+
+			public, err := s.GetPublicKey(&awskms.GetPublicKeyInput{KeyId: keyId})
+			if err != nil {
+				t.Fatalf("GetPublicKey: %v", err)
+			}
+			if public.KeySpec != spec || public.KeyUsage != types.KeyUsageTypeSignVerify ||
+				!slices.Equal(public.SigningAlgorithms, algorithms) || len(public.EncryptionAlgorithms) != 0 {
+				t.Errorf("GetPublicKey() = %s %s %v %v", public.KeySpec, public.KeyUsage, public.SigningAlgorithms, public.EncryptionAlgorithms)
+			}
+			verify := publicKeyVerifier(t, spec, public.PublicKey)
+
+			for _, algorithm := range algorithms {
+				hash := signingHashes[algorithm].New()
+				hash.Write(message)
+				digest := hash.Sum(nil)
+
+				tests := []struct {
+					name        string
+					message     []byte
+					messageType types.MessageType
+				}{
+					{"raw", message, types.MessageTypeRaw},
+					{"default message type", message, ""},
+					{"digest", digest, types.MessageTypeDigest},
+				}
+
+				for _, tt := range tests {
+					t.Run(string(algorithm)+" "+tt.name, func(t *testing.T) {
+						signed, err := s.Sign(&awskms.SignInput{KeyId: keyId, Message: tt.message, MessageType: tt.messageType, SigningAlgorithm: algorithm})
+						if err != nil {
+							t.Fatalf("Sign: %v", err)
+						}
+						if !verify(algorithm, digest, signed.Signature) {
+							t.Error("signature doesn't verify with the public key")
+						}
+
+						verified, err := s.Verify(&awskms.VerifyInput{KeyId: keyId, Message: tt.message, MessageType: tt.messageType,
+							SigningAlgorithm: algorithm, Signature: signed.Signature})
+						if err != nil || !verified.SignatureValid {
+							t.Errorf("Verify() = %v, %v", verified, err)
+						}
+
+						tampered := slices.Clone(signed.Signature)
+						tampered[len(tampered)/2] ^= 1
+						_, err = s.Verify(&awskms.VerifyInput{KeyId: keyId, Message: tt.message, MessageType: tt.messageType,
+							SigningAlgorithm: algorithm, Signature: tampered})
+						if !errors.Is(err, ErrInvalidSignature) {
+							t.Errorf("Verify() of a tampered signature = %v, want %v", err, ErrInvalidSignature)
+						}
+					})
+				}
+			}
+		})
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	s := newTestService(t)
+
+	created, err := s.CreateKey(&awskms.CreateKeyInput{KeySpec: types.KeySpecEccNistP256})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	keyId := aws.String(created.KeyMetadata.KeyId)
+	encrypting, err := s.CreateKey(&awskms.CreateKeyInput{KeySpec: types.KeySpecRsa2048, KeyUsage: types.KeyUsageTypeEncryptDecrypt})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request awskms.SignInput
+		want    error
+	}{
+		{"no message", awskms.SignInput{KeyId: keyId, SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256}, ErrValidation},
+		{"no algorithm", awskms.SignInput{KeyId: keyId, Message: []byte("m")}, ErrValidation},
+		{"algorithm of another spec", awskms.SignInput{KeyId: keyId, Message: []byte("m"), SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha384}, ErrInvalidKeyUsage},
+		{"short digest", awskms.SignInput{KeyId: keyId, Message: []byte("m"), MessageType: types.MessageTypeDigest, SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256}, ErrValidation},
+		{"long raw message", awskms.SignInput{KeyId: keyId, Message: make([]byte, maxRawMessageBytes+1), SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256}, ErrValidation},
+		{"symmetric key", awskms.SignInput{KeyId: aws.String("alias/aws/ssm"), Message: []byte("m"), SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256}, ErrInvalidKeyUsage},
+		{"encrypting key", awskms.SignInput{KeyId: aws.String(encrypting.KeyMetadata.KeyId), Message: []byte("m"), SigningAlgorithm: types.SigningAlgorithmSpecRsassaPssSha256}, ErrInvalidKeyUsage},
+		{"unknown key", awskms.SignInput{KeyId: aws.String("alias/none"), Message: []byte("m"), SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256}, ErrInvalidKeyId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Sign(&tt.request); !errors.Is(err, tt.want) {
+				t.Errorf("Sign() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := s.GetPublicKey(&awskms.GetPublicKeyInput{KeyId: aws.String("alias/aws/ssm")}); !errors.Is(err, ErrUnsupportedOperation) {
+		t.Errorf("GetPublicKey() of a symmetric key = %v, want %v", err, ErrUnsupportedOperation)
+	}
+}
+
+// publicKeyVerifier parses a public key of GetPublicKey and returns a verifier of its signatures.
+func publicKeyVerifier(t *testing.T, spec types.KeySpec, der []byte) func(types.SigningAlgorithmSpec, []byte, []byte) bool {
+	t.Helper()
+
+	if spec == types.KeySpecEccSecgP256k1 {
+		var info subjectPublicKeyInfo
+		if _, err := asn1.Unmarshal(der, &info); err != nil {
+			t.Fatalf("invalid public key: %v", err)
+		}
+		public, err := secp256k1.ParsePubKey(info.PublicKey.Bytes)
+		if err != nil {
+			t.Fatalf("invalid secp256k1 public key: %v", err)
+		}
+		return func(_ types.SigningAlgorithmSpec, digest []byte, signature []byte) bool {
+			return verifySecp256k1(public, digest, signature)
+		}
+	}
+
+	public, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatalf("invalid public key: %v", err)
+	}
+
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		return func(algorithm types.SigningAlgorithmSpec, digest []byte, signature []byte) bool {
+			hash := signingHashes[algorithm]
+			if isPSS(algorithm) {
+				return rsa.VerifyPSS(public, hash, digest, signature, &rsa.PSSOptions{SaltLength: hash.Size()}) == nil
+			}
+			return rsa.VerifyPKCS1v15(public, hash, digest, signature) == nil
+		}
+	case *ecdsa.PublicKey:
+		return func(_ types.SigningAlgorithmSpec, digest []byte, signature []byte) bool {
+			return ecdsa.VerifyASN1(public, digest, signature)
+		}
+	}
+
+	t.Fatalf("unexpected public key %T", public)
+	return nil
+}
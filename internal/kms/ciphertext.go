@@ -0,0 +1,125 @@
+package kms
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// Ciphertext blobs describe themselves, as AWS KMS ones do, so they decrypt without a KeyId:
+// the magic and a version byte, then the key id, the encryption algorithm and the nonce, each
+// after its length in one byte, and the sealed data. Version 2 blobs of rotated keys hold the
+// key material version, in decimal, after the encryption algorithm. Version 3 blobs always hold
+// the key material version, and their header is authenticated with the encryption context.
+//
+// Earlier blobs held the nonce and sealed data only; they still decrypt with the right key.
+
+const (
+	ciphertextVersion              = 1
+	ciphertextVersionRotated       = 2
+	ciphertextVersionAuthenticated = 3
+)
+
+var ciphertextMagic = []byte("fern")
+
+type ciphertextEnvelope struct {
+	Version    byte
+	KeyId      string
+	Algorithm  types.EncryptionAlgorithmSpec
+	KeyVersion int
+	Nonce      []byte
+	Sealed     []byte
+}
+
+// header returns the blob up to the sealed data.
+func (env *ciphertextEnvelope) header() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.Write(ciphertextMagic)
+	buf.WriteByte(env.Version)
+
+	fields := [][]byte{[]byte(env.KeyId), []byte(env.Algorithm), env.Nonce}
+	if env.Version != ciphertextVersion {
+		fields = slices.Insert(fields, 2, []byte(strconv.Itoa(env.KeyVersion)))
+	}
+
+	for _, field := range fields {
+		if len(field) > 255 {
+			return nil, fmt.Errorf("ciphertext field of %d bytes is too long", len(field))
+		}
+		buf.WriteByte(byte(len(field)))
+		buf.Write(field)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (env *ciphertextEnvelope) marshal() ([]byte, error) {
+	header, err := env.header()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, env.Sealed...), nil
+}
+
+// additionalData returns the additional authenticated data of the sealed data: the header
+// and aad, or aad alone for blobs of earlier versions.
+func (env *ciphertextEnvelope) additionalData(aad []byte) ([]byte, error) {
+	if env.Version != ciphertextVersionAuthenticated {
+		return aad, nil
+	}
+
+	header, err := env.header()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, aad...), nil
+}
+
+// parseCiphertext returns the envelope of blob, or false when blob has an earlier format.
+func parseCiphertext(blob []byte) (*ciphertextEnvelope, bool) {
+	rest, found := bytes.CutPrefix(blob, ciphertextMagic)
+	if !found || len(rest) == 0 || rest[0] < ciphertextVersion || rest[0] > ciphertextVersionAuthenticated {
+		return nil, false
+	}
+	version := rest[0]
+	rest = rest[1:]
+
+	fields := make([][]byte, 3)
+	if version != ciphertextVersion {
+		fields = make([][]byte, 4)
+	}
+	for i := range fields {
+		if len(rest) == 0 || len(rest) < 1+int(rest[0]) {
+			return nil, false
+		}
+		fields[i], rest = rest[1:1+int(rest[0])], rest[1+int(rest[0]):]
+	}
+
+	if len(fields[0]) == 0 {
+		return nil, false
+	}
+
+	env := ciphertextEnvelope{
+		Version:   version,
+		KeyId:     string(fields[0]),
+		Algorithm: types.EncryptionAlgorithmSpec(fields[1]),
+		Nonce:     fields[len(fields)-1],
+		Sealed:    rest,
+	}
+
+	if version != ciphertextVersion {
+		keyVersion, err := strconv.Atoi(string(fields[2]))
+		if err != nil || keyVersion < 0 || (keyVersion == 0 && version == ciphertextVersionRotated) {
+			return nil, false
+		}
+		env.KeyVersion = keyVersion
+	}
+
+	return &env, true
+}
@@ -0,0 +1,161 @@
+package kms
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+func TestCiphertextRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		env  ciphertextEnvelope
+	}{
+		{"version 1", ciphertextEnvelope{Version: ciphertextVersion, KeyId: "key-1", Algorithm: types.EncryptionAlgorithmSpecSymmetricDefault, Nonce: []byte("123456789012"), Sealed: []byte("sealed")}},
+		{"version 2", ciphertextEnvelope{Version: ciphertextVersionRotated, KeyId: "key-1", Algorithm: types.EncryptionAlgorithmSpecSymmetricDefault, KeyVersion: 3, Nonce: []byte("123456789012"), Sealed: []byte("sealed")}},
+		{"version 3", ciphertextEnvelope{Version: ciphertextVersionAuthenticated, KeyId: "key-1", Algorithm: types.EncryptionAlgorithmSpecSymmetricDefault, Nonce: []byte("123456789012"), Sealed: []byte("sealed")}},
+		{"version 3 rotated", ciphertextEnvelope{Version: ciphertextVersionAuthenticated, KeyId: "key-1", Algorithm: types.EncryptionAlgorithmSpecSymmetricDefault, KeyVersion: 12, Nonce: []byte("123456789012"), Sealed: []byte("sealed")}},
+		{"empty sealed data", ciphertextEnvelope{Version: ciphertextVersionAuthenticated, KeyId: "alias/a", Algorithm: "X", Nonce: []byte{}, Sealed: []byte{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := tt.env.marshal()
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			env, ok := parseCiphertext(blob)
+			if !ok {
+				t.Fatalf("parseCiphertext(%q) failed", blob)
+			}
+			if env.Version != tt.env.Version || env.KeyId != tt.env.KeyId || env.Algorithm != tt.env.Algorithm ||
+				env.KeyVersion != tt.env.KeyVersion || !bytes.Equal(env.Nonce, tt.env.Nonce) || !bytes.Equal(env.Sealed, tt.env.Sealed) {
+				t.Errorf("parseCiphertext() = %+v, want %+v", env, tt.env)
+			}
+		})
+	}
+}
+
+func TestParseCiphertextInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		blob []byte
+	}{
+		{"empty", nil},
+		{"nonce and sealed data", []byte("123456789012sealed")},
+		{"magic only", []byte("fern")},
+		{"unknown version", []byte("fern\x04\x01k\x01a\x00")},
+		{"truncated field", []byte("fern\x01\x05key")},
+		{"missing nonce", []byte("fern\x01\x01k\x01a")},
+		{"empty key id", []byte("fern\x01\x00\x01a\x00")},
+		{"version 2 without key version", []byte("fern\x02\x01k\x01a\x010\x00")},
+		{"non decimal key version", []byte("fern\x03\x01k\x01a\x01x\x00")},
+		{"negative key version", []byte("fern\x03\x01k\x01a\x02-1\x00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if env, ok := parseCiphertext(tt.blob); ok {
+				t.Errorf("parseCiphertext(%q) = %+v, want failure", tt.blob, env)
+			}
+		})
+	}
+}
+
+func TestMarshalCiphertextFieldTooLong(t *testing.T) {
+	env := ciphertextEnvelope{Version: ciphertextVersionAuthenticated, KeyId: string(make([]byte, 256))}
+	if _, err := env.marshal(); err == nil {
+		t.Error("marshal() of a 256 byte key id succeeded")
+	}
+}
+
+func TestKeyEncryptDecrypt(t *testing.T) {
+	key := KmsKey{
+		KeyId:        "key-1",
+		Key:          "rvl7SbrNObB5MMQDUUAoInJXpyCA3QDqELyuwa2G48M=",
+		PreviousKeys: []string{"DkVsBYNRbORxQ6vtjUCex54YdfYfxd3c5PcP/ZruwUs="},
+	}
+	first := KmsKey{KeyId: key.KeyId, Key: key.PreviousKeys[0]}
+	aad := []byte(`{"purpose":"test"}`)
+
+	blob, err := key.Encrypt([]byte("secret"), aad)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	env, ok := parseCiphertext(blob)
+	if !ok || env.Version != ciphertextVersionAuthenticated || env.KeyVersion != 1 {
+		t.Fatalf("Encrypt() envelope = %+v", env)
+	}
+
+	// blobs of the version 1 and 2 formats, and of the format before them, seal aad alone
+	legacy := func(version byte, keyVersion int, material string) []byte {
+		aesGCM, err := key.aead(material)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := make([]byte, aesGCM.NonceSize())
+		sealed := aesGCM.Seal(nil, nonce, []byte("secret"), aad)
+		if version == 0 {
+			return append(nonce, sealed...)
+		}
+		env := ciphertextEnvelope{Version: version, KeyId: key.KeyId, Algorithm: types.EncryptionAlgorithmSpecSymmetricDefault,
+			KeyVersion: keyVersion, Nonce: nonce, Sealed: sealed}
+		blob, err := env.marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return blob
+	}
+
+	// a version 3 blob rewritten as a version 2 one, its header no longer authenticated
+	downgraded := bytes.Clone(blob)
+	downgraded[len(ciphertextMagic)] = ciphertextVersionRotated
+
+	firstBlob, err := first.Encrypt([]byte("secret"), aad)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		blob    []byte
+		aad     []byte
+		wantErr bool
+	}{
+		{"current", blob, aad, false},
+		{"first key material", firstBlob, aad, false},
+		{"version 1", legacy(ciphertextVersion, 0, key.PreviousKeys[0]), aad, false},
+		{"version 2", legacy(ciphertextVersionRotated, 1, key.Key), aad, false},
+		{"earlier format", legacy(0, 0, key.PreviousKeys[0]), aad, false},
+		{"other encryption context", blob, []byte(`{"purpose":"other"}`), true},
+		{"no encryption context", blob, nil, true},
+		{"downgraded version", downgraded, aad, true},
+		{"unknown key version", legacy(ciphertextVersionRotated, 2, key.Key), aad, true},
+		{"tampered sealed data", append(bytes.Clone(blob[:len(blob)-1]), blob[len(blob)-1]^1), aad, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := key.Decrypt(tt.blob, tt.aad)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Decrypt() = %q, want error", plaintext)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if string(plaintext) != "secret" {
+				t.Errorf("Decrypt() = %q, want %q", plaintext, "secret")
+			}
+		})
+	}
+
+	other := KmsKey{KeyId: "key-2", Key: key.Key}
+	if _, err := other.Decrypt(blob, aad); err == nil {
+		t.Error("Decrypt() with another key succeeded")
+	}
+}
@@ -0,0 +1,156 @@
+package kms
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+const maxDataKeyBytes = 1024
+
+// GenerateDataKey returns a random data key, in plaintext and encrypted with a KMS key, for
+// envelope encryption.
+func (s *Service) GenerateDataKey(request *awskms.GenerateDataKeyInput) (*awskms.GenerateDataKeyOutput, error) {
+
+	key, plaintext, blob, err := s.generateDataKey(
+		aws.ToString(request.KeyId), request.KeySpec, request.NumberOfBytes, request.EncryptionContext)
+	if err != nil {
+		return nil, err
+	}
+
+	result := awskms.GenerateDataKeyOutput{
+		CiphertextBlob: blob,
+		KeyId:          aws.String(s.keyArn(key)),
+		Plaintext:      plaintext,
+	}
+
+	return &result, nil
+}
+
+func (s *Service) GenerateDataKeyWithoutPlaintext(
+	request *awskms.GenerateDataKeyWithoutPlaintextInput) (*awskms.GenerateDataKeyWithoutPlaintextOutput, error) {
+
+	key, _, blob, err := s.generateDataKey(
+		aws.ToString(request.KeyId), request.KeySpec, request.NumberOfBytes, request.EncryptionContext)
+	if err != nil {
+		return nil, err
+	}
+
+	result := awskms.GenerateDataKeyWithoutPlaintextOutput{
+		CiphertextBlob: blob,
+		KeyId:          aws.String(s.keyArn(key)),
+	}
+
+	return &result, nil
+}
+
+// GenerateDataKeyPair returns a new key pair, the private key in plaintext and encrypted with a
+// KMS key. Keys are DER encoded, PKCS #8 private keys and X.509 SubjectPublicKeyInfo public keys.
+func (s *Service) GenerateDataKeyPair(request *awskms.GenerateDataKeyPairInput) (*awskms.GenerateDataKeyPairOutput, error) {
+
+	key, private, blob, public, err := s.generateDataKeyPair(
+		aws.ToString(request.KeyId), request.KeyPairSpec, request.EncryptionContext)
+	if err != nil {
+		return nil, err
+	}
+
+	result := awskms.GenerateDataKeyPairOutput{
+		KeyId:                    aws.String(s.keyArn(key)),
+		KeyPairSpec:              request.KeyPairSpec,
+		PrivateKeyCiphertextBlob: blob,
+		PrivateKeyPlaintext:      private,
+		PublicKey:                public,
+	}
+
+	return &result, nil
+}
+
+func (s *Service) GenerateDataKeyPairWithoutPlaintext(
+	request *awskms.GenerateDataKeyPairWithoutPlaintextInput) (*awskms.GenerateDataKeyPairWithoutPlaintextOutput, error) {
+
+	key, _, blob, public, err := s.generateDataKeyPair(
+		aws.ToString(request.KeyId), request.KeyPairSpec, request.EncryptionContext)
+	if err != nil {
+		return nil, err
+	}
+
+	result := awskms.GenerateDataKeyPairWithoutPlaintextOutput{
+		KeyId:                    aws.String(s.keyArn(key)),
+		KeyPairSpec:              request.KeyPairSpec,
+		PrivateKeyCiphertextBlob: blob,
+		PublicKey:                public,
+	}
+
+	return &result, nil
+}
+
+// generateDataKey returns a data key of keySpec or numberOfBytes, exactly one of them is given,
+// and the data key encrypted with the KMS key.
+func (s *Service) generateDataKey(keyId string, keySpec types.DataKeySpec, numberOfBytes *int32,
+	encryptionContext map[string]string) (*KmsKey, []byte, []byte, error) {
+
+	var size int
+	switch {
+	case keySpec != "" && numberOfBytes != nil:
+		return nil, nil, nil, fmt.Errorf("%w: KeySpec and NumberOfBytes are mutually exclusive", ErrValidation)
+	case keySpec == types.DataKeySpecAes128:
+		size = 16
+	case keySpec == types.DataKeySpecAes256:
+		size = 32
+	case keySpec != "":
+		return nil, nil, nil, fmt.Errorf("%w: KeySpec %s is not valid", ErrValidation, keySpec)
+	case numberOfBytes == nil:
+		return nil, nil, nil, fmt.Errorf("%w: KeySpec or NumberOfBytes is required", ErrValidation)
+	case *numberOfBytes < 1 || *numberOfBytes > maxDataKeyBytes:
+		return nil, nil, nil, fmt.Errorf("%w: NumberOfBytes must be between 1 and %d", ErrValidation, maxDataKeyBytes)
+	default:
+		size = int(*numberOfBytes)
+	}
+
+	plaintext := make([]byte, size)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate data key: %w", ErrKMSInternalException)
+	}
+
+	key, blob, err := s.encrypt(keyId, plaintext, encryptionContext, types.EncryptionAlgorithmSpecSymmetricDefault)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return key, plaintext, blob, nil
+}
+
+// generateDataKeyPair returns a private key, the private key encrypted with the KMS key and the
+// public key.
+func (s *Service) generateDataKeyPair(keyId string, keyPairSpec types.DataKeyPairSpec,
+	encryptionContext map[string]string) (*KmsKey, []byte, []byte, []byte, error) {
+
+	if keyPairSpec == "" {
+		return nil, nil, nil, nil, fmt.Errorf("%w: KeyPairSpec is required", ErrValidation)
+	}
+
+	private, err := newPrivateKey(types.KeySpec(keyPairSpec))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	privateDer, err := marshalPrivateKey(private)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	publicDer, err := marshalPublicKey(private.Public())
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	key, blob, err := s.encrypt(keyId, privateDer, encryptionContext, types.EncryptionAlgorithmSpecSymmetricDefault)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return key, privateDer, blob, publicDer, nil
+}
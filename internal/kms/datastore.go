@@ -0,0 +1,226 @@
+package kms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"home-fern/internal/datastore"
+	"io"
+
+	"go.etcd.io/bbolt"
+)
+
+// Aliases are stored under their name, "/alias/..."; the alias names begin with "alias/".
+const (
+	KeyPrefix   = "/key/"
+	AliasPrefix = "/alias/"
+)
+
+type dataStore struct {
+	ds *datastore.Datastore
+}
+
+func newDataStore(ds *datastore.Datastore) *dataStore {
+	return &dataStore{ds: ds}
+}
+
+func (ds *dataStore) logKeys(w io.Writer) error {
+	return ds.ds.LogKeys(datastore.Kms, w)
+}
+
+func (ds *dataStore) findKeys() ([]KeyData, error) {
+	var result []KeyData
+
+	err := ds.ds.View(datastore.Kms, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(KeyPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var key KeyData
+			if err := json.Unmarshal(v, &key); err != nil {
+				return fmt.Errorf("failed to unmarshal key: %w", err)
+			}
+			result = append(result, key)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []KeyData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find keys: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getKey(keyId string) (*KeyData, error) {
+	var result KeyData
+
+	err := ds.ds.View(datastore.Kms, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(KeyPrefix + keyId))
+		if v == nil {
+			return keyNotFound(keyId)
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, keyNotFound(keyId)
+		}
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get key %s: %w", keyId, err)
+	}
+	return &result, nil
+}
+
+func (ds *dataStore) putKey(key *KeyData, overwrite bool) error {
+	err := ds.ds.PutKeys(datastore.Kms, []datastore.PutData{{
+		Key:       KeyPrefix + key.KeyId,
+		Data:      key,
+		Overwrite: overwrite,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to put key %s: %w", key.KeyId, err)
+	}
+	return nil
+}
+
+// updateKey reads a key, changes it with update and writes it back in one transaction, so
+// concurrent changes of the key aren't lost.
+func (ds *dataStore) updateKey(keyId string, update func(*KeyData) error) (*KeyData, error) {
+	var result KeyData
+	var updateErr error
+
+	err := ds.ds.Update(datastore.Kms, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(KeyPrefix + keyId))
+		if v == nil {
+			return keyNotFound(keyId)
+		}
+		if err := json.Unmarshal(v, &result); err != nil {
+			return err
+		}
+
+		if updateErr = update(&result); updateErr != nil {
+			return updateErr
+		}
+
+		data, err := json.Marshal(&result)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(KeyPrefix+keyId), data)
+	})
+
+	if updateErr != nil {
+		return nil, updateErr
+	}
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update key %s: %w", keyId, err)
+	}
+	return &result, nil
+}
+
+// deleteKey removes a key and the aliases of it.
+func (ds *dataStore) deleteKey(keyId string) error {
+	aliases, err := ds.findAliases()
+	if err != nil {
+		return err
+	}
+
+	data := []datastore.PutData{{Key: KeyPrefix + keyId, Delete: true}}
+	for _, alias := range aliases {
+		if alias.TargetKeyId == keyId {
+			data = append(data, datastore.PutData{Key: "/" + alias.AliasName, Delete: true})
+		}
+	}
+
+	if err := ds.ds.PutKeys(datastore.Kms, data); err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", keyId, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) findAliases() ([]AliasData, error) {
+	var result []AliasData
+
+	err := ds.ds.View(datastore.Kms, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(AliasPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var alias AliasData
+			if err := json.Unmarshal(v, &alias); err != nil {
+				return fmt.Errorf("failed to unmarshal alias: %w", err)
+			}
+			result = append(result, alias)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []AliasData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find aliases: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getAlias(aliasName string) (*AliasData, error) {
+	var result AliasData
+
+	err := ds.ds.View(datastore.Kms, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte("/" + aliasName))
+		if v == nil {
+			return aliasNotFound(aliasName)
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, aliasNotFound(aliasName)
+		}
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get alias %s: %w", aliasName, err)
+	}
+	return &result, nil
+}
+
+func (ds *dataStore) putAlias(alias *AliasData, overwrite bool) error {
+	err := ds.ds.PutKeys(datastore.Kms, []datastore.PutData{{
+		Key:       "/" + alias.AliasName,
+		Data:      alias,
+		Overwrite: overwrite,
+	}})
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) {
+			return fmt.Errorf("%w: alias %s", ErrAlreadyExists, alias.AliasName)
+		}
+		return fmt.Errorf("failed to put alias %s: %w", alias.AliasName, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) deleteAlias(aliasName string) error {
+	if err := ds.ds.DeleteKeys(datastore.Kms, []string{"/" + aliasName}); err != nil {
+		return fmt.Errorf("failed to delete alias %s: %w", aliasName, err)
+	}
+	return nil
+}
+
+func keyNotFound(keyId string) error {
+	return fmt.Errorf("%w: key %s", ErrNotFound, keyId)
+}
+
+func aliasNotFound(aliasName string) error {
+	return fmt.Errorf("%w: alias %s", ErrNotFound, aliasName)
+}
@@ -6,4 +6,18 @@ var (
 	ErrInvalidCiphertextException = errors.New("invalid ciphertext exception")
 	ErrKMSInternalException       = errors.New("kms internal exception")
 	ErrInvalidKeyId               = errors.New("invalid key id")
+	ErrInvalidKeyUsage            = errors.New("invalid key usage")
+	ErrIncorrectKey               = errors.New("the ciphertext was not encrypted with the given key")
+	ErrValidation                 = errors.New("validation exception")
+	ErrUnsupportedOperation       = errors.New("unsupported operation")
+	ErrNotFound                   = errors.New("not found")
+	ErrAlreadyExists              = errors.New("already exists")
+	ErrDisabled                   = errors.New("the key is disabled")
+	ErrInvalidState               = errors.New("the key state doesn't allow the operation")
+	ErrInvalidAliasName           = errors.New("invalid alias name")
+	ErrInvalidMarker              = errors.New("invalid marker")
+	ErrMalformedPolicyDocument    = errors.New("malformed policy document")
+	ErrTag                        = errors.New("invalid tag")
+	ErrLimitExceeded              = errors.New("limit exceeded")
+	ErrInvalidSignature           = errors.New("the signature is not valid")
 )
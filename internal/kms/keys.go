@@ -0,0 +1,604 @@
+package kms
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+const (
+	defaultPendingWindowInDays = 30
+	defaultPolicyName          = "default"
+	defaultListLimit           = 100
+	maxListLimit               = 1000
+	maxDescriptionLength       = 8192
+	deletionTick               = time.Minute
+)
+
+// defaultPolicy is the key policy AWS gives keys made without one, allowing the account.
+const defaultPolicy = `{"Version":"2012-10-17","Id":"key-default-1","Statement":[{"Sid":"Enable IAM User Permissions",` +
+	`"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::%s:root"},"Action":"kms:*","Resource":"*"}]}`
+
+// keyUsages are the key specs CreateKey makes, with the key usages allowed for them; the first
+// is the default.
+var keyUsages = map[types.KeySpec][]types.KeyUsageType{
+	types.KeySpecSymmetricDefault: {types.KeyUsageTypeEncryptDecrypt},
+	types.KeySpecRsa2048:          {types.KeyUsageTypeEncryptDecrypt, types.KeyUsageTypeSignVerify},
+	types.KeySpecRsa3072:          {types.KeyUsageTypeEncryptDecrypt, types.KeyUsageTypeSignVerify},
+	types.KeySpecRsa4096:          {types.KeyUsageTypeEncryptDecrypt, types.KeyUsageTypeSignVerify},
+	types.KeySpecEccNistP256:      {types.KeyUsageTypeSignVerify},
+	types.KeySpecEccNistP384:      {types.KeyUsageTypeSignVerify},
+	types.KeySpecEccNistP521:      {types.KeyUsageTypeSignVerify},
+	types.KeySpecEccSecgP256k1:    {types.KeyUsageTypeSignVerify},
+}
+
+// CreateKey makes a key with key material generated here, wrapped by the root key.
+func (s *Service) CreateKey(request *awskms.CreateKeyInput) (*DescribeKeyOutput, error) {
+
+	spec := request.KeySpec
+	if spec == "" {
+		spec = types.KeySpec(request.CustomerMasterKeySpec)
+	}
+	if spec == "" {
+		spec = types.KeySpecSymmetricDefault
+	}
+
+	usages, ok := keyUsages[spec]
+	if !ok {
+		return nil, fmt.Errorf("%w: KeySpec %s", ErrUnsupportedOperation, spec)
+	}
+
+	usage := request.KeyUsage
+	if usage == "" {
+		usage = usages[0]
+	}
+	if !slices.Contains(usages, usage) {
+		return nil, fmt.Errorf("%w: KeyUsage %s is not valid for KeySpec %s", ErrValidation, usage, spec)
+	}
+
+	if request.Origin != "" && request.Origin != types.OriginTypeAwsKms {
+		return nil, fmt.Errorf("%w: Origin %s", ErrUnsupportedOperation, request.Origin)
+	}
+
+	if aws.ToBool(request.MultiRegion) {
+		return nil, fmt.Errorf("%w: multi-Region keys", ErrUnsupportedOperation)
+	}
+
+	if len(aws.ToString(request.Description)) > maxDescriptionLength {
+		return nil, fmt.Errorf("%w: Description is longer than %d characters", ErrValidation, maxDescriptionLength)
+	}
+
+	policy, err := s.keyPolicy(request.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validTags(request.Tags); err != nil {
+		return nil, err
+	}
+
+	key := KeyData{
+		KeyId:        newUuid(),
+		Description:  aws.ToString(request.Description),
+		KeySpec:      spec,
+		KeyUsage:     usage,
+		KeyState:     types.KeyStateEnabled,
+		Origin:       types.OriginTypeAwsKms,
+		Policy:       policy,
+		CreationDate: epoch(time.Now()),
+		Tags:         request.Tags,
+	}
+
+	key.WrappedKey, err = s.newWrappedKey(key.KeyId, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.dataStore.putKey(&key, false); err != nil {
+		return nil, err
+	}
+
+	return &DescribeKeyOutput{KeyMetadata: key.toKeyMetadata(s.arn("key/"+key.KeyId), s.accountId)}, nil
+}
+
+func (s *Service) DescribeKey(request *awskms.DescribeKeyInput) (*DescribeKeyOutput, error) {
+
+	key, err := s.keyData(aws.ToString(request.KeyId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &DescribeKeyOutput{KeyMetadata: key.toKeyMetadata(s.arn("key/"+key.KeyId), s.accountId)}, nil
+}
+
+// ListKeys lists the config keys and the keys made by CreateKey, by key id.
+func (s *Service) ListKeys(request *awskms.ListKeysInput) (*awskms.ListKeysOutput, error) {
+
+	keys, err := s.allKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, next, err := page(keys, func(key KeyData) string { return key.KeyId }, request.Marker, request.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := awskms.ListKeysOutput{Keys: []types.KeyListEntry{}, NextMarker: next, Truncated: next != nil}
+	for _, key := range keys {
+		result.Keys = append(result.Keys, types.KeyListEntry{
+			KeyArn: aws.String(s.arn("key/" + key.KeyId)),
+			KeyId:  aws.String(key.KeyId),
+		})
+	}
+
+	return &result, nil
+}
+
+func (s *Service) EnableKey(request *awskms.EnableKeyInput) error {
+
+	_, err := s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		if key.KeyState == types.KeyStatePendingDeletion {
+			return fmt.Errorf("%w: key %s is pending deletion", ErrInvalidState, key.KeyId)
+		}
+		key.KeyState = types.KeyStateEnabled
+		return nil
+	})
+
+	return err
+}
+
+func (s *Service) DisableKey(request *awskms.DisableKeyInput) error {
+
+	_, err := s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		if key.KeyState == types.KeyStatePendingDeletion {
+			return fmt.Errorf("%w: key %s is pending deletion", ErrInvalidState, key.KeyId)
+		}
+		key.KeyState = types.KeyStateDisabled
+		return nil
+	})
+
+	return err
+}
+
+// ScheduleKeyDeletion makes a key unusable, RunKeyDeletion deletes it and its aliases after the
+// waiting period.
+func (s *Service) ScheduleKeyDeletion(request *awskms.ScheduleKeyDeletionInput) (*ScheduleKeyDeletionOutput, error) {
+
+	days := aws.ToInt32(request.PendingWindowInDays)
+	if request.PendingWindowInDays == nil {
+		days = defaultPendingWindowInDays
+	}
+	if days < 7 || days > 30 {
+		return nil, fmt.Errorf("%w: PendingWindowInDays must be between 7 and 30", ErrValidation)
+	}
+
+	key, err := s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		if key.KeyState == types.KeyStatePendingDeletion {
+			return fmt.Errorf("%w: key %s is pending deletion", ErrInvalidState, key.KeyId)
+		}
+		key.KeyState = types.KeyStatePendingDeletion
+		key.DeletionDate = epoch(time.Now().AddDate(0, 0, int(days)))
+		key.PendingWindowInDays = days
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduleKeyDeletionOutput{
+		DeletionDate:        key.DeletionDate,
+		KeyId:               s.arn("key/" + key.KeyId),
+		KeyState:            key.KeyState,
+		PendingWindowInDays: key.PendingWindowInDays,
+	}, nil
+}
+
+// CancelKeyDeletion leaves a key disabled, as AWS does.
+func (s *Service) CancelKeyDeletion(request *awskms.CancelKeyDeletionInput) (*awskms.CancelKeyDeletionOutput, error) {
+
+	key, err := s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		if key.KeyState != types.KeyStatePendingDeletion {
+			return fmt.Errorf("%w: key %s is not pending deletion", ErrInvalidState, key.KeyId)
+		}
+		key.KeyState = types.KeyStateDisabled
+		key.DeletionDate = 0
+		key.PendingWindowInDays = 0
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &awskms.CancelKeyDeletionOutput{KeyId: aws.String(s.arn("key/" + key.KeyId))}, nil
+}
+
+func (s *Service) UpdateKeyDescription(request *awskms.UpdateKeyDescriptionInput) error {
+
+	if len(aws.ToString(request.Description)) > maxDescriptionLength {
+		return fmt.Errorf("%w: Description is longer than %d characters", ErrValidation, maxDescriptionLength)
+	}
+
+	_, err := s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		key.Description = aws.ToString(request.Description)
+		return nil
+	})
+
+	return err
+}
+
+// GetKeyPolicy returns the key policy; it's kept for Terraform and the like, not enforced.
+func (s *Service) GetKeyPolicy(request *awskms.GetKeyPolicyInput) (*awskms.GetKeyPolicyOutput, error) {
+
+	if err := validPolicyName(request.PolicyName); err != nil {
+		return nil, err
+	}
+
+	key, err := s.keyData(aws.ToString(request.KeyId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &awskms.GetKeyPolicyOutput{Policy: aws.String(key.Policy), PolicyName: aws.String(defaultPolicyName)}, nil
+}
+
+func (s *Service) PutKeyPolicy(request *awskms.PutKeyPolicyInput) error {
+
+	if err := validPolicyName(request.PolicyName); err != nil {
+		return err
+	}
+
+	if request.Policy == nil {
+		return fmt.Errorf("%w: Policy is required", ErrValidation)
+	}
+
+	policy, err := s.keyPolicy(request.Policy)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		key.Policy = policy
+		return nil
+	})
+
+	return err
+}
+
+func (s *Service) ListResourceTags(request *awskms.ListResourceTagsInput) (*awskms.ListResourceTagsOutput, error) {
+
+	key, err := s.keyData(aws.ToString(request.KeyId))
+	if err != nil {
+		return nil, err
+	}
+
+	result := awskms.ListResourceTagsOutput{Tags: key.Tags}
+	if result.Tags == nil {
+		result.Tags = []types.Tag{}
+	}
+
+	return &result, nil
+}
+
+func (s *Service) TagResource(request *awskms.TagResourceInput) error {
+
+	if err := validTags(request.Tags); err != nil {
+		return err
+	}
+
+	_, err := s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		for _, tag := range request.Tags {
+			key.Tags = slices.DeleteFunc(key.Tags, func(t types.Tag) bool {
+				return aws.ToString(t.TagKey) == aws.ToString(tag.TagKey)
+			})
+			key.Tags = append(key.Tags, tag)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (s *Service) UntagResource(request *awskms.UntagResourceInput) error {
+
+	_, err := s.updateKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		key.Tags = slices.DeleteFunc(key.Tags, func(t types.Tag) bool {
+			return slices.Contains(request.TagKeys, aws.ToString(t.TagKey))
+		})
+		return nil
+	})
+
+	return err
+}
+
+// RunKeyDeletion deletes the keys whose waiting period is over. It doesn't return.
+func (s *Service) RunKeyDeletion() {
+
+	ticker := time.NewTicker(deletionTick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.deleteKeys(time.Now()); err != nil {
+			log.Println("Error:", err)
+		}
+	}
+}
+
+func (s *Service) deleteKeys(now time.Time) error {
+
+	keys, err := s.dataStore.findKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.KeyState == types.KeyStatePendingDeletion && key.DeletionDate <= epoch(now) {
+			if err := s.dataStore.deleteKey(key.KeyId); err != nil {
+				return err
+			}
+			log.Println("Deleted KMS key", key.KeyId)
+		}
+	}
+
+	return nil
+}
+
+// FindKey returns the key material of an enabled key, by key id, key ARN, alias name or
+// alias ARN.
+func (s *Service) FindKey(keyId string) (*KmsKey, error) {
+
+	if key, err := FindKeyId(s.keys, keyId); err == nil {
+		return key, nil
+	}
+
+	data, err := s.keyData(keyId)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyId, keyId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data.fromConfig() {
+		return FindKeyId(s.keys, data.KeyId)
+	}
+
+	switch data.KeyState {
+	case types.KeyStateEnabled:
+	case types.KeyStateDisabled:
+		return nil, fmt.Errorf("%w: %s", ErrDisabled, data.KeyId)
+	default:
+		return nil, fmt.Errorf("%w: key %s is %s", ErrInvalidState, data.KeyId, data.KeyState)
+	}
+
+	if s.rootKey == nil {
+		return nil, fmt.Errorf("no root key to unwrap key %s: %w", data.KeyId, ErrKMSInternalException)
+	}
+
+	wrapped := [][]byte{data.WrappedKey}
+	for _, rotation := range data.Rotations {
+		wrapped = append(wrapped, rotation.WrappedKey)
+	}
+
+	var materials []string
+	for _, w := range wrapped {
+		material, err := s.rootKey.Decrypt(w, []byte(data.KeyId))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap key %s: %w", data.KeyId, ErrKMSInternalException)
+		}
+		materials = append(materials, base64.StdEncoding.EncodeToString(material))
+	}
+
+	return &KmsKey{
+		KeyId:        data.KeyId,
+		KeySpec:      data.KeySpec,
+		KeyUsage:     data.KeyUsage,
+		Key:          materials[len(materials)-1],
+		PreviousKeys: materials[:len(materials)-1],
+	}, nil
+}
+
+// keyData returns a key by key id, key ARN, alias name or alias ARN.
+func (s *Service) keyData(keyId string) (*KeyData, error) {
+
+	if key, err := FindKeyId(s.keys, keyId); err == nil {
+		return s.configKeyData(key), nil
+	}
+
+	id, err := keyIdOf(keyId)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, fmt.Errorf("%w: KeyId is required", ErrValidation)
+	}
+
+	if strings.HasPrefix(id, "alias/") {
+		alias, err := s.dataStore.getAlias(id)
+		if err != nil {
+			return nil, err
+		}
+		id = alias.TargetKeyId
+
+		if key, err := FindKeyId(s.keys, id); err == nil {
+			return s.configKeyData(key), nil
+		}
+	}
+
+	return s.dataStore.getKey(id)
+}
+
+// allKeys returns the config keys and the keys made by CreateKey, by key id.
+func (s *Service) allKeys() ([]KeyData, error) {
+
+	keys, err := s.dataStore.findKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range s.keys {
+		keys = append(keys, *s.configKeyData(&s.keys[i]))
+	}
+
+	slices.SortFunc(keys, func(a, b KeyData) int { return strings.Compare(a.KeyId, b.KeyId) })
+
+	return keys, nil
+}
+
+// configKeyData returns a config key as an imported key, made when home-fern started.
+func (s *Service) configKeyData(key *KmsKey) *KeyData {
+
+	result := KeyData{
+		KeyId:        key.KeyId,
+		KeySpec:      key.KeySpec,
+		KeyUsage:     key.usage(),
+		KeyState:     types.KeyStateEnabled,
+		Origin:       types.OriginTypeExternal,
+		Policy:       fmt.Sprintf(defaultPolicy, s.accountId),
+		CreationDate: s.started,
+	}
+
+	if key.IsSymmetric() {
+		result.KeySpec = types.KeySpecSymmetricDefault
+	}
+
+	return &result
+}
+
+// updateKey changes a key made by CreateKey, in one transaction; config keys don't change.
+func (s *Service) updateKey(keyId string, update func(*KeyData) error) (*KeyData, error) {
+
+	key, err := s.keyData(keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.fromConfig() {
+		return nil, fmt.Errorf("%w: key %s is from the config", ErrUnsupportedOperation, key.KeyId)
+	}
+
+	return s.dataStore.updateKey(key.KeyId, update)
+}
+
+func (s *Service) keyPolicy(policy *string) (string, error) {
+
+	if policy == nil {
+		return fmt.Sprintf(defaultPolicy, s.accountId), nil
+	}
+
+	if !json.Valid([]byte(*policy)) {
+		return "", fmt.Errorf("%w: the policy isn't JSON", ErrMalformedPolicyDocument)
+	}
+
+	return *policy, nil
+}
+
+func validPolicyName(name *string) error {
+
+	if name != nil && *name != defaultPolicyName {
+		return fmt.Errorf("%w: policy %s", ErrNotFound, *name)
+	}
+	return nil
+}
+
+func validTags(tags []types.Tag) error {
+
+	for _, tag := range tags {
+		if aws.ToString(tag.TagKey) == "" || tag.TagValue == nil {
+			return fmt.Errorf("%w: tags need a TagKey and a TagValue", ErrTag)
+		}
+		if strings.HasPrefix(aws.ToString(tag.TagKey), "aws:") {
+			return fmt.Errorf("%w: tag keys beginning with aws: are reserved", ErrTag)
+		}
+	}
+	return nil
+}
+
+// newWrappedKey returns new key material wrapped by the root key. The key id is the additional
+// data, key material doesn't unwrap as another key's.
+func (s *Service) newWrappedKey(keyId string, spec types.KeySpec) ([]byte, error) {
+
+	if s.rootKey == nil {
+		return nil, fmt.Errorf("%w: there is no symmetric config key to wrap key material", ErrUnsupportedOperation)
+	}
+
+	material, err := newKeyMaterial(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := s.rootKey.Encrypt(material, []byte(keyId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key: %w", ErrKMSInternalException)
+	}
+
+	return wrapped, nil
+}
+
+func newKeyMaterial(spec types.KeySpec) ([]byte, error) {
+
+	if spec != types.KeySpecSymmetricDefault {
+		private, err := newPrivateKey(spec)
+		if err != nil {
+			return nil, err
+		}
+		return marshalPrivateKey(private)
+	}
+
+	material := make([]byte, 32)
+	if _, err := rand.Read(material); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", ErrKMSInternalException)
+	}
+	return material, nil
+}
+
+// page returns the page of items, sorted by name, from the marker on, and the marker of the
+// next page.
+func page[T any](items []T, name func(T) string, marker *string, limit *int32) ([]T, *string, error) {
+
+	size := defaultListLimit
+	if limit != nil {
+		if *limit < 1 || *limit > maxListLimit {
+			return nil, nil, fmt.Errorf("%w: Limit must be between 1 and %d", ErrValidation, maxListLimit)
+		}
+		size = int(*limit)
+	}
+
+	start := 0
+	if marker != nil {
+		start = slices.IndexFunc(items, func(item T) bool { return name(item) == *marker })
+		if start < 0 {
+			return nil, nil, fmt.Errorf("%w: %s", ErrInvalidMarker, *marker)
+		}
+	}
+
+	if start+size >= len(items) {
+		return items[start:], nil, nil
+	}
+
+	return items[start : start+size], aws.String(name(items[start+size])), nil
+}
+
+func epoch(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func newUuid() string {
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,248 @@
+package kms
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+const (
+	defaultRotationPeriodInDays = 365
+	minRotationPeriodInDays     = 90
+	maxRotationPeriodInDays     = 2560
+	maxOnDemandRotations        = 10
+	rotationTick                = time.Hour
+)
+
+// Rotation adds key material to a symmetric key made by CreateKey. Encrypt uses the newest key
+// material, Decrypt the version named in the ciphertext blob. Config keys rotate in the config,
+// with previousKeys.
+
+func (s *Service) EnableKeyRotation(request *awskms.EnableKeyRotationInput) error {
+
+	days := aws.ToInt32(request.RotationPeriodInDays)
+	if request.RotationPeriodInDays == nil {
+		days = defaultRotationPeriodInDays
+	}
+	if days < minRotationPeriodInDays || days > maxRotationPeriodInDays {
+		return fmt.Errorf("%w: RotationPeriodInDays must be between %d and %d",
+			ErrValidation, minRotationPeriodInDays, maxRotationPeriodInDays)
+	}
+
+	_, err := s.updateRotatingKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		if key.KeyState != types.KeyStateEnabled {
+			return keyNotEnabled(key)
+		}
+
+		// the period counts from the last rotation, as AWS does
+		last := key.CreationDate
+		if len(key.Rotations) > 0 {
+			last = key.Rotations[len(key.Rotations)-1].RotationDate
+		}
+
+		key.RotationPeriodInDays = days
+		key.NextRotationDate = last + float64(days)*24*60*60
+		return nil
+	})
+
+	return err
+}
+
+func (s *Service) DisableKeyRotation(request *awskms.DisableKeyRotationInput) error {
+
+	_, err := s.updateRotatingKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		if key.KeyState != types.KeyStateEnabled {
+			return keyNotEnabled(key)
+		}
+
+		key.RotationPeriodInDays = 0
+		key.NextRotationDate = 0
+		return nil
+	})
+
+	return err
+}
+
+func (s *Service) GetKeyRotationStatus(request *awskms.GetKeyRotationStatusInput) (*GetKeyRotationStatusOutput, error) {
+
+	key, err := s.keyData(aws.ToString(request.KeyId))
+	if err != nil {
+		return nil, err
+	}
+
+	result := GetKeyRotationStatusOutput{
+		KeyId:              s.arn("key/" + key.KeyId),
+		KeyRotationEnabled: key.RotationPeriodInDays > 0,
+	}
+
+	if result.KeyRotationEnabled {
+		result.RotationPeriodInDays = &key.RotationPeriodInDays
+		result.NextRotationDate = &key.NextRotationDate
+	}
+
+	return &result, nil
+}
+
+// RotateKeyOnDemand rotates a key at once, its automatic rotation stays on schedule.
+func (s *Service) RotateKeyOnDemand(request *awskms.RotateKeyOnDemandInput) (*awskms.RotateKeyOnDemandOutput, error) {
+
+	key, err := s.updateRotatingKey(aws.ToString(request.KeyId), func(key *KeyData) error {
+		if key.KeyState != types.KeyStateEnabled {
+			return keyNotEnabled(key)
+		}
+
+		onDemand := 0
+		for _, rotation := range key.Rotations {
+			if rotation.RotationType == types.RotationTypeOnDemand {
+				onDemand++
+			}
+		}
+		if onDemand >= maxOnDemandRotations {
+			return fmt.Errorf("%w: key %s was rotated on demand %d times", ErrLimitExceeded, key.KeyId, onDemand)
+		}
+
+		return s.rotate(key, types.RotationTypeOnDemand, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &awskms.RotateKeyOnDemandOutput{KeyId: aws.String(s.arn("key/" + key.KeyId))}, nil
+}
+
+// ListKeyRotations lists the rotations of a key, oldest first. The marker is the key material
+// version of the first rotation of the page.
+func (s *Service) ListKeyRotations(request *awskms.ListKeyRotationsInput) (*ListKeyRotationsOutput, error) {
+
+	key, err := s.keyData(aws.ToString(request.KeyId))
+	if err != nil {
+		return nil, err
+	}
+
+	if !key.isSymmetric() {
+		return nil, fmt.Errorf("%w: key %s is asymmetric, it doesn't rotate", ErrUnsupportedOperation, key.KeyId)
+	}
+
+	versions := make([]int, len(key.Rotations))
+	for i := range versions {
+		versions[i] = i + 1
+	}
+
+	versions, next, err := page(versions, strconv.Itoa, request.Marker, request.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ListKeyRotationsOutput{Rotations: []RotationsListEntryOutput{}, NextMarker: next, Truncated: next != nil}
+	for _, version := range versions {
+		rotation := key.Rotations[version-1]
+		result.Rotations = append(result.Rotations, RotationsListEntryOutput{
+			KeyId:        key.KeyId,
+			RotationDate: rotation.RotationDate,
+			RotationType: rotation.RotationType,
+		})
+	}
+
+	return &result, nil
+}
+
+// RunKeyRotation rotates the keys whose rotation date has come. It doesn't return.
+func (s *Service) RunKeyRotation() {
+
+	ticker := time.NewTicker(rotationTick)
+	defer ticker.Stop()
+
+	for {
+		if err := s.rotateKeys(time.Now()); err != nil {
+			log.Println("Error:", err)
+		}
+		<-ticker.C
+	}
+}
+
+// rotateKeys rotates the enabled keys due for rotation; disabled keys rotate once enabled.
+func (s *Service) rotateKeys(now time.Time) error {
+
+	keys, err := s.dataStore.findKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if !rotationDue(&key, now) {
+			continue
+		}
+
+		// the key is checked again in the transaction, it may have changed since
+		rotated := false
+		_, err := s.dataStore.updateKey(key.KeyId, func(key *KeyData) error {
+			if !rotationDue(key, now) {
+				return nil
+			}
+			if err := s.rotate(key, types.RotationTypeAutomatic, now); err != nil {
+				return err
+			}
+			key.NextRotationDate = epoch(now.AddDate(0, 0, int(key.RotationPeriodInDays)))
+			rotated = true
+			return nil
+		})
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if rotated {
+			log.Println("Rotated KMS key", key.KeyId)
+		}
+	}
+
+	return nil
+}
+
+func rotationDue(key *KeyData, now time.Time) bool {
+	return key.RotationPeriodInDays > 0 && key.KeyState == types.KeyStateEnabled && key.NextRotationDate <= epoch(now)
+}
+
+// rotate adds new key material to a key.
+func (s *Service) rotate(key *KeyData, rotationType types.RotationType, now time.Time) error {
+
+	wrapped, err := s.newWrappedKey(key.KeyId, key.KeySpec)
+	if err != nil {
+		return err
+	}
+
+	key.Rotations = append(key.Rotations, KeyRotationData{
+		WrappedKey:   wrapped,
+		RotationDate: epoch(now),
+		RotationType: rotationType,
+	})
+
+	return nil
+}
+
+// updateRotatingKey changes a symmetric key made by CreateKey.
+func (s *Service) updateRotatingKey(keyId string, update func(*KeyData) error) (*KeyData, error) {
+
+	return s.updateKey(keyId, func(key *KeyData) error {
+		if !key.isSymmetric() {
+			return fmt.Errorf("%w: key %s is asymmetric, it doesn't rotate", ErrUnsupportedOperation, key.KeyId)
+		}
+		return update(key)
+	})
+}
+
+func keyNotEnabled(key *KeyData) error {
+
+	if key.KeyState == types.KeyStateDisabled {
+		return fmt.Errorf("%w: %s", ErrDisabled, key.KeyId)
+	}
+	return fmt.Errorf("%w: key %s is %s", ErrInvalidState, key.KeyId, key.KeyState)
+}
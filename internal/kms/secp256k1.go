@@ -0,0 +1,133 @@
+package kms
+
+import (
+	"crypto"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// crypto/x509 doesn't know the secp256k1 curve of ECC_SECG_P256K1 keys, so they're encoded here:
+// PKCS #8 private keys holding an RFC 5915 EC private key, and X.509 SubjectPublicKeyInfo
+// public keys, the way openssl writes them.
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSecp256k1      = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// secp256k1Signer is a crypto.Signer of secp256k1 keys, its signatures are DER encoded.
+type secp256k1Signer struct {
+	key *secp256k1.PrivateKey
+}
+
+func (s *secp256k1Signer) Public() crypto.PublicKey {
+	return s.key.PubKey()
+}
+
+func (s *secp256k1Signer) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return ecdsa.Sign(s.key, digest).Serialize(), nil
+}
+
+func newSecp256k1Key() (*secp256k1Signer, error) {
+
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &secp256k1Signer{key: key}, nil
+}
+
+func marshalSecp256k1PrivateKey(s *secp256k1Signer) ([]byte, error) {
+
+	params, err := asn1.Marshal(oidSecp256k1)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: s.key.Serialize(),
+		PublicKey:  asn1.BitString{Bytes: s.key.PubKey().SerializeUncompressed(), BitLength: 65 * 8},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8PrivateKey{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PrivateKey: inner,
+	})
+}
+
+func parseSecp256k1PrivateKey(der []byte) (*secp256k1Signer, error) {
+
+	var outer pkcs8PrivateKey
+	if _, err := asn1.Unmarshal(der, &outer); err != nil {
+		return nil, err
+	}
+
+	var curve asn1.ObjectIdentifier
+	if !outer.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, fmt.Errorf("not an EC private key")
+	}
+	if _, err := asn1.Unmarshal(outer.Algorithm.Parameters.FullBytes, &curve); err != nil || !curve.Equal(oidSecp256k1) {
+		return nil, fmt.Errorf("not a secp256k1 private key")
+	}
+
+	var inner ecPrivateKey
+	if _, err := asn1.Unmarshal(outer.PrivateKey, &inner); err != nil {
+		return nil, err
+	}
+	if len(inner.PrivateKey) != 32 {
+		return nil, fmt.Errorf("invalid secp256k1 private key length %d", len(inner.PrivateKey))
+	}
+
+	return &secp256k1Signer{key: secp256k1.PrivKeyFromBytes(inner.PrivateKey)}, nil
+}
+
+func marshalSecp256k1PublicKey(public *secp256k1.PublicKey) ([]byte, error) {
+
+	params, err := asn1.Marshal(oidSecp256k1)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: public.SerializeUncompressed(), BitLength: 65 * 8},
+	})
+}
+
+func verifySecp256k1(public *secp256k1.PublicKey, digest []byte, signature []byte) bool {
+
+	sig, err := ecdsa.ParseDERSignature(signature)
+	if err != nil {
+		return false
+	}
+
+	return sig.Verify(digest, public)
+}
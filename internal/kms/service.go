@@ -1,25 +1,55 @@
 package kms
 
 import (
+	"crypto"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"home-fern/internal/datastore"
+	"io"
+	"log"
+	"slices"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/kms/types"
 )
 
+// Service holds the keys of the config and the keys made by CreateKey, whose key material is
+// wrapped by the root key, a symmetric config key. Without a root key no keys are made.
 type Service struct {
 	accountId string
 	region    string
 	keys      []KmsKey
+	rootKey   *KmsKey
+	dataStore *dataStore
+	started   float64
 }
 
-func NewService(keys []KmsKey, region string, accountId string) *Service {
+func NewService(keys []KmsKey, rootKeyId string, region string, accountId string, ds *datastore.Datastore) *Service {
+
+	var rootKey *KmsKey
+	if rootKeyId != "" {
+		key, err := FindKeyId(keys, rootKeyId)
+		if err == nil && key.IsSymmetric() {
+			rootKey = key
+		} else {
+			log.Println("KMS root key must be a symmetric key of the config, CreateKey is not available:", rootKeyId)
+		}
+	} else if i := slices.IndexFunc(keys, func(key KmsKey) bool { return key.IsSymmetric() }); i >= 0 {
+		rootKey = &keys[i]
+	} else {
+		log.Println("No symmetric KMS key in the config, CreateKey is not available")
+	}
 
 	result := Service{
 		region:    region,
 		accountId: accountId,
 		keys:      keys,
+		rootKey:   rootKey,
+		dataStore: newDataStore(ds),
+		started:   epoch(time.Now()),
 	}
 
 	return &result
@@ -27,62 +57,284 @@ func NewService(keys []KmsKey, region string, accountId string) *Service {
 
 func (s *Service) Encrypt(request *awskms.EncryptInput) (*awskms.EncryptOutput, error) {
 
-	key, err := FindKeyId(s.keys, aws.ToString(request.KeyId))
+	algorithm := encryptionAlgorithm(request.EncryptionAlgorithm)
+
+	key, blob, err := s.encrypt(aws.ToString(request.KeyId), request.Plaintext, request.EncryptionContext, algorithm)
 	if err != nil {
 		return nil, err
 	}
 
-	var aad []byte
+	result := awskms.EncryptOutput{
+		KeyId:               aws.String(s.keyArn(key)),
+		CiphertextBlob:      blob,
+		EncryptionAlgorithm: algorithm,
+	}
 
-	if len(request.EncryptionContext) > 0 {
+	return &result, nil
+}
 
-		aad, err = json.Marshal(request.EncryptionContext)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal encryption context: %w", ErrKMSInternalException)
-		}
-	}
+func (s *Service) Decrypt(request *awskms.DecryptInput) (*awskms.DecryptOutput, error) {
+
+	algorithm := encryptionAlgorithm(request.EncryptionAlgorithm)
 
-	encstr, err := key.EncryptString(string(request.Plaintext), aad)
+	key, plaintext, err := s.decrypt(request.CiphertextBlob, aws.ToString(request.KeyId), request.EncryptionContext, algorithm)
 	if err != nil {
-		return nil, fmt.Errorf("encryption failed: %w", ErrKMSInternalException)
+		return nil, err
 	}
 
-	result := awskms.EncryptOutput{
-		KeyId:               request.KeyId,
-		CiphertextBlob:      []byte(encstr),
-		EncryptionAlgorithm: types.EncryptionAlgorithmSpecSymmetricDefault,
+	result := awskms.DecryptOutput{
+		EncryptionAlgorithm: algorithm,
+		KeyId:               aws.String(s.keyArn(key)),
+		Plaintext:           plaintext,
 	}
 
 	return &result, nil
 }
 
-func (s *Service) Decrypt(request *awskms.DecryptInput) (*awskms.DecryptOutput, error) {
+// ReEncrypt decrypts a ciphertext blob and encrypts its plaintext with the destination key,
+// the plaintext doesn't leave home-fern.
+func (s *Service) ReEncrypt(request *awskms.ReEncryptInput) (*awskms.ReEncryptOutput, error) {
+
+	sourceAlgorithm := encryptionAlgorithm(request.SourceEncryptionAlgorithm)
+	destinationAlgorithm := encryptionAlgorithm(request.DestinationEncryptionAlgorithm)
+
+	source, plaintext, err := s.decrypt(
+		request.CiphertextBlob, aws.ToString(request.SourceKeyId), request.SourceEncryptionContext, sourceAlgorithm)
+	if err != nil {
+		return nil, err
+	}
 
-	key, err := FindKeyId(s.keys, aws.ToString(request.KeyId))
+	destination, blob, err := s.encrypt(
+		aws.ToString(request.DestinationKeyId), plaintext, request.DestinationEncryptionContext, destinationAlgorithm)
 	if err != nil {
 		return nil, err
 	}
 
-	var aad []byte
+	result := awskms.ReEncryptOutput{
+		CiphertextBlob:                 blob,
+		KeyId:                          aws.String(s.keyArn(destination)),
+		SourceKeyId:                    aws.String(s.keyArn(source)),
+		SourceEncryptionAlgorithm:      sourceAlgorithm,
+		DestinationEncryptionAlgorithm: destinationAlgorithm,
+	}
+
+	return &result, nil
+}
+
+// Signer returns the private key of an asymmetric key for services which sign with KMS keys,
+// such as Route53 key signing keys.
+func (s *Service) Signer(keyId string) (crypto.Signer, types.KeySpec, error) {
+
+	key, err := s.FindKey(keyId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	signer, err := key.Signer()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return signer, key.KeySpec, nil
+}
+
+// WrapKey wraps the key material of another service with the root key, the way the key material
+// of CreateKey's keys is. The material only unwraps with the same name.
+func (s *Service) WrapKey(name string, material []byte) ([]byte, error) {
+
+	if s.rootKey == nil {
+		return nil, fmt.Errorf("%w: there is no symmetric config key to wrap key material", ErrUnsupportedOperation)
+	}
+
+	wrapped, err := s.rootKey.Encrypt(material, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key %s: %w", name, ErrKMSInternalException)
+	}
+
+	return wrapped, nil
+}
+
+// UnwrapKey returns the key material WrapKey wrapped with the same name.
+func (s *Service) UnwrapKey(name string, wrapped []byte) ([]byte, error) {
+
+	if s.rootKey == nil {
+		return nil, fmt.Errorf("no root key to unwrap key %s: %w", name, ErrKMSInternalException)
+	}
+
+	material, err := s.rootKey.Decrypt(wrapped, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key %s: %w", name, ErrKMSInternalException)
+	}
+
+	return material, nil
+}
 
-	if request.EncryptionContext != nil && len(request.EncryptionContext) > 0 {
+func (s *Service) LogKeys(writer io.Writer) error {
+	return s.dataStore.logKeys(writer)
+}
 
-		aad, err = json.Marshal(request.EncryptionContext)
+func (s *Service) keyArn(key *KmsKey) string {
+	return s.arn("key/" + key.KeyId)
+}
+
+func (s *Service) arn(resource string) string {
+	return fmt.Sprintf("arn:aws:kms:%s:%s:%s", s.region, s.accountId, resource)
+}
+
+// encrypt encrypts plaintext with a symmetric key, bound to the encryption context, or with the
+// public key of an RSA key.
+func (s *Service) encrypt(keyId string, plaintext []byte, encryptionContext map[string]string,
+	algorithm types.EncryptionAlgorithmSpec) (*KmsKey, []byte, error) {
+
+	key, err := s.FindKey(keyId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := validEncryption(key, encryptionContext, algorithm); err != nil {
+		return nil, nil, err
+	}
+
+	if !key.IsSymmetric() {
+		blob, err := encryptOAEP(key, plaintext, algorithm)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal encryption context: %w", ErrKMSInternalException)
+			return nil, nil, err
 		}
+		return key, blob, nil
 	}
 
-	decstr, err := key.DecryptString(string(request.CiphertextBlob), aad)
+	aad, err := additionalData(encryptionContext)
 	if err != nil {
-		return nil, ErrInvalidCiphertextException
+		return nil, nil, err
 	}
 
-	result := awskms.DecryptOutput{
-		EncryptionAlgorithm: types.EncryptionAlgorithmSpecSymmetricDefault,
-		KeyId:               request.KeyId,
-		Plaintext:           []byte(decstr),
+	blob, err := key.Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encryption failed: %w", ErrKMSInternalException)
 	}
 
-	return &result, nil
+	return key, blob, nil
+}
+
+// decrypt finds the key in the ciphertext blob, a keyId only has to match it. Blobs of the
+// earlier format, base64 text without the key, and blobs of RSA keys need the keyId.
+func (s *Service) decrypt(blob []byte, keyId string, encryptionContext map[string]string,
+	algorithm types.EncryptionAlgorithmSpec) (*KmsKey, []byte, error) {
+
+	var key *KmsKey
+	var err error
+
+	if algorithm != types.EncryptionAlgorithmSpecSymmetricDefault {
+		if keyId == "" {
+			return nil, nil, fmt.Errorf("%w: KeyId is required with encryption algorithm %s", ErrValidation, algorithm)
+		}
+		key, err = s.FindKey(keyId)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := validEncryption(key, encryptionContext, algorithm); err != nil {
+			return nil, nil, err
+		}
+
+		plaintext, err := decryptOAEP(key, blob, algorithm)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, plaintext, nil
+	}
+
+	env, ok := parseCiphertext(blob)
+	if ok {
+		key, err = s.FindKey(env.KeyId)
+		if errors.Is(err, ErrInvalidKeyId) {
+			return nil, nil, ErrInvalidCiphertextException
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if keyId != "" {
+			requested, err := s.FindKey(keyId)
+			if err != nil {
+				return nil, nil, err
+			}
+			if requested.KeyId != key.KeyId {
+				return nil, nil, ErrIncorrectKey
+			}
+		}
+	} else {
+		if keyId == "" {
+			return nil, nil, ErrInvalidCiphertextException
+		}
+		key, err = s.FindKey(keyId)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := validEncryption(key, encryptionContext, algorithm); err != nil {
+		return nil, nil, err
+	}
+
+	aad, err := additionalData(encryptionContext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var plaintext []byte
+	if ok {
+		plaintext, err = key.Decrypt(blob, aad)
+	} else {
+		var decstr string
+		decstr, err = key.DecryptString(string(blob), aad)
+		plaintext = []byte(decstr)
+	}
+	if err != nil {
+		return nil, nil, ErrInvalidCiphertextException
+	}
+
+	return key, plaintext, nil
+}
+
+// validEncryption checks that a key encrypts with the encryption algorithm: symmetric keys with
+// SYMMETRIC_DEFAULT, RSA keys with RSAES_OAEP and without an encryption context.
+func validEncryption(key *KmsKey, encryptionContext map[string]string, algorithm types.EncryptionAlgorithmSpec) error {
+
+	if key.usage() != types.KeyUsageTypeEncryptDecrypt {
+		return fmt.Errorf("%w: key %s doesn't encrypt", ErrInvalidKeyUsage, key.KeyId)
+	}
+
+	if key.IsSymmetric() != (algorithm == types.EncryptionAlgorithmSpecSymmetricDefault) {
+		return fmt.Errorf("%w: encryption algorithm %s is not valid for key %s", ErrInvalidKeyUsage, algorithm, key.KeyId)
+	}
+
+	if !key.IsSymmetric() && len(encryptionContext) > 0 {
+		return fmt.Errorf("%w: asymmetric keys don't take an encryption context", ErrValidation)
+	}
+
+	return nil
+}
+
+// encryptionAlgorithm returns the encryption algorithm of a request, SYMMETRIC_DEFAULT by default.
+func encryptionAlgorithm(algorithm types.EncryptionAlgorithmSpec) types.EncryptionAlgorithmSpec {
+
+	if algorithm == "" {
+		return types.EncryptionAlgorithmSpecSymmetricDefault
+	}
+	return algorithm
+}
+
+// additionalData returns the encryption context as the additional authenticated data.
+func additionalData(encryptionContext map[string]string) ([]byte, error) {
+
+	if len(encryptionContext) == 0 {
+		return nil, nil
+	}
+
+	aad, err := json.Marshal(encryptionContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal encryption context: %w", ErrKMSInternalException)
+	}
+
+	return aad, nil
 }
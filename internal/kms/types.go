@@ -1,33 +1,38 @@
 package kms
 
 import (
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
 )
 
+// KmsKey is a key from the config. Symmetric keys hold the base64 AES key, asymmetric
+// keys the base64 PKCS #8 DER private key. PreviousKeys are the earlier AES keys of a rotated
+// key, oldest first: ciphertext names the version of the key material it was encrypted with.
+// Asymmetric keys sign, RSA keys encrypt with KeyUsage ENCRYPT_DECRYPT.
 type KmsKey struct {
-	KeyId string `yaml:"id"`
-	Alias string `yaml:"alias"`
-	Key   string `yaml:"key"`
+	KeyId        string             `yaml:"id"`
+	Alias        string             `yaml:"alias"`
+	KeySpec      types.KeySpec      `yaml:"keySpec"`
+	KeyUsage     types.KeyUsageType `yaml:"keyUsage"`
+	Key          string             `yaml:"key"`
+	PreviousKeys []string           `yaml:"previousKeys"`
 }
 
 func FindKeyId(keys []KmsKey, keyId string) (*KmsKey, error) {
 
-	chk := keyId
-	if strings.HasPrefix(keyId, "arn:aws:kms:") {
-
-		// (obviously) ignores region and account id
-		pieces := strings.Split(keyId, ":")
-		if len(pieces) != 6 {
-			return nil, ErrInvalidKeyId
-		}
-
-		chk = strings.TrimPrefix(pieces[5], "key/")
+	chk, err := keyIdOf(keyId)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, key := range keys {
@@ -41,63 +46,323 @@ func FindKeyId(keys []KmsKey, keyId string) (*KmsKey, error) {
 	return nil, ErrInvalidKeyId
 }
 
-func (key *KmsKey) EncryptString(stringToEncrypt string, aad []byte) (string, error) {
-	bytes, err := base64.StdEncoding.DecodeString(key.Key)
+// keyIdOf returns the key id or alias name of a key or alias ARN, other key ids as they are.
+func keyIdOf(keyId string) (string, error) {
+
+	if strings.HasPrefix(keyId, "arn:aws:kms:") {
+
+		// (obviously) ignores region and account id
+		pieces := strings.Split(keyId, ":")
+		if len(pieces) != 6 {
+			return "", ErrInvalidKeyId
+		}
+
+		return strings.TrimPrefix(pieces[5], "key/"), nil
+	}
+
+	return keyId, nil
+}
+
+func (key *KmsKey) IsSymmetric() bool {
+	return key.KeySpec == "" || key.KeySpec == types.KeySpecSymmetricDefault
+}
+
+// Signer returns the private key of an asymmetric key.
+func (key *KmsKey) Signer() (crypto.Signer, error) {
+	if key.IsSymmetric() {
+		return nil, ErrInvalidKeyUsage
+	}
+
+	der, err := base64.StdEncoding.DecodeString(key.Key)
 	if err != nil {
-		return "", fmt.Errorf("invalid base64 key: %w", err)
+		return nil, fmt.Errorf("invalid base64 key: %w", err)
 	}
 
-	block, err := aes.NewCipher(bytes)
+	var signer crypto.Signer
+	if key.KeySpec == types.KeySpecEccSecgP256k1 {
+		signer, err = parseSecp256k1PrivateKey(der)
+	} else {
+		var priv any
+		priv, err = x509.ParsePKCS8PrivateKey(der)
+		signer, _ = priv.(crypto.Signer)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
 
-	aesGCM, err := cipher.NewGCM(block)
+	if signer == nil || privateKeySpec(signer) != key.KeySpec {
+		return nil, fmt.Errorf("key %s doesn't match key spec %s", key.KeyId, key.KeySpec)
+	}
+
+	return signer, nil
+}
+
+// usage returns the key usage: symmetric keys encrypt, asymmetric keys sign unless the config
+// says otherwise.
+func (key *KmsKey) usage() types.KeyUsageType {
+	switch {
+	case key.IsSymmetric():
+		return types.KeyUsageTypeEncryptDecrypt
+	case key.KeyUsage != "":
+		return key.KeyUsage
+	}
+	return types.KeyUsageTypeSignVerify
+}
+
+// Encrypt seals plaintext with the newest key material into a ciphertext blob naming the key.
+func (key *KmsKey) Encrypt(plaintext []byte, aad []byte) ([]byte, error) {
+	aesGCM, err := key.aead(key.Key)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
 
 	nonce := make([]byte, aesGCM.NonceSize())
 	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", fmt.Errorf("failed to generate nonce: %w", err)
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	env := ciphertextEnvelope{
+		Version:    ciphertextVersionAuthenticated,
+		KeyId:      key.KeyId,
+		Algorithm:  types.EncryptionAlgorithmSpecSymmetricDefault,
+		KeyVersion: len(key.PreviousKeys),
+		Nonce:      nonce,
+	}
+
+	additional, err := env.additionalData(aad)
+	if err != nil {
+		return nil, err
+	}
+	env.Sealed = aesGCM.Seal(nil, nonce, plaintext, additional)
+
+	return env.marshal()
+}
+
+// Decrypt opens a ciphertext blob of the key, in the current or the earlier format. Blobs of
+// the earlier format were encrypted with the first key material.
+func (key *KmsKey) Decrypt(blob []byte, aad []byte) ([]byte, error) {
+	env, ok := parseCiphertext(blob)
+
+	version := 0
+	if ok {
+		if env.KeyId != key.KeyId {
+			return nil, fmt.Errorf("ciphertext of key %s: %w", env.KeyId, ErrIncorrectKey)
+		}
+		version = env.KeyVersion
+	}
+
+	materials := append(slices.Clone(key.PreviousKeys), key.Key)
+	if version >= len(materials) {
+		return nil, fmt.Errorf("ciphertext of key %s version %d: %w", key.KeyId, version, ErrIncorrectKey)
+	}
+
+	aesGCM, err := key.aead(materials[version])
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce, sealed []byte
+	if ok {
+		if env.Algorithm != types.EncryptionAlgorithmSpecSymmetricDefault || len(env.Nonce) != aesGCM.NonceSize() {
+			return nil, fmt.Errorf("unsupported ciphertext")
+		}
+		nonce, sealed = env.Nonce, env.Sealed
+		if aad, err = env.additionalData(aad); err != nil {
+			return nil, err
+		}
+	} else {
+		nonceSize := aesGCM.NonceSize()
+		if len(blob) < nonceSize {
+			return nil, fmt.Errorf("ciphertext too short")
+		}
+		nonce, sealed = blob[:nonceSize], blob[nonceSize:]
+	}
+
+	plaintext, err := aesGCM.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
 	}
 
-	ciphertext := aesGCM.Seal(nonce, nonce, []byte(stringToEncrypt), aad)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return plaintext, nil
+}
+
+func (key *KmsKey) EncryptString(stringToEncrypt string, aad []byte) (string, error) {
+	blob, err := key.Encrypt([]byte(stringToEncrypt), aad)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(blob), nil
 }
 
 func (key *KmsKey) DecryptString(encryptedString string, aad []byte) (string, error) {
-	enc, err := base64.StdEncoding.DecodeString(encryptedString)
+	blob, err := base64.StdEncoding.DecodeString(encryptedString)
 	if err != nil {
 		return "", fmt.Errorf("invalid base64 ciphertext: %w", err)
 	}
 
-	bytes, err := base64.StdEncoding.DecodeString(key.Key)
+	plaintext, err := key.Decrypt(blob, aad)
 	if err != nil {
-		return "", fmt.Errorf("invalid base64 key: %w", err)
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func (key *KmsKey) aead(material string) (cipher.AEAD, error) {
+	bytes, err := base64.StdEncoding.DecodeString(material)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 key: %w", err)
 	}
 
 	block, err := aes.NewCipher(bytes)
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
 
-	nonceSize := aesGCM.NonceSize()
-	if len(enc) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
+	return aesGCM, nil
+}
 
-	nonce, ciphertext := enc[:nonceSize], enc[nonceSize:]
+// KeyData is a key made by CreateKey, its key material wrapped by the root key; rotations add
+// key material. Config keys have no key material here. Dates are seconds since the epoch.
+type KeyData struct {
+	KeyId               string
+	Description         string
+	KeySpec             types.KeySpec
+	KeyUsage            types.KeyUsageType
+	KeyState            types.KeyState
+	Origin              types.OriginType
+	Policy              string
+	CreationDate        float64
+	DeletionDate        float64     `json:",omitempty"`
+	PendingWindowInDays int32       `json:",omitempty"`
+	Tags                []types.Tag `json:",omitempty"`
+	WrappedKey          []byte      `json:",omitempty"`
 
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, aad)
-	if err != nil {
-		return "", fmt.Errorf("decryption failed: %w", err)
+	// RotationPeriodInDays is 0 when automatic rotation is disabled
+	RotationPeriodInDays int32             `json:",omitempty"`
+	NextRotationDate     float64           `json:",omitempty"`
+	Rotations            []KeyRotationData `json:",omitempty"`
+}
+
+// KeyRotationData is the key material of a rotation, version 1 is the first rotation.
+type KeyRotationData struct {
+	WrappedKey   []byte
+	RotationDate float64
+	RotationType types.RotationType
+}
+
+type AliasData struct {
+	AliasName       string
+	TargetKeyId     string
+	CreationDate    float64
+	LastUpdatedDate float64
+}
+
+// KeyMetadataOutput is types.KeyMetadata with the dates in seconds since the epoch, the way
+// the KMS JSON protocol writes them.
+type KeyMetadataOutput struct {
+	AWSAccountId                string
+	Arn                         string
+	CreationDate                float64
+	CustomerMasterKeySpec       types.CustomerMasterKeySpec
+	DeletionDate                *float64 `json:",omitempty"`
+	Description                 string
+	Enabled                     bool
+	EncryptionAlgorithms        []types.EncryptionAlgorithmSpec `json:",omitempty"`
+	KeyId                       string
+	KeyManager                  types.KeyManagerType
+	KeySpec                     types.KeySpec
+	KeyState                    types.KeyState
+	KeyUsage                    types.KeyUsageType
+	MultiRegion                 bool
+	Origin                      types.OriginType
+	PendingDeletionWindowInDays *int32                       `json:",omitempty"`
+	SigningAlgorithms           []types.SigningAlgorithmSpec `json:",omitempty"`
+}
+
+type DescribeKeyOutput struct {
+	KeyMetadata *KeyMetadataOutput
+}
+
+type ScheduleKeyDeletionOutput struct {
+	DeletionDate        float64
+	KeyId               string
+	KeyState            types.KeyState
+	PendingWindowInDays int32
+}
+
+type GetKeyRotationStatusOutput struct {
+	KeyId                     string
+	KeyRotationEnabled        bool
+	NextRotationDate          *float64 `json:",omitempty"`
+	OnDemandRotationStartDate *float64 `json:",omitempty"`
+	RotationPeriodInDays      *int32   `json:",omitempty"`
+}
+
+type RotationsListEntryOutput struct {
+	KeyId        string
+	RotationDate float64
+	RotationType types.RotationType
+}
+
+type ListKeyRotationsOutput struct {
+	NextMarker *string `json:",omitempty"`
+	Rotations  []RotationsListEntryOutput
+	Truncated  bool
+}
+
+type AliasListEntryOutput struct {
+	AliasArn        string
+	AliasName       string
+	TargetKeyId     string
+	CreationDate    float64
+	LastUpdatedDate float64
+}
+
+type ListAliasesOutput struct {
+	Aliases    []AliasListEntryOutput
+	NextMarker *string `json:",omitempty"`
+	Truncated  bool
+}
+
+func (key *KeyData) isSymmetric() bool {
+	return key.KeySpec == types.KeySpecSymmetricDefault
+}
+
+// fromConfig returns whether the key is a config key, not made by CreateKey.
+func (key *KeyData) fromConfig() bool {
+	return key.WrappedKey == nil
+}
+
+func (key *KeyData) toKeyMetadata(arn string, accountId string) *KeyMetadataOutput {
+
+	result := KeyMetadataOutput{
+		AWSAccountId:          accountId,
+		Arn:                   arn,
+		CreationDate:          key.CreationDate,
+		CustomerMasterKeySpec: types.CustomerMasterKeySpec(key.KeySpec),
+		Description:           key.Description,
+		Enabled:               key.KeyState == types.KeyStateEnabled,
+		KeyId:                 key.KeyId,
+		KeyManager:            types.KeyManagerTypeCustomer,
+		KeySpec:               key.KeySpec,
+		KeyState:              key.KeyState,
+		KeyUsage:              key.KeyUsage,
+		Origin:                key.Origin,
 	}
 
-	return string(plaintext), nil
+	if key.KeyState == types.KeyStatePendingDeletion {
+		result.DeletionDate = &key.DeletionDate
+		result.PendingDeletionWindowInDays = &key.PendingWindowInDays
+	}
+
+	result.EncryptionAlgorithms, result.SigningAlgorithms = keyAlgorithms(key.KeySpec, key.KeyUsage)
+
+	return &result
 }
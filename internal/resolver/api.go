@@ -0,0 +1,228 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"home-fern/internal/awslib"
+	"log"
+	"net/http"
+	"strings"
+
+	awsresolver "github.com/aws/aws-sdk-go-v2/service/route53resolver"
+)
+
+type Api struct {
+	service     *Service
+	credentials *awslib.CredentialsProvider
+}
+
+func NewResolverApi(service *Service, credentials *awslib.CredentialsProvider) *Api {
+
+	return &Api{service: service, credentials: credentials}
+}
+
+func (api *Api) Handle(w http.ResponseWriter, r *http.Request) {
+
+	requestUser := r.Context().Value(awslib.RequestUser)
+	if requestUser == nil {
+		awslib.WriteAwsError(w, http.StatusInternalServerError, awslib.AwsErrorResponse{Code: "InternalFailure", Message: "An internal error occurred."})
+		return
+	}
+
+	creds, _ := api.credentials.FindCredentials(fmt.Sprintf("%v", requestUser))
+
+	amztarget := r.Header.Get("X-Amz-Target")
+
+	awslib.LogEndpoint(r, amztarget, creds)
+
+	if amztarget == "Route53Resolver.AssociateResolverRule" {
+		api.associateResolverRule(w, r)
+	} else if amztarget == "Route53Resolver.CreateResolverRule" {
+		api.createResolverRule(w, r)
+	} else if amztarget == "Route53Resolver.DeleteResolverRule" {
+		api.deleteResolverRule(w, r)
+	} else if amztarget == "Route53Resolver.DisassociateResolverRule" {
+		api.disassociateResolverRule(w, r)
+	} else if amztarget == "Route53Resolver.GetResolverRule" {
+		api.getResolverRule(w, r)
+	} else if amztarget == "Route53Resolver.GetResolverRuleAssociation" {
+		api.getResolverRuleAssociation(w, r)
+	} else if amztarget == "Route53Resolver.ListResolverRules" {
+		api.listResolverRules(w, r)
+	} else if amztarget == "Route53Resolver.ListResolverRuleAssociations" {
+		api.listResolverRuleAssociations(w, r)
+	} else {
+		log.Println("Unknown Target:", amztarget)
+		awslib.WriteAwsError(w, http.StatusBadRequest, awslib.AwsErrorResponse{Code: "ValidationException", Message: "Unknown operation"})
+	}
+}
+
+func (api *Api) associateResolverRule(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.AssociateResolverRuleInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.AssociateResolverRule(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) createResolverRule(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.CreateResolverRuleInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.CreateResolverRule(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) deleteResolverRule(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.DeleteResolverRuleInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.DeleteResolverRule(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) disassociateResolverRule(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.DisassociateResolverRuleInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.DisassociateResolverRule(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) getResolverRule(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.GetResolverRuleInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GetResolverRule(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) getResolverRuleAssociation(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.GetResolverRuleAssociationInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.GetResolverRuleAssociation(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) listResolverRules(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.ListResolverRulesInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ListResolverRules(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func (api *Api) listResolverRuleAssociations(w http.ResponseWriter, r *http.Request) {
+	var request awsresolver.ListResolverRuleAssociationsInput
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.ListResolverRuleAssociations(&request)
+	if err != nil {
+		log.Println("Error:", err)
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseJSON(w, response)
+}
+
+func translateError(err error) (int, awslib.AwsErrorResponse) {
+	if errors.Is(err, ErrResourceNotFound) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "ResourceNotFoundException", Message: errorMessage(err, ErrResourceNotFound)}
+	}
+	if errors.Is(err, ErrResourceExists) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "ResourceExistsException", Message: errorMessage(err, ErrResourceExists)}
+	}
+	if errors.Is(err, ErrResourceInUse) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "ResourceInUseException", Message: errorMessage(err, ErrResourceInUse)}
+	}
+	if errors.Is(err, ErrInvalidParameter) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidParameterException", Message: errorMessage(err, ErrInvalidParameter)}
+	}
+	if errors.Is(err, ErrInvalidRequest) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidRequestException", Message: errorMessage(err, ErrInvalidRequest)}
+	}
+
+	return http.StatusInternalServerError, awslib.AwsErrorResponse{Code: "InternalFailure", Message: "An internal error occurred."}
+}
+
+// errorMessage returns the detail a service error carries after its sentinel.
+func errorMessage(err error, sentinel error) string {
+	return strings.TrimPrefix(err.Error(), sentinel.Error()+": ")
+}
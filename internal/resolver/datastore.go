@@ -0,0 +1,286 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"home-fern/internal/datastore"
+	"io"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	ResolverRulePrefix            = "/rule/"
+	ResolverRuleAssociationPrefix = "/association/"
+)
+
+type dataStore struct {
+	ds *datastore.Datastore
+}
+
+func newDataStore(ds *datastore.Datastore) *dataStore {
+	return &dataStore{ds: ds}
+}
+
+func (ds *dataStore) logKeys(w io.Writer) error {
+	return ds.ds.LogKeys(datastore.Resolver, w)
+}
+
+// deleteResolverRule removes a rule which isn't associated with any VPC.
+func (ds *dataStore) deleteResolverRule(id string) (*ResolverRuleData, error) {
+	var result ResolverRuleData
+
+	err := ds.ds.Update(datastore.Resolver, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(ResolverRulePrefix + id))
+		if v == nil {
+			return ruleNotFound(id)
+		}
+		if err := json.Unmarshal(v, &result); err != nil {
+			return err
+		}
+
+		associations, err := readResolverRuleAssociations(b)
+		if err != nil {
+			return err
+		}
+		for _, assoc := range associations {
+			if assoc.ResolverRuleId == id {
+				return fmt.Errorf("%w: Resolver rule %s is associated with VPC %s", ErrResourceInUse, id, assoc.VPCId)
+			}
+		}
+
+		return b.Delete([]byte(ResolverRulePrefix + id))
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) || errors.Is(err, ErrResourceInUse) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to delete resolver rule %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+func (ds *dataStore) findResolverRules() ([]ResolverRuleData, error) {
+	var result []ResolverRuleData
+
+	err := ds.ds.View(datastore.Resolver, func(b *bbolt.Bucket) error {
+		var err error
+		result, err = readResolverRules(b)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []ResolverRuleData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find resolver rules: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getResolverRule(id string) (*ResolverRuleData, error) {
+	var result ResolverRuleData
+
+	err := ds.ds.View(datastore.Resolver, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(ResolverRulePrefix + id))
+		if v == nil {
+			return ruleNotFound(id)
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, ruleNotFound(id)
+		}
+		if errors.Is(err, ErrResourceNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get resolver rule %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+func (ds *dataStore) putResolverRule(rule *ResolverRuleData, overwrite bool) error {
+	data := []datastore.PutData{{
+		Key:       ResolverRulePrefix + rule.Id,
+		Data:      rule,
+		Overwrite: overwrite,
+	}}
+	err := ds.ds.PutKeys(datastore.Resolver, data)
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) {
+			return fmt.Errorf("%w: Resolver rule %s already exists", ErrResourceExists, rule.Id)
+		}
+		return fmt.Errorf("failed to put resolver rule: %w", err)
+	}
+	return nil
+}
+
+// deleteResolverRuleAssociation removes the association of a rule with a VPC and returns it.
+func (ds *dataStore) deleteResolverRuleAssociation(ruleId string, vpcId string) (*ResolverRuleAssociationData, error) {
+	var result *ResolverRuleAssociationData
+
+	err := ds.ds.Update(datastore.Resolver, func(b *bbolt.Bucket) error {
+		if b.Get([]byte(ResolverRulePrefix+ruleId)) == nil {
+			return ruleNotFound(ruleId)
+		}
+
+		associations, err := readResolverRuleAssociations(b)
+		if err != nil {
+			return err
+		}
+		for i := range associations {
+			if associations[i].ResolverRuleId == ruleId && associations[i].VPCId == vpcId {
+				result = &associations[i]
+				return b.Delete([]byte(ResolverRuleAssociationPrefix + result.Id))
+			}
+		}
+
+		return fmt.Errorf("%w: Resolver rule %s is not associated with VPC %s", ErrResourceNotFound, ruleId, vpcId)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to delete resolver rule association: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) findResolverRuleAssociations() ([]ResolverRuleAssociationData, error) {
+	var result []ResolverRuleAssociationData
+
+	err := ds.ds.View(datastore.Resolver, func(b *bbolt.Bucket) error {
+		var err error
+		result, err = readResolverRuleAssociations(b)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []ResolverRuleAssociationData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find resolver rule associations: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getResolverRuleAssociation(id string) (*ResolverRuleAssociationData, error) {
+	var result ResolverRuleAssociationData
+
+	err := ds.ds.View(datastore.Resolver, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(ResolverRuleAssociationPrefix + id))
+		if v == nil {
+			return associationNotFound(id)
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, associationNotFound(id)
+		}
+		if errors.Is(err, ErrResourceNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get resolver rule association %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// putResolverRuleAssociation stores a new association, a VPC can have only one rule per domain name.
+func (ds *dataStore) putResolverRuleAssociation(assoc *ResolverRuleAssociationData) error {
+
+	err := ds.ds.Update(datastore.Resolver, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(ResolverRulePrefix + assoc.ResolverRuleId))
+		if v == nil {
+			return ruleNotFound(assoc.ResolverRuleId)
+		}
+		var rule ResolverRuleData
+		if err := json.Unmarshal(v, &rule); err != nil {
+			return err
+		}
+
+		associations, err := readResolverRuleAssociations(b)
+		if err != nil {
+			return err
+		}
+		for _, other := range associations {
+			if other.VPCId != assoc.VPCId {
+				continue
+			}
+			if other.ResolverRuleId == assoc.ResolverRuleId {
+				return fmt.Errorf("%w: Resolver rule %s is already associated with VPC %s",
+					ErrResourceExists, assoc.ResolverRuleId, assoc.VPCId)
+			}
+
+			var otherRule ResolverRuleData
+			if v := b.Get([]byte(ResolverRulePrefix + other.ResolverRuleId)); v != nil &&
+				json.Unmarshal(v, &otherRule) == nil && otherRule.DomainName == rule.DomainName {
+				return fmt.Errorf("%w: VPC %s already has resolver rule %s for domain %s",
+					ErrResourceExists, assoc.VPCId, otherRule.Id, rule.DomainName)
+			}
+		}
+
+		return putJson(b, ResolverRuleAssociationPrefix+assoc.Id, assoc)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) || errors.Is(err, ErrResourceExists) {
+			return err
+		}
+		return fmt.Errorf("failed to put resolver rule association: %w", err)
+	}
+	return nil
+}
+
+func readResolverRules(b *bbolt.Bucket) ([]ResolverRuleData, error) {
+	var result []ResolverRuleData
+
+	c := b.Cursor()
+	prefix := []byte(ResolverRulePrefix)
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var rule ResolverRuleData
+		if err := json.Unmarshal(v, &rule); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal resolver rule: %w", err)
+		}
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+func readResolverRuleAssociations(b *bbolt.Bucket) ([]ResolverRuleAssociationData, error) {
+	var result []ResolverRuleAssociationData
+
+	c := b.Cursor()
+	prefix := []byte(ResolverRuleAssociationPrefix)
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var assoc ResolverRuleAssociationData
+		if err := json.Unmarshal(v, &assoc); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal resolver rule association: %w", err)
+		}
+		result = append(result, assoc)
+	}
+	return result, nil
+}
+
+func ruleNotFound(id string) error {
+	return fmt.Errorf("%w: Resolver rule with ID '%s' does not exist", ErrResourceNotFound, id)
+}
+
+func associationNotFound(id string) error {
+	return fmt.Errorf("%w: Resolver rule association with ID '%s' does not exist", ErrResourceNotFound, id)
+}
+
+func putJson(b *bbolt.Bucket, key string, data interface{}) error {
+	jsonbytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), jsonbytes)
+}
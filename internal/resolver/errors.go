@@ -0,0 +1,11 @@
+package resolver
+
+import "errors"
+
+var (
+	ErrResourceNotFound = errors.New("the specified resource doesn't exist")
+	ErrResourceExists   = errors.New("the specified resource already exists")
+	ErrResourceInUse    = errors.New("the specified resource is in use")
+	ErrInvalidParameter = errors.New("one or more parameters in this request are not valid")
+	ErrInvalidRequest   = errors.New("the request is invalid")
+)
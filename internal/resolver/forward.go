@@ -0,0 +1,99 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"home-fern/internal/core"
+	"net"
+	"slices"
+	"time"
+
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53resolver/types"
+	"github.com/miekg/dns"
+)
+
+// forwardTimeout bounds the wait for each target name server.
+const forwardTimeout = 2 * time.Second
+
+// Forward answers a query from the targets of the most specific rule associated with one of
+// the client's VPCs. A nil message means home-fern answers the query itself: no rule matches,
+// the rule is a SYSTEM rule, or zone, the most specific hosted zone the client sees, is more
+// specific than the rule.
+func (s *Service) Forward(r *dns.Msg, clientIP net.IP, zone string) (*dns.Msg, error) {
+
+	rule, err := s.matchRule(r.Question[0].Name, clientIP)
+	if err != nil || rule == nil {
+		return nil, err
+	}
+
+	if rule.RuleType != awstypes.RuleTypeOptionForward ||
+		(zone != "" && dns.CountLabel(zone) > dns.CountLabel(rule.DomainName)) {
+		return nil, nil
+	}
+
+	reply, err := exchange(r, rule.TargetIps)
+	if err != nil {
+		return nil, fmt.Errorf("forwarding %s for rule %s: %w", r.Question[0].Name, rule.Id, err)
+	}
+
+	return reply, nil
+}
+
+// matchRule returns the rule with the longest domain name containing name among the rules
+// associated with a VPC the client is in.
+func (s *Service) matchRule(name string, clientIP net.IP) (*ResolverRuleData, error) {
+
+	associations, err := s.dataStore.findResolverRuleAssociations()
+	if err != nil || len(associations) == 0 {
+		return nil, err
+	}
+
+	var match *ResolverRuleData
+	for _, assoc := range associations {
+
+		if vpc, found := s.vpcs[assoc.VPCId]; !found || !core.IPInList(vpc.Cidrs, clientIP) {
+			continue
+		}
+
+		rule, err := s.dataStore.getResolverRule(assoc.ResolverRuleId)
+		if err != nil {
+			return nil, err
+		}
+
+		if dns.IsSubDomain(rule.DomainName, name) &&
+			(match == nil || dns.CountLabel(rule.DomainName) > dns.CountLabel(match.DomainName)) {
+			match = rule
+		}
+	}
+
+	return match, nil
+}
+
+// exchange sends the query to each target in turn until one answers, truncated UDP
+// answers are retried over TCP.
+func exchange(r *dns.Msg, targets []TargetAddressData) (*dns.Msg, error) {
+
+	query := r.Copy()
+	query.Extra = slices.DeleteFunc(query.Extra, func(rr dns.RR) bool {
+		return rr.Header().Rrtype == dns.TypeTSIG
+	})
+
+	udp := &dns.Client{Net: "udp", Timeout: forwardTimeout}
+	tcp := &dns.Client{Net: "tcp", Timeout: forwardTimeout}
+
+	err := errors.New("no targets")
+	for _, target := range targets {
+
+		var reply *dns.Msg
+		reply, _, err = udp.Exchange(query, target.address())
+		if err == nil && reply.Truncated {
+			reply, _, err = tcp.Exchange(query, target.address())
+		}
+
+		if err == nil {
+			return reply, nil
+		}
+	}
+
+	return nil, err
+}
@@ -0,0 +1,323 @@
+package resolver
+
+import (
+	"cmp"
+	"fmt"
+	"home-fern/internal/core"
+	"home-fern/internal/datastore"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsresolver "github.com/aws/aws-sdk-go-v2/service/route53resolver"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53resolver/types"
+	"github.com/miekg/dns"
+)
+
+const (
+	ResolverRuleIdPrefix            = "rslvr-rr-"
+	ResolverRuleAssociationIdPrefix = "rslvr-rrassoc-"
+
+	defaultMaxResults = 100
+	maxTargetIps      = 6
+)
+
+type Service struct {
+	dataStore *dataStore
+	accountId string
+	region    string
+	vpcs      map[string]core.DnsVpc
+}
+
+func NewService(fernConfig *core.FernConfig, accountId string, ds *datastore.Datastore) *Service {
+
+	result := Service{
+		dataStore: newDataStore(ds),
+		accountId: accountId,
+		region:    fernConfig.Region,
+		vpcs:      make(map[string]core.DnsVpc),
+	}
+
+	for _, vpc := range fernConfig.DnsDefaults.Vpcs {
+		result.vpcs[vpc.Id] = vpc
+	}
+
+	return &result
+}
+
+func (s *Service) AssociateResolverRule(
+	request *awsresolver.AssociateResolverRuleInput) (*ResolverRuleAssociationOutput, error) {
+
+	vpcId := aws.ToString(request.VPCId)
+	if _, found := s.vpcs[vpcId]; !found {
+		return nil, fmt.Errorf("%w: [VPCId] VPC %s isn't configured in home-fern", ErrInvalidParameter, vpcId)
+	}
+
+	assoc := ResolverRuleAssociationData{
+		Id:             ResolverRuleAssociationIdPrefix + newId(),
+		Name:           aws.ToString(request.Name),
+		ResolverRuleId: aws.ToString(request.ResolverRuleId),
+		Status:         awstypes.ResolverRuleAssociationStatusComplete,
+		VPCId:          vpcId,
+	}
+
+	if err := s.dataStore.putResolverRuleAssociation(&assoc); err != nil {
+		return nil, err
+	}
+
+	return &ResolverRuleAssociationOutput{ResolverRuleAssociation: &assoc}, nil
+}
+
+func (s *Service) CreateResolverRule(request *awsresolver.CreateResolverRuleInput) (*ResolverRuleOutput, error) {
+
+	creatorRequestId := aws.ToString(request.CreatorRequestId)
+	if creatorRequestId == "" {
+		return nil, fmt.Errorf("%w: [CreatorRequestId] is required", ErrInvalidParameter)
+	}
+
+	domainName := strings.ToLower(dns.Fqdn(aws.ToString(request.DomainName)))
+	if _, ok := dns.IsDomainName(domainName); !ok || aws.ToString(request.DomainName) == "" {
+		return nil, fmt.Errorf("%w: [DomainName] '%s' is not a valid domain name",
+			ErrInvalidParameter, aws.ToString(request.DomainName))
+	}
+
+	rules, err := s.dataStore.findResolverRules()
+	if err != nil {
+		return nil, err
+	}
+
+	// a retried request gets the rule created the first time
+	for i := range rules {
+		if rules[i].CreatorRequestId == creatorRequestId {
+			if rules[i].DomainName != domainName || rules[i].RuleType != request.RuleType {
+				return nil, fmt.Errorf("%w: CreatorRequestId %s was used for resolver rule %s",
+					ErrResourceExists, creatorRequestId, rules[i].Id)
+			}
+			return &ResolverRuleOutput{ResolverRule: &rules[i]}, nil
+		}
+	}
+
+	switch request.RuleType {
+	case awstypes.RuleTypeOptionForward:
+		if len(request.TargetIps) == 0 {
+			return nil, fmt.Errorf("%w: FORWARD rules need at least one target IP", ErrInvalidRequest)
+		}
+	case awstypes.RuleTypeOptionSystem:
+		if len(request.TargetIps) > 0 {
+			return nil, fmt.Errorf("%w: SYSTEM rules can't have target IPs", ErrInvalidRequest)
+		}
+	case awstypes.RuleTypeOptionRecursive:
+		return nil, fmt.Errorf("%w: RECURSIVE rules are defined by Resolver", ErrInvalidRequest)
+	default:
+		return nil, fmt.Errorf("%w: [RuleType] '%s' is not one of [FORWARD, SYSTEM]", ErrInvalidParameter, request.RuleType)
+	}
+
+	if len(request.TargetIps) > maxTargetIps {
+		return nil, fmt.Errorf("%w: [TargetIps] a rule can have at most %d targets", ErrInvalidParameter, maxTargetIps)
+	}
+
+	id := ResolverRuleIdPrefix + newId()
+	now := timestamp()
+
+	rule := ResolverRuleData{
+		Arn:                fmt.Sprintf("arn:aws:route53resolver:%s:%s:resolver-rule/%s", s.region, s.accountId, id),
+		CreationTime:       now,
+		CreatorRequestId:   creatorRequestId,
+		DomainName:         domainName,
+		Id:                 id,
+		ModificationTime:   now,
+		Name:               aws.ToString(request.Name),
+		OwnerId:            s.accountId,
+		ResolverEndpointId: aws.ToString(request.ResolverEndpointId),
+		RuleType:           request.RuleType,
+		ShareStatus:        awstypes.ShareStatusNotShared,
+		Status:             awstypes.ResolverRuleStatusComplete,
+		StatusMessage:      "[Trace id: " + id + "] Successfully created Resolver Rule.",
+	}
+
+	for _, target := range request.TargetIps {
+		address, err := NewTargetAddressData(target)
+		if err != nil {
+			return nil, err
+		}
+		rule.TargetIps = append(rule.TargetIps, address)
+	}
+
+	if err := s.dataStore.putResolverRule(&rule, false); err != nil {
+		return nil, err
+	}
+
+	return &ResolverRuleOutput{ResolverRule: &rule}, nil
+}
+
+func (s *Service) DeleteResolverRule(request *awsresolver.DeleteResolverRuleInput) (*ResolverRuleOutput, error) {
+
+	rule, err := s.dataStore.deleteResolverRule(aws.ToString(request.ResolverRuleId))
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Status = awstypes.ResolverRuleStatusDeleting
+	rule.StatusMessage = "[Trace id: " + rule.Id + "] Deleting Resolver Rule."
+
+	return &ResolverRuleOutput{ResolverRule: rule}, nil
+}
+
+func (s *Service) DisassociateResolverRule(
+	request *awsresolver.DisassociateResolverRuleInput) (*ResolverRuleAssociationOutput, error) {
+
+	assoc, err := s.dataStore.deleteResolverRuleAssociation(
+		aws.ToString(request.ResolverRuleId), aws.ToString(request.VPCId))
+	if err != nil {
+		return nil, err
+	}
+
+	assoc.Status = awstypes.ResolverRuleAssociationStatusDeleting
+
+	return &ResolverRuleAssociationOutput{ResolverRuleAssociation: assoc}, nil
+}
+
+func (s *Service) GetResolverRule(request *awsresolver.GetResolverRuleInput) (*ResolverRuleOutput, error) {
+
+	rule, err := s.dataStore.getResolverRule(aws.ToString(request.ResolverRuleId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResolverRuleOutput{ResolverRule: rule}, nil
+}
+
+func (s *Service) GetResolverRuleAssociation(
+	request *awsresolver.GetResolverRuleAssociationInput) (*ResolverRuleAssociationOutput, error) {
+
+	assoc, err := s.dataStore.getResolverRuleAssociation(aws.ToString(request.ResolverRuleAssociationId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResolverRuleAssociationOutput{ResolverRuleAssociation: assoc}, nil
+}
+
+func (s *Service) ListResolverRules(request *awsresolver.ListResolverRulesInput) (*ListResolverRulesOutput, error) {
+
+	rules, err := s.dataStore.findResolverRules()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]func(rule *ResolverRuleData) string{
+		"CREATOR_REQUEST_ID":   func(rule *ResolverRuleData) string { return rule.CreatorRequestId },
+		"DOMAIN_NAME":          func(rule *ResolverRuleData) string { return rule.DomainName },
+		"NAME":                 func(rule *ResolverRuleData) string { return rule.Name },
+		"RESOLVER_ENDPOINT_ID": func(rule *ResolverRuleData) string { return rule.ResolverEndpointId },
+		"STATUS":               func(rule *ResolverRuleData) string { return string(rule.Status) },
+		"TYPE":                 func(rule *ResolverRuleData) string { return string(rule.RuleType) },
+	}
+
+	rules, err = filter(rules, request.Filters, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	page, maxResults, nextToken := paginate(rules, request.MaxResults, request.NextToken,
+		func(rule ResolverRuleData) string { return rule.Id })
+
+	return &ListResolverRulesOutput{
+		MaxResults:    maxResults,
+		NextToken:     nextToken,
+		ResolverRules: page,
+	}, nil
+}
+
+func (s *Service) ListResolverRuleAssociations(
+	request *awsresolver.ListResolverRuleAssociationsInput) (*ListResolverRuleAssociationsOutput, error) {
+
+	associations, err := s.dataStore.findResolverRuleAssociations()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]func(assoc *ResolverRuleAssociationData) string{
+		"NAME":             func(assoc *ResolverRuleAssociationData) string { return assoc.Name },
+		"RESOLVER_RULE_ID": func(assoc *ResolverRuleAssociationData) string { return assoc.ResolverRuleId },
+		"STATUS":           func(assoc *ResolverRuleAssociationData) string { return string(assoc.Status) },
+		"VPC_ID":           func(assoc *ResolverRuleAssociationData) string { return assoc.VPCId },
+	}
+
+	associations, err = filter(associations, request.Filters, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	page, maxResults, nextToken := paginate(associations, request.MaxResults, request.NextToken,
+		func(assoc ResolverRuleAssociationData) string { return assoc.Id })
+
+	return &ListResolverRuleAssociationsOutput{
+		MaxResults:               maxResults,
+		NextToken:                nextToken,
+		ResolverRuleAssociations: page,
+	}, nil
+}
+
+func (s *Service) LogKeys(writer io.Writer) error {
+	return s.dataStore.logKeys(writer)
+}
+
+// filter keeps the items matching all filters, an item matches a filter when the field
+// has one of the filter's values.
+func filter[T any](items []T, filters []awstypes.Filter, fields map[string]func(*T) string) ([]T, error) {
+
+	for _, f := range filters {
+		field, found := fields[aws.ToString(f.Name)]
+		if !found {
+			return nil, fmt.Errorf("%w: [Filters] '%s' is not a supported filter name",
+				ErrInvalidParameter, aws.ToString(f.Name))
+		}
+
+		items = slices.DeleteFunc(items, func(item T) bool {
+			return !slices.Contains(f.Values, field(&item))
+		})
+	}
+
+	return items, nil
+}
+
+// paginate sorts items by id and returns the page starting at nextToken, which is the id
+// of the first item of the page.
+func paginate[T any](
+	items []T, maxResults *int32, nextToken *string, id func(T) string) ([]T, int32, *string) {
+
+	limit := int32(defaultMaxResults)
+	if maxResults != nil && *maxResults > 0 && *maxResults < limit {
+		limit = *maxResults
+	}
+
+	slices.SortFunc(items, func(a, b T) int { return cmp.Compare(id(a), id(b)) })
+
+	start := 0
+	if nextToken != nil {
+		start, _ = slices.BinarySearchFunc(items, *nextToken, func(item T, token string) int {
+			return cmp.Compare(id(item), token)
+		})
+	}
+	items = items[start:]
+
+	if len(items) > int(limit) {
+		return items[:limit], limit, aws.String(id(items[limit]))
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+	return items, limit, nil
+}
+
+func newId() string {
+	return strings.ToLower(core.GenerateRandomString(17))
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+}
@@ -0,0 +1,113 @@
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53resolver/types"
+)
+
+type TargetAddressData struct {
+	Ip       string `json:",omitempty"`
+	Ipv6     string `json:",omitempty"`
+	Port     int32
+	Protocol awstypes.Protocol
+}
+
+// address returns the host:port queries are forwarded to.
+func (t TargetAddressData) address() string {
+
+	ip := t.Ip
+	if ip == "" {
+		ip = t.Ipv6
+	}
+
+	return net.JoinHostPort(ip, strconv.Itoa(int(t.Port)))
+}
+
+func NewTargetAddressData(target awstypes.TargetAddress) (TargetAddressData, error) {
+
+	result := TargetAddressData{
+		Port:     53,
+		Protocol: target.Protocol,
+	}
+
+	if target.Port != nil {
+		result.Port = *target.Port
+	}
+	if result.Protocol == "" {
+		result.Protocol = awstypes.ProtocolDo53
+	}
+
+	if target.Ip != nil {
+		if ip := net.ParseIP(*target.Ip); ip == nil || ip.To4() == nil {
+			return result, fmt.Errorf("%w: [TargetIps.Ip] '%s' is not a valid IPv4 address", ErrInvalidParameter, *target.Ip)
+		}
+		result.Ip = *target.Ip
+	}
+	if target.Ipv6 != nil {
+		if ip := net.ParseIP(*target.Ipv6); ip == nil || ip.To4() != nil {
+			return result, fmt.Errorf("%w: [TargetIps.Ipv6] '%s' is not a valid IPv6 address", ErrInvalidParameter, *target.Ipv6)
+		}
+		result.Ipv6 = *target.Ipv6
+	}
+
+	if (result.Ip == "") == (result.Ipv6 == "") {
+		return result, fmt.Errorf("%w: [TargetIps] each target needs exactly one of Ip and Ipv6", ErrInvalidParameter)
+	}
+	if result.Port < 1 || result.Port > 65535 {
+		return result, fmt.Errorf("%w: [TargetIps.Port] must be between 1 and 65535", ErrInvalidParameter)
+	}
+	if result.Protocol != awstypes.ProtocolDo53 {
+		return result, fmt.Errorf("%w: [TargetIps.Protocol] only Do53 is supported", ErrInvalidParameter)
+	}
+
+	return result, nil
+}
+
+type ResolverRuleData struct {
+	Arn                string
+	CreationTime       string
+	CreatorRequestId   string
+	DomainName         string
+	Id                 string
+	ModificationTime   string
+	Name               string `json:",omitempty"`
+	OwnerId            string
+	ResolverEndpointId string `json:",omitempty"`
+	RuleType           awstypes.RuleTypeOption
+	ShareStatus        awstypes.ShareStatus
+	Status             awstypes.ResolverRuleStatus
+	StatusMessage      string
+	TargetIps          []TargetAddressData `json:",omitempty"`
+}
+
+type ResolverRuleAssociationData struct {
+	Id             string
+	Name           string `json:",omitempty"`
+	ResolverRuleId string
+	Status         awstypes.ResolverRuleAssociationStatus
+	StatusMessage  string
+	VPCId          string
+}
+
+type ResolverRuleOutput struct {
+	ResolverRule *ResolverRuleData
+}
+
+type ResolverRuleAssociationOutput struct {
+	ResolverRuleAssociation *ResolverRuleAssociationData
+}
+
+type ListResolverRulesOutput struct {
+	MaxResults    int32
+	NextToken     *string `json:",omitempty"`
+	ResolverRules []ResolverRuleData
+}
+
+type ListResolverRuleAssociationsOutput struct {
+	MaxResults               int32
+	NextToken                *string `json:",omitempty"`
+	ResolverRuleAssociations []ResolverRuleAssociationData
+}
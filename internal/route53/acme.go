@@ -0,0 +1,136 @@
+package route53
+
+import (
+	"fmt"
+	"home-fern/internal/core"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// acmeChallengeTtl is the TTL of the TXT records answering ACME DNS-01 challenges, short so
+// a CA sees a new token soon after it's written.
+const acmeChallengeTtl = 60
+
+// AcmeChallengeZone returns the hosted zone the challenge records of name go into: the most
+// specific zone containing name, a public zone before a private one of the same name.
+func (s *Service) AcmeChallengeZone(name string) (*HostedZoneData, error) {
+
+	zones, err := s.dataStore.findHostedZones(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	name = normalizeDnsName(name)
+
+	var match *HostedZoneData
+	for i := range zones {
+
+		if !isSubdomain(name, zones[i].Name) {
+			continue
+		}
+
+		if match == nil || len(zones[i].Name) > len(match.Name) ||
+			(zones[i].Name == match.Name && match.Config.PrivateZone) {
+			match = &zones[i]
+		}
+	}
+
+	if match == nil {
+		return nil, ErrNoSuchHostedZone
+	}
+	return match, nil
+}
+
+// PutAcmeChallenge puts values in place of the previous ones in the TXT record set of name, as a
+// change to its hosted zone. The other values of the set are left alone, a set left without
+// values is deleted.
+func (s *Service) PutAcmeChallenge(name string, previous []string, values []string) error {
+
+	hz, err := s.AcmeChallengeZone(name)
+	if err != nil {
+		return err
+	}
+
+	name = normalizeDnsName(name)
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "ACME challenge",
+	}
+
+	changed := false
+	_, err = s.dataStore.changeRecordSets(hz, &ci, func(records []ResourceRecordSetData) ([]ChangeData, error) {
+		rrset := ResourceRecordSetData{Name: name, Type: awstypes.RRTypeTxt, TTL: aws.Int64(acmeChallengeTtl)}
+		var existing *ResourceRecordSetData
+		for i := range records {
+			if records[i].Type == awstypes.RRTypeTxt && normalizeDnsName(records[i].Name) == name &&
+				records[i].SetIdentifier == nil {
+				existing = &records[i]
+				rrset.TTL = existing.TTL
+			}
+		}
+
+		var kept []string
+		if existing != nil {
+			for _, rr := range existing.ResourceRecords {
+				value := txtValue(aws.ToString(rr.Value))
+				if !slices.Contains(previous, value) || slices.Contains(values, value) {
+					rrset.ResourceRecords = append(rrset.ResourceRecords, rr)
+					kept = append(kept, value)
+				}
+			}
+		}
+		for _, value := range values {
+			if !slices.Contains(kept, value) {
+				rrset.ResourceRecords = append(rrset.ResourceRecords, awstypes.ResourceRecord{Value: aws.String(strconv.Quote(value))})
+				kept = append(kept, value)
+			}
+		}
+
+		var change ChangeData
+		switch {
+		case existing != nil && slices.EqualFunc(rrset.ResourceRecords, existing.ResourceRecords, sameValue):
+			return nil, nil
+		case len(rrset.ResourceRecords) > 0:
+			change = ChangeData{Action: awstypes.ChangeActionUpsert, ResourceRecordSet: &rrset}
+		case existing != nil:
+			change = ChangeData{Action: awstypes.ChangeActionDelete, ResourceRecordSet: existing}
+		default:
+			return nil, nil
+		}
+
+		changes := []ChangeData{change}
+		if err := validateChangeBatch(hz, records, changes); err != nil {
+			return nil, fmt.Errorf("acme challenge %s: %w", name, err)
+		}
+		changed = true
+		return changes, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		s.zoneChanged(hz)
+	}
+
+	return nil
+}
+
+// txtValue returns the text of a TXT record value of one quoted string, other values as they are.
+func txtValue(value string) string {
+	if text, err := strconv.Unquote(value); err == nil {
+		return text
+	}
+	return value
+}
+
+func sameValue(a awstypes.ResourceRecord, b awstypes.ResourceRecord) bool {
+	return aws.ToString(a.Value) == aws.ToString(b.Value)
+}
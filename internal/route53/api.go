@@ -42,6 +42,96 @@ func (api *Api) logEndpoint(w http.ResponseWriter, r *http.Request, amztarget st
 	awslib.LogEndpoint(r, amztarget, creds)
 }
 
+func (api *Api) ActivateKeySigningKey(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/keysigningkey/HostedZoneId/Name/activate
+
+	api.logEndpoint(w, r, "Route53.ActivateKeySigningKey")
+
+	var request aws53.ActivateKeySigningKeyInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+	request.Name = aws.String(vars["name"])
+
+	response, err := api.service.ActivateKeySigningKey(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"ActivateKeySigningKeyResponse"`
+		*aws53.ActivateKeySigningKeyOutput
+	}{
+		ActivateKeySigningKeyOutput: response,
+	})
+}
+
+func (api *Api) AssociateVPCWithHostedZone(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/hostedzone/Id/associatevpc
+
+	api.logEndpoint(w, r, "Route53.AssociateVPCWithHostedZone")
+
+	var request aws53.AssociateVPCWithHostedZoneInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+
+	response, err := api.service.AssociateVPCWithHostedZone(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"AssociateVPCWithHostedZoneResponse"`
+		*aws53.AssociateVPCWithHostedZoneOutput
+	}{
+		AssociateVPCWithHostedZoneOutput: response,
+	})
+}
+
+func (api *Api) ChangeCidrCollection(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/cidrcollection/CidrCollectionId
+
+	api.logEndpoint(w, r, "Route53.ChangeCidrCollection")
+
+	var request ChangeCidrCollectionRequest
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	request.Id = vars["id"]
+
+	response, err := api.service.ChangeCidrCollection(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"ChangeCidrCollectionResponse"`
+		*aws53.ChangeCidrCollectionOutput
+	}{
+		ChangeCidrCollectionOutput: response,
+	})
+}
+
 func (api *Api) ChangeResourceRecordSets(w http.ResponseWriter, r *http.Request) {
 
 	api.logEndpoint(w, r, "Route53.ChangeResourceRecordSets")
@@ -59,6 +149,12 @@ func (api *Api) ChangeResourceRecordSets(w http.ResponseWriter, r *http.Request)
 
 	response, err := api.service.ChangeResourceRecordSets(&request)
 	if err != nil {
+		var batchErr *InvalidChangeBatchError
+		if errors.As(err, &batchErr) {
+			writeInvalidChangeBatch(w, batchErr)
+			return
+		}
+
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
 		return
@@ -115,18 +211,20 @@ func (api *Api) ChangeTagsForResource(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (api *Api) CreateHostedZone(w http.ResponseWriter, r *http.Request) {
+func (api *Api) CreateCidrCollection(w http.ResponseWriter, r *http.Request) {
 
-	api.logEndpoint(w, r, "Route53.CreateHostedZone")
+	// POST /2013-04-01/cidrcollection
 
-	var request aws53.CreateHostedZoneInput
+	api.logEndpoint(w, r, "Route53.CreateCidrCollection")
+
+	var request aws53.CreateCidrCollectionInput
 	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
 		log.Println("Error:", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response, err := api.service.CreateHostedZone(&request)
+	response, err := api.service.CreateCidrCollection(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
@@ -134,26 +232,27 @@ func (api *Api) CreateHostedZone(w http.ResponseWriter, r *http.Request) {
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName xml.Name `xml:"CreateHostedZoneResponse"`
-		*aws53.CreateHostedZoneOutput
+		XMLName    xml.Name `xml:"CreateCidrCollectionResponse"`
+		Collection *CidrCollectionData
 	}{
-		CreateHostedZoneOutput: response,
+		Collection: response,
 	})
 }
 
-func (api *Api) DeleteHostedZone(w http.ResponseWriter, r *http.Request) {
-
-	// DELETE /2013-04-01/hostedzone/Id
+func (api *Api) CreateHealthCheck(w http.ResponseWriter, r *http.Request) {
 
-	api.logEndpoint(w, r, "Route53.DeleteHostedZone")
+	// POST /2013-04-01/healthcheck
 
-	var request aws53.DeleteHostedZoneInput
+	api.logEndpoint(w, r, "Route53.CreateHealthCheck")
 
-	// Parsing the path parameters
-	vars := mux.Vars(r)
-	request.Id = aws.String(vars["id"])
+	var request CreateHealthCheckRequest
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	response, err := api.service.DeleteHostedZone(&request)
+	response, err := api.service.CreateHealthCheck(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
@@ -161,26 +260,25 @@ func (api *Api) DeleteHostedZone(w http.ResponseWriter, r *http.Request) {
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName xml.Name `xml:"DeleteHostedZoneResponse"`
-		*aws53.DeleteHostedZoneOutput
+		XMLName     xml.Name `xml:"CreateHealthCheckResponse"`
+		HealthCheck *HealthCheckData
 	}{
-		DeleteHostedZoneOutput: response,
+		HealthCheck: response,
 	})
 }
 
-func (api *Api) GetChange(w http.ResponseWriter, r *http.Request) {
-
-	// GET /2013-04-01/change/Id
-
-	api.logEndpoint(w, r, "Route53.GetChange")
+func (api *Api) CreateHostedZone(w http.ResponseWriter, r *http.Request) {
 
-	var request aws53.GetChangeInput
+	api.logEndpoint(w, r, "Route53.CreateHostedZone")
 
-	// Parsing the path parameters
-	vars := mux.Vars(r)
-	request.Id = aws.String(vars["id"])
+	var request aws53.CreateHostedZoneInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	response, err := api.service.GetChange(&request)
+	response, err := api.service.CreateHostedZone(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
@@ -188,59 +286,55 @@ func (api *Api) GetChange(w http.ResponseWriter, r *http.Request) {
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName xml.Name `xml:"GetChangeResponse"`
-		*aws53.GetChangeOutput
+		XMLName xml.Name `xml:"CreateHostedZoneResponse"`
+		*aws53.CreateHostedZoneOutput
 	}{
-		GetChangeOutput: response,
+		CreateHostedZoneOutput: response,
 	})
 }
 
-func (api *Api) GetHostedZone(w http.ResponseWriter, r *http.Request) {
-
-	// GET /2013-04-01/hostedzone/Id
+func (api *Api) CreateKeySigningKey(w http.ResponseWriter, r *http.Request) {
 
-	api.logEndpoint(w, r, "Route53.GetHostedZone")
+	// POST /2013-04-01/keysigningkey
 
-	var request aws53.GetHostedZoneInput
+	api.logEndpoint(w, r, "Route53.CreateKeySigningKey")
 
-	// Parsing the path parameters
-	vars := mux.Vars(r)
-	request.Id = aws.String(vars["id"])
+	var request aws53.CreateKeySigningKeyInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	response, err := api.service.GetHostedZone(&request)
+	response, err := api.service.CreateKeySigningKey(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
 		return
 	}
 
-	type DelegationSetWrapper struct {
-		NameServers     []string `xml:"NameServers>NameServer"`
-		CallerReference *string  `xml:",omitempty"`
-		Id              *string  `xml:",omitempty"`
-	}
-
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName       xml.Name `xml:"GetHostedZoneResponse"`
-		HostedZone    *awstypes.HostedZone
-		DelegationSet DelegationSetWrapper
+		XMLName xml.Name `xml:"CreateKeySigningKeyResponse"`
+		*aws53.CreateKeySigningKeyOutput
 	}{
-		HostedZone: response.HostedZone,
-		DelegationSet: DelegationSetWrapper{
-			NameServers:     response.DelegationSet.NameServers,
-			CallerReference: response.DelegationSet.CallerReference,
-			Id:              response.DelegationSet.Id,
-		},
+		CreateKeySigningKeyOutput: response,
 	})
 }
 
-func (api *Api) GetHostedZoneCount(w http.ResponseWriter, r *http.Request) {
+func (api *Api) CreateQueryLoggingConfig(w http.ResponseWriter, r *http.Request) {
 
-	// GET /2013-04-01/hostedzonecount
+	// POST /2013-04-01/queryloggingconfig
 
-	api.logEndpoint(w, r, "Route53.GetHostedZoneCount")
+	api.logEndpoint(w, r, "Route53.CreateQueryLoggingConfig")
 
-	response, err := api.service.GetHostedZoneCount()
+	var request aws53.CreateQueryLoggingConfigInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.service.CreateQueryLoggingConfig(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
@@ -248,30 +342,27 @@ func (api *Api) GetHostedZoneCount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName xml.Name `xml:"GetHostedZoneCountResponse"`
-		*aws53.GetHostedZoneCountOutput
+		XMLName            xml.Name `xml:"CreateQueryLoggingConfigResponse"`
+		QueryLoggingConfig *QueryLoggingConfigData
 	}{
-		GetHostedZoneCountOutput: response,
+		QueryLoggingConfig: response,
 	})
 }
 
-func (api *Api) ListHostedZonesByName(w http.ResponseWriter, r *http.Request) {
-
-	api.logEndpoint(w, r, "Route53.ListHostedZonesByName")
+func (api *Api) CreateReusableDelegationSet(w http.ResponseWriter, r *http.Request) {
 
-	var request aws53.ListHostedZonesByNameInput
+	// POST /2013-04-01/delegationset
 
-	// Parsing the query parameters
-	query := r.URL.Query()
-	request.HostedZoneId = aws.String(query.Get("hostedzoneid"))
-	request.DNSName = aws.String(query.Get("dnsname"))
+	api.logEndpoint(w, r, "Route53.CreateReusableDelegationSet")
 
-	mi, merr := strconv.Atoi(query.Get("maxitems"))
-	if merr == nil {
-		request.MaxItems = aws.Int32(int32(mi))
+	var request aws53.CreateReusableDelegationSetInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	response, err := api.service.ListHostedZonesByName(&request)
+	response, err := api.service.CreateReusableDelegationSet(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
@@ -279,45 +370,27 @@ func (api *Api) ListHostedZonesByName(w http.ResponseWriter, r *http.Request) {
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName          xml.Name              `xml:"ListHostedZonesByNameResponse"`
-		HostedZones      []awstypes.HostedZone `xml:"HostedZones>HostedZone"`
-		IsTruncated      bool
-		MaxItems         *int32
-		DNSName          *string
-		HostedZoneId     *string
-		NextDNSName      *string `xml:",omitempty"`
-		NextHostedZoneId *string `xml:",omitempty"`
+		XMLName       xml.Name `xml:"CreateReusableDelegationSetResponse"`
+		DelegationSet *DelegationSetData
 	}{
-		HostedZones:      response.HostedZones,
-		IsTruncated:      response.IsTruncated,
-		DNSName:          response.DNSName,
-		HostedZoneId:     response.HostedZoneId,
-		MaxItems:         response.MaxItems,
-		NextDNSName:      response.NextDNSName,
-		NextHostedZoneId: response.NextHostedZoneId,
+		DelegationSet: response,
 	})
 }
 
-func (api *Api) ListHostedZones(w http.ResponseWriter, r *http.Request) {
-
-	// GET /2013-04-01/hostedzone?delegationsetid=DelegationSetId&hostedzonetype=HostedZoneType&marker=Marker&maxitems=MaxItems
-
-	api.logEndpoint(w, r, "Route53.ListHostedZones")
+func (api *Api) CreateTrafficPolicy(w http.ResponseWriter, r *http.Request) {
 
-	var request aws53.ListHostedZonesInput
+	// POST /2013-04-01/trafficpolicy
 
-	// Parsing the query parameters
-	query := r.URL.Query()
-	request.DelegationSetId = aws.String(query.Get("delegationsetid"))
-	request.HostedZoneType = awstypes.HostedZoneType(query.Get("hostedzonetype"))
-	request.Marker = aws.String(query.Get("marker"))
+	api.logEndpoint(w, r, "Route53.CreateTrafficPolicy")
 
-	mi, merr := strconv.Atoi(query.Get("maxitems"))
-	if merr == nil {
-		request.MaxItems = aws.Int32(int32(mi))
+	var request aws53.CreateTrafficPolicyInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	response, err := api.service.ListHostedZones(&request)
+	response, err := api.service.CreateTrafficPolicy(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
@@ -325,43 +398,27 @@ func (api *Api) ListHostedZones(w http.ResponseWriter, r *http.Request) {
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName     xml.Name              `xml:"ListHostedZoneResponse"`
-		HostedZones []awstypes.HostedZone `xml:"HostedZones>HostedZone"`
-		IsTruncated bool
-		MaxItems    *int32
-		Marker      *string `xml:",omitempty"`
-		NextMarker  *string `xml:",omitempty"`
+		XMLName       xml.Name `xml:"CreateTrafficPolicyResponse"`
+		TrafficPolicy *TrafficPolicyData
 	}{
-		HostedZones: response.HostedZones,
-		IsTruncated: response.IsTruncated,
-		Marker:      response.Marker,
-		MaxItems:    response.MaxItems,
-		NextMarker:  response.NextMarker,
+		TrafficPolicy: response,
 	})
 }
 
-func (api *Api) ListResourceRecordSets(w http.ResponseWriter, r *http.Request) {
-
-	api.logEndpoint(w, r, "Route53.ListResourceRecordSets")
+func (api *Api) CreateTrafficPolicyInstance(w http.ResponseWriter, r *http.Request) {
 
-	var request aws53.ListResourceRecordSetsInput
+	// POST /2013-04-01/trafficpolicyinstance
 
-	vars := mux.Vars(r)
-	request.HostedZoneId = aws.String(vars["id"])
+	api.logEndpoint(w, r, "Route53.CreateTrafficPolicyInstance")
 
-	// Parsing the query parameters
-	query := r.URL.Query()
-	request.StartRecordName = aws.String(query.Get("name"))
-	startRecordType := query.Get("type")
-	if startRecordType != "" {
-		request.StartRecordType = awstypes.RRType(startRecordType)
-	}
-	mi, merr := strconv.Atoi(query.Get("maxitems"))
-	if merr == nil {
-		request.MaxItems = aws.Int32(int32(mi))
+	var request aws53.CreateTrafficPolicyInstanceInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	response, err := api.service.ListResourceRecordSets(&request)
+	response, err := api.service.CreateTrafficPolicyInstance(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
@@ -369,43 +426,1104 @@ func (api *Api) ListResourceRecordSets(w http.ResponseWriter, r *http.Request) {
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
-		XMLName            xml.Name `xml:"ListResourceRecordSetsResponse"`
-		IsTruncated        bool
-		MaxItems           *int32
-		NextRecordName     string                  `xml:",omitempty"`
-		NextRecordType     awstypes.RRType         `xml:",omitempty"`
-		ResourceRecordSets []ResourceRecordSetData `xml:"ResourceRecordSets>ResourceRecordSet"`
+		XMLName               xml.Name `xml:"CreateTrafficPolicyInstanceResponse"`
+		TrafficPolicyInstance *TrafficPolicyInstanceData
 	}{
-		MaxItems:           request.MaxItems,
-		IsTruncated:        response.NextRecord != "",
-		NextRecordName:     response.NextRecord,
-		NextRecordType:     response.NexType,
-		ResourceRecordSets: response.Records,
+		TrafficPolicyInstance: response,
 	})
 }
 
-func (api *Api) ListTagsForResource(w http.ResponseWriter, r *http.Request) {
+func (api *Api) CreateTrafficPolicyVersion(w http.ResponseWriter, r *http.Request) {
 
-	api.logEndpoint(w, r, "Route53.ListTagsForResource")
+	// POST /2013-04-01/trafficpolicy/Id
 
-	var request aws53.ListTagsForResourceInput
+	api.logEndpoint(w, r, "Route53.CreateTrafficPolicyVersion")
+
+	var request aws53.CreateTrafficPolicyVersionInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Parsing the path parameters
 	vars := mux.Vars(r)
-	request.ResourceId = aws.String(vars["resourceId"])
-	request.ResourceType = awstypes.TagResourceType(vars["resourceType"])
+	request.Id = aws.String(vars["id"])
 
-	response, err := api.service.ListTagsForResource(&request)
+	response, err := api.service.CreateTrafficPolicyVersion(&request)
 	if err != nil {
 		httpStatus, awsErr := translateError(err)
 		awslib.WriteAwsError(w, httpStatus, awsErr)
 		return
 	}
 
-	type ResourceTagSetWrapper struct {
-		ResourceId   *string
-		ResourceType awstypes.TagResourceType
-		Tags         []awstypes.Tag `xml:"Tags>Tag"`
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName       xml.Name `xml:"CreateTrafficPolicyVersionResponse"`
+		TrafficPolicy *TrafficPolicyData
+	}{
+		TrafficPolicy: response,
+	})
+}
+
+func (api *Api) DeactivateKeySigningKey(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/keysigningkey/HostedZoneId/Name/deactivate
+
+	api.logEndpoint(w, r, "Route53.DeactivateKeySigningKey")
+
+	var request aws53.DeactivateKeySigningKeyInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+	request.Name = aws.String(vars["name"])
+
+	response, err := api.service.DeactivateKeySigningKey(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeactivateKeySigningKeyResponse"`
+		*aws53.DeactivateKeySigningKeyOutput
+	}{
+		DeactivateKeySigningKeyOutput: response,
+	})
+}
+
+func (api *Api) DeleteCidrCollection(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/cidrcollection/CidrCollectionId
+
+	api.logEndpoint(w, r, "Route53.DeleteCidrCollection")
+
+	var request aws53.DeleteCidrCollectionInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.DeleteCidrCollection(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteCidrCollectionResponse"`
+		*aws53.DeleteCidrCollectionOutput
+	}{
+		DeleteCidrCollectionOutput: response,
+	})
+}
+
+func (api *Api) DeleteHealthCheck(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/healthcheck/HealthCheckId
+
+	api.logEndpoint(w, r, "Route53.DeleteHealthCheck")
+
+	var request aws53.DeleteHealthCheckInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.HealthCheckId = aws.String(vars["id"])
+
+	response, err := api.service.DeleteHealthCheck(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteHealthCheckResponse"`
+		*aws53.DeleteHealthCheckOutput
+	}{
+		DeleteHealthCheckOutput: response,
+	})
+}
+
+func (api *Api) DeleteHostedZone(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/hostedzone/Id
+
+	api.logEndpoint(w, r, "Route53.DeleteHostedZone")
+
+	var request aws53.DeleteHostedZoneInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.DeleteHostedZone(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteHostedZoneResponse"`
+		*aws53.DeleteHostedZoneOutput
+	}{
+		DeleteHostedZoneOutput: response,
+	})
+}
+
+func (api *Api) DeleteKeySigningKey(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/keysigningkey/HostedZoneId/Name
+
+	api.logEndpoint(w, r, "Route53.DeleteKeySigningKey")
+
+	var request aws53.DeleteKeySigningKeyInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+	request.Name = aws.String(vars["name"])
+
+	response, err := api.service.DeleteKeySigningKey(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteKeySigningKeyResponse"`
+		*aws53.DeleteKeySigningKeyOutput
+	}{
+		DeleteKeySigningKeyOutput: response,
+	})
+}
+
+func (api *Api) DeleteQueryLoggingConfig(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/queryloggingconfig/Id
+
+	api.logEndpoint(w, r, "Route53.DeleteQueryLoggingConfig")
+
+	var request aws53.DeleteQueryLoggingConfigInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.DeleteQueryLoggingConfig(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteQueryLoggingConfigResponse"`
+		*aws53.DeleteQueryLoggingConfigOutput
+	}{
+		DeleteQueryLoggingConfigOutput: response,
+	})
+}
+
+func (api *Api) DeleteReusableDelegationSet(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/delegationset/Id
+
+	api.logEndpoint(w, r, "Route53.DeleteReusableDelegationSet")
+
+	var request aws53.DeleteReusableDelegationSetInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.DeleteReusableDelegationSet(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteReusableDelegationSetResponse"`
+		*aws53.DeleteReusableDelegationSetOutput
+	}{
+		DeleteReusableDelegationSetOutput: response,
+	})
+}
+
+func (api *Api) DeleteTrafficPolicy(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/trafficpolicy/Id/Version
+
+	api.logEndpoint(w, r, "Route53.DeleteTrafficPolicy")
+
+	var request aws53.DeleteTrafficPolicyInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+	version, _ := strconv.Atoi(vars["version"])
+	request.Version = aws.Int32(int32(version))
+
+	response, err := api.service.DeleteTrafficPolicy(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteTrafficPolicyResponse"`
+		*aws53.DeleteTrafficPolicyOutput
+	}{
+		DeleteTrafficPolicyOutput: response,
+	})
+}
+
+func (api *Api) DeleteTrafficPolicyInstance(w http.ResponseWriter, r *http.Request) {
+
+	// DELETE /2013-04-01/trafficpolicyinstance/Id
+
+	api.logEndpoint(w, r, "Route53.DeleteTrafficPolicyInstance")
+
+	var request aws53.DeleteTrafficPolicyInstanceInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.DeleteTrafficPolicyInstance(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DeleteTrafficPolicyInstanceResponse"`
+		*aws53.DeleteTrafficPolicyInstanceOutput
+	}{
+		DeleteTrafficPolicyInstanceOutput: response,
+	})
+}
+
+func (api *Api) DisableHostedZoneDNSSEC(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/hostedzone/Id/disable-dnssec
+
+	api.logEndpoint(w, r, "Route53.DisableHostedZoneDNSSEC")
+
+	var request aws53.DisableHostedZoneDNSSECInput
+
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+
+	response, err := api.service.DisableHostedZoneDNSSEC(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DisableHostedZoneDNSSECResponse"`
+		*aws53.DisableHostedZoneDNSSECOutput
+	}{
+		DisableHostedZoneDNSSECOutput: response,
+	})
+}
+
+func (api *Api) DisassociateVPCFromHostedZone(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/hostedzone/Id/disassociatevpc
+
+	api.logEndpoint(w, r, "Route53.DisassociateVPCFromHostedZone")
+
+	var request aws53.DisassociateVPCFromHostedZoneInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+
+	response, err := api.service.DisassociateVPCFromHostedZone(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"DisassociateVPCFromHostedZoneResponse"`
+		*aws53.DisassociateVPCFromHostedZoneOutput
+	}{
+		DisassociateVPCFromHostedZoneOutput: response,
+	})
+}
+
+func (api *Api) EnableHostedZoneDNSSEC(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/hostedzone/Id/enable-dnssec
+
+	api.logEndpoint(w, r, "Route53.EnableHostedZoneDNSSEC")
+
+	var request aws53.EnableHostedZoneDNSSECInput
+
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+
+	response, err := api.service.EnableHostedZoneDNSSEC(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"EnableHostedZoneDNSSECResponse"`
+		*aws53.EnableHostedZoneDNSSECOutput
+	}{
+		EnableHostedZoneDNSSECOutput: response,
+	})
+}
+
+func (api *Api) GetChange(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/change/Id
+
+	api.logEndpoint(w, r, "Route53.GetChange")
+
+	var request aws53.GetChangeInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.GetChange(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"GetChangeResponse"`
+		*aws53.GetChangeOutput
+	}{
+		GetChangeOutput: response,
+	})
+}
+
+func (api *Api) GetDNSSEC(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/hostedzone/Id/dnssec
+
+	api.logEndpoint(w, r, "Route53.GetDNSSEC")
+
+	var request aws53.GetDNSSECInput
+
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+
+	response, err := api.service.GetDNSSEC(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName        xml.Name                 `xml:"GetDNSSECResponse"`
+		KeySigningKeys []awstypes.KeySigningKey `xml:"KeySigningKeys>member"`
+		Status         *awstypes.DNSSECStatus
+	}{
+		KeySigningKeys: response.KeySigningKeys,
+		Status:         response.Status,
+	})
+}
+
+func (api *Api) GetHealthCheck(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/healthcheck/HealthCheckId
+
+	api.logEndpoint(w, r, "Route53.GetHealthCheck")
+
+	var request aws53.GetHealthCheckInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.HealthCheckId = aws.String(vars["id"])
+
+	response, err := api.service.GetHealthCheck(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName     xml.Name `xml:"GetHealthCheckResponse"`
+		HealthCheck *HealthCheckData
+	}{
+		HealthCheck: response,
+	})
+}
+
+func (api *Api) GetHealthCheckStatus(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/healthcheck/HealthCheckId/status
+
+	api.logEndpoint(w, r, "Route53.GetHealthCheckStatus")
+
+	var request aws53.GetHealthCheckStatusInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.HealthCheckId = aws.String(vars["id"])
+
+	response, err := api.service.GetHealthCheckStatus(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName                 xml.Name                          `xml:"GetHealthCheckStatusResponse"`
+		HealthCheckObservations []awstypes.HealthCheckObservation `xml:"HealthCheckObservations>HealthCheckObservation"`
+	}{
+		HealthCheckObservations: response.HealthCheckObservations,
+	})
+}
+
+func (api *Api) GetHostedZone(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/hostedzone/Id
+
+	api.logEndpoint(w, r, "Route53.GetHostedZone")
+
+	var request aws53.GetHostedZoneInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.GetHostedZone(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	type DelegationSetWrapper struct {
+		NameServers     []string `xml:"NameServers>NameServer"`
+		CallerReference *string  `xml:",omitempty"`
+		Id              *string  `xml:",omitempty"`
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName       xml.Name `xml:"GetHostedZoneResponse"`
+		HostedZone    *awstypes.HostedZone
+		DelegationSet DelegationSetWrapper
+		VPCs          []awstypes.VPC `xml:"VPCs>VPC,omitempty"`
+	}{
+		HostedZone: response.HostedZone,
+		VPCs:       response.VPCs,
+		DelegationSet: DelegationSetWrapper{
+			NameServers:     response.DelegationSet.NameServers,
+			CallerReference: response.DelegationSet.CallerReference,
+			Id:              response.DelegationSet.Id,
+		},
+	})
+}
+
+func (api *Api) GetHostedZoneCount(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/hostedzonecount
+
+	api.logEndpoint(w, r, "Route53.GetHostedZoneCount")
+
+	response, err := api.service.GetHostedZoneCount()
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"GetHostedZoneCountResponse"`
+		*aws53.GetHostedZoneCountOutput
+	}{
+		GetHostedZoneCountOutput: response,
+	})
+}
+
+func (api *Api) GetQueryLoggingConfig(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/queryloggingconfig/Id
+
+	api.logEndpoint(w, r, "Route53.GetQueryLoggingConfig")
+
+	var request aws53.GetQueryLoggingConfigInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.GetQueryLoggingConfig(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName            xml.Name `xml:"GetQueryLoggingConfigResponse"`
+		QueryLoggingConfig *QueryLoggingConfigData
+	}{
+		QueryLoggingConfig: response,
+	})
+}
+
+func (api *Api) GetReusableDelegationSet(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/delegationset/Id
+
+	api.logEndpoint(w, r, "Route53.GetReusableDelegationSet")
+
+	var request aws53.GetReusableDelegationSetInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.GetReusableDelegationSet(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName       xml.Name `xml:"GetReusableDelegationSetResponse"`
+		DelegationSet *DelegationSetData
+	}{
+		DelegationSet: response,
+	})
+}
+
+func (api *Api) GetTrafficPolicy(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicy/Id/Version
+
+	api.logEndpoint(w, r, "Route53.GetTrafficPolicy")
+
+	var request aws53.GetTrafficPolicyInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+	version, _ := strconv.Atoi(vars["version"])
+	request.Version = aws.Int32(int32(version))
+
+	response, err := api.service.GetTrafficPolicy(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName       xml.Name `xml:"GetTrafficPolicyResponse"`
+		TrafficPolicy *TrafficPolicyData
+	}{
+		TrafficPolicy: response,
+	})
+}
+
+func (api *Api) GetTrafficPolicyInstance(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicyinstance/Id
+
+	api.logEndpoint(w, r, "Route53.GetTrafficPolicyInstance")
+
+	var request aws53.GetTrafficPolicyInstanceInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.GetTrafficPolicyInstance(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName               xml.Name `xml:"GetTrafficPolicyInstanceResponse"`
+		TrafficPolicyInstance *TrafficPolicyInstanceData
+	}{
+		TrafficPolicyInstance: response,
+	})
+}
+
+func (api *Api) GetTrafficPolicyInstanceCount(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicyinstancecount
+
+	api.logEndpoint(w, r, "Route53.GetTrafficPolicyInstanceCount")
+
+	response, err := api.service.GetTrafficPolicyInstanceCount()
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName xml.Name `xml:"GetTrafficPolicyInstanceCountResponse"`
+		*aws53.GetTrafficPolicyInstanceCountOutput
+	}{
+		GetTrafficPolicyInstanceCountOutput: response,
+	})
+}
+
+func (api *Api) ListCidrBlocks(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/cidrcollection/CidrCollectionId/cidrblocks?location=LocationName&nexttoken=NextToken&maxresults=MaxResults
+
+	api.logEndpoint(w, r, "Route53.ListCidrBlocks")
+
+	var request aws53.ListCidrBlocksInput
+
+	// Parsing the path and query parameters
+	vars := mux.Vars(r)
+	request.CollectionId = aws.String(vars["id"])
+
+	query := r.URL.Query()
+	request.LocationName = aws.String(query.Get("location"))
+	request.NextToken = aws.String(query.Get("nexttoken"))
+
+	mr, merr := strconv.Atoi(query.Get("maxresults"))
+	if merr == nil {
+		request.MaxResults = aws.Int32(int32(mr))
+	}
+
+	response, err := api.service.ListCidrBlocks(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName    xml.Name               `xml:"ListCidrBlocksResponse"`
+		CidrBlocks []CidrBlockSummaryData `xml:"CidrBlocks>member"`
+		NextToken  *string                `xml:",omitempty"`
+	}{
+		CidrBlocks: response.CidrBlocks,
+		NextToken:  response.NextToken,
+	})
+}
+
+func (api *Api) ListCidrCollections(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/cidrcollection?nexttoken=NextToken&maxresults=MaxResults
+
+	api.logEndpoint(w, r, "Route53.ListCidrCollections")
+
+	var request aws53.ListCidrCollectionsInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.NextToken = aws.String(query.Get("nexttoken"))
+
+	mr, merr := strconv.Atoi(query.Get("maxresults"))
+	if merr == nil {
+		request.MaxResults = aws.Int32(int32(mr))
+	}
+
+	response, err := api.service.ListCidrCollections(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName         xml.Name             `xml:"ListCidrCollectionsResponse"`
+		CidrCollections []CidrCollectionData `xml:"CidrCollections>member"`
+		NextToken       *string              `xml:",omitempty"`
+	}{
+		CidrCollections: response.CidrCollections,
+		NextToken:       response.NextToken,
+	})
+}
+
+func (api *Api) ListCidrLocations(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/cidrcollection/CidrCollectionId?nexttoken=NextToken&maxresults=MaxResults
+
+	api.logEndpoint(w, r, "Route53.ListCidrLocations")
+
+	var request aws53.ListCidrLocationsInput
+
+	// Parsing the path and query parameters
+	vars := mux.Vars(r)
+	request.CollectionId = aws.String(vars["id"])
+
+	query := r.URL.Query()
+	request.NextToken = aws.String(query.Get("nexttoken"))
+
+	mr, merr := strconv.Atoi(query.Get("maxresults"))
+	if merr == nil {
+		request.MaxResults = aws.Int32(int32(mr))
+	}
+
+	response, err := api.service.ListCidrLocations(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	type locationSummary struct {
+		LocationName string
+	}
+
+	locations := make([]locationSummary, 0, len(response.LocationNames))
+	for _, name := range response.LocationNames {
+		locations = append(locations, locationSummary{LocationName: name})
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName       xml.Name          `xml:"ListCidrLocationsResponse"`
+		CidrLocations []locationSummary `xml:"CidrLocations>member"`
+		NextToken     *string           `xml:",omitempty"`
+	}{
+		CidrLocations: locations,
+		NextToken:     response.NextToken,
+	})
+}
+
+func (api *Api) ListHostedZonesByName(w http.ResponseWriter, r *http.Request) {
+
+	api.logEndpoint(w, r, "Route53.ListHostedZonesByName")
+
+	var request aws53.ListHostedZonesByNameInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.HostedZoneId = aws.String(query.Get("hostedzoneid"))
+	request.DNSName = aws.String(query.Get("dnsname"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListHostedZonesByName(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName          xml.Name              `xml:"ListHostedZonesByNameResponse"`
+		HostedZones      []awstypes.HostedZone `xml:"HostedZones>HostedZone"`
+		IsTruncated      bool
+		MaxItems         *int32
+		DNSName          *string
+		HostedZoneId     *string
+		NextDNSName      *string `xml:",omitempty"`
+		NextHostedZoneId *string `xml:",omitempty"`
+	}{
+		HostedZones:      response.HostedZones,
+		IsTruncated:      response.IsTruncated,
+		DNSName:          response.DNSName,
+		HostedZoneId:     response.HostedZoneId,
+		MaxItems:         response.MaxItems,
+		NextDNSName:      response.NextDNSName,
+		NextHostedZoneId: response.NextHostedZoneId,
+	})
+}
+
+func (api *Api) ListHostedZonesByVPC(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/hostedzonesbyvpc?vpcid=VPCId&vpcregion=VPCRegion&maxitems=MaxItems&nexttoken=NextToken
+
+	api.logEndpoint(w, r, "Route53.ListHostedZonesByVPC")
+
+	var request aws53.ListHostedZonesByVPCInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.VPCId = aws.String(query.Get("vpcid"))
+	request.VPCRegion = awstypes.VPCRegion(query.Get("vpcregion"))
+	request.NextToken = core.StringOrNil(query.Get("nexttoken"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListHostedZonesByVPC(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName             xml.Name                     `xml:"ListHostedZonesByVPCResponse"`
+		HostedZoneSummaries []awstypes.HostedZoneSummary `xml:"HostedZoneSummaries>HostedZoneSummary"`
+		MaxItems            *int32
+		NextToken           *string `xml:",omitempty"`
+	}{
+		HostedZoneSummaries: response.HostedZoneSummaries,
+		MaxItems:            response.MaxItems,
+		NextToken:           response.NextToken,
+	})
+}
+
+func (api *Api) ListQueryLoggingConfigs(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/queryloggingconfig?hostedzoneid=HostedZoneId&nexttoken=NextToken&maxresults=MaxResults
+
+	api.logEndpoint(w, r, "Route53.ListQueryLoggingConfigs")
+
+	var request aws53.ListQueryLoggingConfigsInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.HostedZoneId = aws.String(query.Get("hostedzoneid"))
+	request.NextToken = aws.String(query.Get("nexttoken"))
+
+	mr, merr := strconv.Atoi(query.Get("maxresults"))
+	if merr == nil {
+		request.MaxResults = aws.Int32(int32(mr))
+	}
+
+	response, err := api.service.ListQueryLoggingConfigs(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName             xml.Name                 `xml:"ListQueryLoggingConfigsResponse"`
+		QueryLoggingConfigs []QueryLoggingConfigData `xml:"QueryLoggingConfigs>QueryLoggingConfig"`
+		NextToken           *string                  `xml:",omitempty"`
+	}{
+		QueryLoggingConfigs: response.QueryLoggingConfigs,
+		NextToken:           response.NextToken,
+	})
+}
+
+func (api *Api) ListHealthChecks(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/healthcheck?marker=Marker&maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListHealthChecks")
+
+	var request aws53.ListHealthChecksInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.Marker = aws.String(query.Get("marker"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListHealthChecks(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName      xml.Name          `xml:"ListHealthChecksResponse"`
+		HealthChecks []HealthCheckData `xml:"HealthChecks>HealthCheck"`
+		IsTruncated  bool
+		MaxItems     *int32
+		Marker       *string `xml:",omitempty"`
+		NextMarker   *string `xml:",omitempty"`
+	}{
+		HealthChecks: response.HealthChecks,
+		IsTruncated:  response.NextMarker != nil,
+		Marker:       response.Marker,
+		MaxItems:     response.MaxItems,
+		NextMarker:   response.NextMarker,
+	})
+}
+
+func (api *Api) ListHostedZones(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/hostedzone?delegationsetid=DelegationSetId&hostedzonetype=HostedZoneType&marker=Marker&maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListHostedZones")
+
+	var request aws53.ListHostedZonesInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.DelegationSetId = aws.String(query.Get("delegationsetid"))
+	request.HostedZoneType = awstypes.HostedZoneType(query.Get("hostedzonetype"))
+	request.Marker = aws.String(query.Get("marker"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListHostedZones(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName     xml.Name              `xml:"ListHostedZoneResponse"`
+		HostedZones []awstypes.HostedZone `xml:"HostedZones>HostedZone"`
+		IsTruncated bool
+		MaxItems    *int32
+		Marker      *string `xml:",omitempty"`
+		NextMarker  *string `xml:",omitempty"`
+	}{
+		HostedZones: response.HostedZones,
+		IsTruncated: response.IsTruncated,
+		Marker:      response.Marker,
+		MaxItems:    response.MaxItems,
+		NextMarker:  response.NextMarker,
+	})
+}
+
+func (api *Api) ListResourceRecordSets(w http.ResponseWriter, r *http.Request) {
+
+	api.logEndpoint(w, r, "Route53.ListResourceRecordSets")
+
+	var request aws53.ListResourceRecordSetsInput
+
+	vars := mux.Vars(r)
+	request.HostedZoneId = aws.String(vars["id"])
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.StartRecordName = aws.String(query.Get("name"))
+	startRecordType := query.Get("type")
+	if startRecordType != "" {
+		request.StartRecordType = awstypes.RRType(startRecordType)
+	}
+	if identifier := query.Get("identifier"); identifier != "" {
+		request.StartRecordIdentifier = aws.String(identifier)
+	}
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListResourceRecordSets(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName              xml.Name `xml:"ListResourceRecordSetsResponse"`
+		IsTruncated          bool
+		MaxItems             *int32
+		NextRecordName       string                  `xml:",omitempty"`
+		NextRecordType       awstypes.RRType         `xml:",omitempty"`
+		NextRecordIdentifier string                  `xml:",omitempty"`
+		ResourceRecordSets   []ResourceRecordSetData `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}{
+		MaxItems:             request.MaxItems,
+		IsTruncated:          response.NextRecord != "",
+		NextRecordName:       response.NextRecord,
+		NextRecordType:       response.NexType,
+		NextRecordIdentifier: response.NextIdentifier,
+		ResourceRecordSets:   response.Records,
+	})
+}
+
+func (api *Api) ListReusableDelegationSets(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/delegationset?marker=Marker&maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListReusableDelegationSets")
+
+	var request aws53.ListReusableDelegationSetsInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.Marker = aws.String(query.Get("marker"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListReusableDelegationSets(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName        xml.Name            `xml:"ListReusableDelegationSetsResponse"`
+		DelegationSets []DelegationSetData `xml:"DelegationSets>DelegationSet"`
+		IsTruncated    bool
+		MaxItems       *int32
+		Marker         *string `xml:",omitempty"`
+		NextMarker     *string `xml:",omitempty"`
+	}{
+		DelegationSets: response.DelegationSets,
+		IsTruncated:    response.NextMarker != nil,
+		Marker:         response.Marker,
+		MaxItems:       response.MaxItems,
+		NextMarker:     response.NextMarker,
+	})
+}
+
+func (api *Api) ListTagsForResource(w http.ResponseWriter, r *http.Request) {
+
+	api.logEndpoint(w, r, "Route53.ListTagsForResource")
+
+	var request aws53.ListTagsForResourceInput
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.ResourceId = aws.String(vars["resourceId"])
+	request.ResourceType = awstypes.TagResourceType(vars["resourceType"])
+
+	response, err := api.service.ListTagsForResource(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	type ResourceTagSetWrapper struct {
+		ResourceId   *string
+		ResourceType awstypes.TagResourceType
+		Tags         []awstypes.Tag `xml:"Tags>Tag"`
 	}
 
 	awslib.WriteSuccessResponseXML(w, struct {
@@ -420,6 +1538,256 @@ func (api *Api) ListTagsForResource(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (api *Api) ListTrafficPolicies(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicies?trafficpolicyid=TrafficPolicyIdMarker&maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListTrafficPolicies")
+
+	var request aws53.ListTrafficPoliciesInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.TrafficPolicyIdMarker = aws.String(query.Get("trafficpolicyid"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListTrafficPolicies(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName                xml.Name                   `xml:"ListTrafficPoliciesResponse"`
+		TrafficPolicySummaries []TrafficPolicySummaryData `xml:"TrafficPolicySummaries>TrafficPolicySummary"`
+		IsTruncated            bool
+		MaxItems               *int32
+		TrafficPolicyIdMarker  *string `xml:",omitempty"`
+	}{
+		TrafficPolicySummaries: response.TrafficPolicySummaries,
+		IsTruncated:            response.NextMarker != nil,
+		MaxItems:               response.MaxItems,
+		TrafficPolicyIdMarker:  response.NextMarker,
+	})
+}
+
+func (api *Api) ListTrafficPolicyInstances(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicyinstances?hostedzoneid=HostedZoneIdMarker
+	//   &trafficpolicyinstancename=TrafficPolicyInstanceNameMarker&trafficpolicyinstancetype=TrafficPolicyInstanceTypeMarker
+	//   &maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListTrafficPolicyInstances")
+
+	var request aws53.ListTrafficPolicyInstancesInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.HostedZoneIdMarker = aws.String(query.Get("hostedzoneid"))
+	request.TrafficPolicyInstanceNameMarker = aws.String(query.Get("trafficpolicyinstancename"))
+	request.TrafficPolicyInstanceTypeMarker = awstypes.RRType(query.Get("trafficpolicyinstancetype"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListTrafficPolicyInstances(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	writeTrafficPolicyInstances(w, "ListTrafficPolicyInstancesResponse", response, true)
+}
+
+func (api *Api) ListTrafficPolicyInstancesByHostedZone(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicyinstances/hostedzone?id=HostedZoneId
+	//   &trafficpolicyinstancename=TrafficPolicyInstanceNameMarker&trafficpolicyinstancetype=TrafficPolicyInstanceTypeMarker
+	//   &maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListTrafficPolicyInstancesByHostedZone")
+
+	var request aws53.ListTrafficPolicyInstancesByHostedZoneInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.HostedZoneId = aws.String(query.Get("id"))
+	request.TrafficPolicyInstanceNameMarker = aws.String(query.Get("trafficpolicyinstancename"))
+	request.TrafficPolicyInstanceTypeMarker = awstypes.RRType(query.Get("trafficpolicyinstancetype"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListTrafficPolicyInstancesByHostedZone(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	writeTrafficPolicyInstances(w, "ListTrafficPolicyInstancesByHostedZoneResponse", response, false)
+}
+
+func (api *Api) ListTrafficPolicyInstancesByPolicy(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicyinstances/trafficpolicy?id=TrafficPolicyId&version=TrafficPolicyVersion
+	//   &hostedzoneid=HostedZoneIdMarker&trafficpolicyinstancename=TrafficPolicyInstanceNameMarker
+	//   &trafficpolicyinstancetype=TrafficPolicyInstanceTypeMarker&maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListTrafficPolicyInstancesByPolicy")
+
+	var request aws53.ListTrafficPolicyInstancesByPolicyInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.TrafficPolicyId = aws.String(query.Get("id"))
+	version, _ := strconv.Atoi(query.Get("version"))
+	request.TrafficPolicyVersion = aws.Int32(int32(version))
+	request.HostedZoneIdMarker = aws.String(query.Get("hostedzoneid"))
+	request.TrafficPolicyInstanceNameMarker = aws.String(query.Get("trafficpolicyinstancename"))
+	request.TrafficPolicyInstanceTypeMarker = awstypes.RRType(query.Get("trafficpolicyinstancetype"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListTrafficPolicyInstancesByPolicy(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	writeTrafficPolicyInstances(w, "ListTrafficPolicyInstancesByPolicyResponse", response, true)
+}
+
+func (api *Api) ListTrafficPolicyVersions(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/trafficpolicies/Id/versions?trafficpolicyversion=TrafficPolicyVersionMarker&maxitems=MaxItems
+
+	api.logEndpoint(w, r, "Route53.ListTrafficPolicyVersions")
+
+	var request aws53.ListTrafficPolicyVersionsInput
+
+	// Parsing the path and query parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	query := r.URL.Query()
+	request.TrafficPolicyVersionMarker = aws.String(query.Get("trafficpolicyversion"))
+
+	mi, merr := strconv.Atoi(query.Get("maxitems"))
+	if merr == nil {
+		request.MaxItems = aws.Int32(int32(mi))
+	}
+
+	response, err := api.service.ListTrafficPolicyVersions(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName                    xml.Name            `xml:"ListTrafficPolicyVersionsResponse"`
+		TrafficPolicies            []TrafficPolicyData `xml:"TrafficPolicies>TrafficPolicy"`
+		IsTruncated                bool
+		MaxItems                   *int32
+		TrafficPolicyVersionMarker *string `xml:",omitempty"`
+	}{
+		TrafficPolicies:            response.TrafficPolicies,
+		IsTruncated:                response.NextMarker != nil,
+		MaxItems:                   response.MaxItems,
+		TrafficPolicyVersionMarker: response.NextMarker,
+	})
+}
+
+func (api *Api) TestDNSAnswer(w http.ResponseWriter, r *http.Request) {
+
+	// GET /2013-04-01/testdnsanswer?hostedzoneid=HostedZoneId&recordname=RecordName&recordtype=RecordType
+
+	api.logEndpoint(w, r, "Route53.TestDNSAnswer")
+
+	var request aws53.TestDNSAnswerInput
+
+	// Parsing the query parameters
+	query := r.URL.Query()
+	request.HostedZoneId = aws.String(query.Get("hostedzoneid"))
+	request.RecordName = aws.String(query.Get("recordname"))
+	request.RecordType = awstypes.RRType(query.Get("recordtype"))
+	request.ResolverIP = aws.String(query.Get("resolverip"))
+	request.EDNS0ClientSubnetIP = aws.String(query.Get("edns0clientsubnetip"))
+	request.EDNS0ClientSubnetMask = aws.String(query.Get("edns0clientsubnetmask"))
+
+	response, err := api.service.TestDNSAnswer(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName      xml.Name `xml:"TestDNSAnswerResponse"`
+		Nameserver   *string
+		RecordName   *string
+		RecordType   awstypes.RRType
+		RecordData   []string `xml:"RecordData>RecordDataEntry"`
+		ResponseCode *string
+		Protocol     *string
+	}{
+		Nameserver:   response.Nameserver,
+		RecordName:   response.RecordName,
+		RecordType:   response.RecordType,
+		RecordData:   response.RecordData,
+		ResponseCode: response.ResponseCode,
+		Protocol:     response.Protocol,
+	})
+}
+
+func (api *Api) UpdateHealthCheck(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/healthcheck/HealthCheckId
+
+	api.logEndpoint(w, r, "Route53.UpdateHealthCheck")
+
+	var request UpdateHealthCheckRequest
+
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.HealthCheckId = vars["id"]
+
+	response, err := api.service.UpdateHealthCheck(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName     xml.Name `xml:"UpdateHealthCheckResponse"`
+		HealthCheck *HealthCheckData
+	}{
+		HealthCheck: response,
+	})
+}
+
 func (api *Api) UpdateHostedZoneComment(w http.ResponseWriter, r *http.Request) {
 
 	api.logEndpoint(w, r, "Route53.UpdateHostedZoneComment")
@@ -456,6 +1824,121 @@ func (api *Api) UpdateHostedZoneComment(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// writeInvalidChangeBatch writes the InvalidChangeBatch document Route53 returns in place of
+// an error response, SDKs read the individual messages from it.
+func (api *Api) UpdateTrafficPolicyComment(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/trafficpolicy/Id/Version
+
+	api.logEndpoint(w, r, "Route53.UpdateTrafficPolicyComment")
+
+	var request aws53.UpdateTrafficPolicyCommentInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+	version, _ := strconv.Atoi(vars["version"])
+	request.Version = aws.Int32(int32(version))
+
+	response, err := api.service.UpdateTrafficPolicyComment(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName       xml.Name `xml:"UpdateTrafficPolicyCommentResponse"`
+		TrafficPolicy *TrafficPolicyData
+	}{
+		TrafficPolicy: response,
+	})
+}
+
+func (api *Api) UpdateTrafficPolicyInstance(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2013-04-01/trafficpolicyinstance/Id
+
+	api.logEndpoint(w, r, "Route53.UpdateTrafficPolicyInstance")
+
+	var request aws53.UpdateTrafficPolicyInstanceInput
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println("Error:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parsing the path parameters
+	vars := mux.Vars(r)
+	request.Id = aws.String(vars["id"])
+
+	response, err := api.service.UpdateTrafficPolicyInstance(&request)
+	if err != nil {
+		httpStatus, awsErr := translateError(err)
+		awslib.WriteAwsError(w, httpStatus, awsErr)
+		return
+	}
+
+	awslib.WriteSuccessResponseXML(w, struct {
+		XMLName               xml.Name `xml:"UpdateTrafficPolicyInstanceResponse"`
+		TrafficPolicyInstance *TrafficPolicyInstanceData
+	}{
+		TrafficPolicyInstance: response,
+	})
+}
+
+func writeInvalidChangeBatch(w http.ResponseWriter, batchErr *InvalidChangeBatchError) {
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusBadRequest)
+
+	err := xml.NewEncoder(w).Encode(struct {
+		XMLName  xml.Name `xml:"https://route53.amazonaws.com/doc/2013-04-01/ InvalidChangeBatch"`
+		Messages []string `xml:"Messages>Message"`
+	}{
+		Messages: batchErr.Messages,
+	})
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
+
+// writeTrafficPolicyInstances writes a page of traffic policy instances, the markers are
+// those of the first instance of the next page.
+func writeTrafficPolicyInstances(
+	w http.ResponseWriter, name string, response *ListTrafficPolicyInstancesOutput, zoneMarker bool) {
+
+	result := struct {
+		XMLName                         xml.Name
+		TrafficPolicyInstances          []TrafficPolicyInstanceData `xml:"TrafficPolicyInstances>TrafficPolicyInstance"`
+		IsTruncated                     bool
+		MaxItems                        *int32
+		HostedZoneIdMarker              *string         `xml:",omitempty"`
+		TrafficPolicyInstanceNameMarker *string         `xml:",omitempty"`
+		TrafficPolicyInstanceTypeMarker awstypes.RRType `xml:",omitempty"`
+	}{
+		XMLName:                xml.Name{Local: name},
+		TrafficPolicyInstances: response.TrafficPolicyInstances,
+		IsTruncated:            response.Next != nil,
+		MaxItems:               response.MaxItems,
+	}
+
+	if next := response.Next; next != nil {
+		if zoneMarker {
+			result.HostedZoneIdMarker = aws.String(next.HostedZoneId)
+		}
+		result.TrafficPolicyInstanceNameMarker = aws.String(next.Name)
+		result.TrafficPolicyInstanceTypeMarker = next.TrafficPolicyType
+	}
+
+	awslib.WriteSuccessResponseXML(w, result)
+}
+
 func translateError(err error) (int, awslib.AwsErrorResponse) {
 	if errors.Is(err, ErrHostedZoneAlreadyExists) {
 		return http.StatusConflict, awslib.AwsErrorResponse{Code: "HostedZoneAlreadyExists", Message: "The hosted zone already exists."}
@@ -466,6 +1949,121 @@ func translateError(err error) (int, awslib.AwsErrorResponse) {
 	if errors.Is(err, ErrHostedZoneNotEmpty) {
 		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "HostedZoneNotEmpty", Message: "The hosted zone contains resource records that are not SOA or NS records."}
 	}
+	if errors.Is(err, ErrNoSuchHealthCheck) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchHealthCheck", Message: "No health check exists with the specified ID."}
+	}
+	if errors.Is(err, ErrHealthCheckAlreadyExists) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "HealthCheckAlreadyExists", Message: "The health check you're attempting to create already exists."}
+	}
+	if errors.Is(err, ErrNoSuchDelegationSet) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "NoSuchDelegationSet", Message: "A reusable delegation set with the specified ID does not exist."}
+	}
+	if errors.Is(err, ErrDelegationSetAlreadyExists) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "DelegationSetAlreadyCreated", Message: "A delegation set with the same owner and caller reference combination has already been created."}
+	}
+	if errors.Is(err, ErrDelegationSetInUse) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "DelegationSetInUse", Message: "The specified delegation contains associated hosted zones which must be deleted before the reusable delegation set can be deleted."}
+	}
+	if errors.Is(err, ErrDelegationSetReusable) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "DelegationSetAlreadyReusable", Message: "The specified delegation set has already been marked as reusable."}
+	}
+	if errors.Is(err, ErrInvalidVPCId) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidVPCId", Message: "The VPC ID that you specified either isn't a valid ID or the current account is not authorized to access this VPC."}
+	}
+	if errors.Is(err, ErrPublicZoneVPCAssociation) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "PublicZoneVPCAssociation", Message: "You're trying to associate a VPC with a public hosted zone."}
+	}
+	if errors.Is(err, ErrVPCAssociationNotFound) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "VPCAssociationNotFound", Message: "The specified VPC and hosted zone are not currently associated."}
+	}
+	if errors.Is(err, ErrLastVPCAssociation) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "LastVPCAssociation", Message: "The VPC that you're trying to disassociate from the private hosted zone is the last VPC that is associated with the hosted zone."}
+	}
+	if errors.Is(err, ErrConflictingDomainExists) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "ConflictingDomainExists", Message: "A hosted zone with the same name is already associated with the specified VPC."}
+	}
+	if errors.Is(err, ErrNoSuchQueryLoggingConfig) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchQueryLoggingConfig", Message: "There is no DNS query logging configuration with the specified ID."}
+	}
+	if errors.Is(err, ErrQueryLoggingConfigExists) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "QueryLoggingConfigAlreadyExists", Message: "You can create only one query logging configuration for a hosted zone, and a query logging configuration already exists for this hosted zone."}
+	}
+	if errors.Is(err, ErrNoSuchLogGroup) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchCloudWatchLogsLogGroup", Message: "There is no CloudWatch Logs log group with the specified ARN."}
+	}
+	if errors.Is(err, ErrNoSuchKeySigningKey) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchKeySigningKey", Message: "The specified key-signing key (KSK) doesn't exist."}
+	}
+	if errors.Is(err, ErrKeySigningKeyAlreadyExists) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "KeySigningKeyAlreadyExists", Message: "You've already created a key-signing key (KSK) with this name or with the same customer managed key ARN."}
+	}
+	if errors.Is(err, ErrInvalidKeySigningKeyName) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidKeySigningKeyName", Message: "The key-signing key (KSK) name that you specified isn't a valid name."}
+	}
+	if errors.Is(err, ErrInvalidKeySigningKeyStatus) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidKeySigningKeyStatus", Message: "The key-signing key (KSK) status isn't valid or another KSK has the status INTERNAL_FAILURE."}
+	}
+	if errors.Is(err, ErrKeySigningKeyInUse) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "KeySigningKeyInUse", Message: "The key-signing key (KSK) that you specified can't be deactivated because it's the only KSK for a currently-enabled DNSSEC."}
+	}
+	if errors.Is(err, ErrNoActiveKeySigningKey) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "KeySigningKeyWithActiveStatusNotFound", Message: "A key-signing key (KSK) with ACTIVE status wasn't found."}
+	}
+	if errors.Is(err, ErrTooManyKeySigningKeys) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "TooManyKeySigningKeys", Message: "You've reached the limit for the number of key-signing keys (KSKs)."}
+	}
+	if errors.Is(err, ErrInvalidKMSArn) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidKMSArn", Message: "The KeyManagementServiceArn that you specified isn't valid to use with DNSSEC signing."}
+	}
+	if errors.Is(err, ErrDNSSECNotSupported) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidArgument", Message: "DNSSEC signing isn't supported for private hosted zones."}
+	}
+	if errors.Is(err, ErrNoSuchTrafficPolicy) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchTrafficPolicy", Message: "No traffic policy exists with the specified ID."}
+	}
+	if errors.Is(err, ErrTrafficPolicyAlreadyExists) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "TrafficPolicyAlreadyExists", Message: "A traffic policy that has the same value for Name already exists."}
+	}
+	if errors.Is(err, ErrTrafficPolicyInUse) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "TrafficPolicyInUse", Message: "One or more traffic policy instances were created by using the specified traffic policy."}
+	}
+	if errors.Is(err, ErrNoSuchTrafficPolicyInstance) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchTrafficPolicyInstance", Message: "No traffic policy instance exists with the specified ID."}
+	}
+	if errors.Is(err, ErrTrafficPolicyInstanceExists) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "TrafficPolicyInstanceAlreadyExists", Message: "There is already a traffic policy instance with the specified name and type."}
+	}
+	if errors.Is(err, ErrConflictingTypes) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "ConflictingTypes", Message: "You tried to update a traffic policy instance by using a traffic policy version that has a different DNS type than the current type for the instance."}
+	}
+	if errors.Is(err, ErrInvalidTrafficPolicyDocument) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidTrafficPolicyDocument", Message: err.Error()}
+	}
+	if errors.Is(err, ErrNoSuchCidrCollection) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchCidrCollectionException", Message: "The CIDR collection you specified, doesn't exist."}
+	}
+	if errors.Is(err, ErrCidrCollectionAlreadyExists) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "CidrCollectionAlreadyExistsException", Message: "A CIDR collection with this name and a different caller reference already exists in this account."}
+	}
+	if errors.Is(err, ErrCidrCollectionVersionMismatch) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "CidrCollectionVersionMismatchException", Message: "The CIDR collection version you provided, doesn't match the one in the ListCidrCollections operation."}
+	}
+	if errors.Is(err, ErrCidrCollectionInUse) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "CidrCollectionInUseException", Message: "This CIDR collection is in use, and isn't empty."}
+	}
+	if errors.Is(err, ErrNoSuchCidrLocation) {
+		return http.StatusNotFound, awslib.AwsErrorResponse{Code: "NoSuchCidrLocationException", Message: "The CIDR collection location doesn't match any locations in your account."}
+	}
+	if errors.Is(err, ErrCidrBlockInUse) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "CidrBlockInUseException", Message: "This CIDR block is already in use."}
+	}
+	if errors.Is(err, ErrHealthCheckVersionMismatch) {
+		return http.StatusConflict, awslib.AwsErrorResponse{Code: "HealthCheckVersionMismatch", Message: "The value of HealthCheckVersion in the request doesn't match the value of HealthCheckVersion in the health check."}
+	}
+	var batchErr *InvalidChangeBatchError
+	if errors.As(err, &batchErr) {
+		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidChangeBatch", Message: batchErr.Error()}
+	}
 	if errors.Is(err, ErrInvalidChangeBatch) {
 		return http.StatusBadRequest, awslib.AwsErrorResponse{Code: "InvalidChangeBatch", Message: "The change batch is invalid."}
 	}
@@ -0,0 +1,183 @@
+package route53
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"regexp"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// cidrDefaultLocation is the location of the record set answering clients which aren't in
+// any other location of the collection.
+const cidrDefaultLocation = "*"
+
+var (
+	cidrCollectionName = regexp.MustCompile(`^[0-9A-Za-z_\-]{1,64}$`)
+	cidrLocationName   = regexp.MustCompile(`^[0-9A-Za-z_\-]{1,16}$`)
+)
+
+// applyCidrChanges returns the collection's locations after the changes, and the locations
+// the changes removed. A CIDR block belongs to at most one location of a collection.
+func applyCidrChanges(
+	coll *CidrCollectionData, changes []CidrCollectionChangeData) (map[string][]string, []string, error) {
+
+	if len(changes) == 0 || len(changes) > 1000 {
+		return nil, nil, ErrInvalidInput
+	}
+
+	locations := make(map[string][]string, len(coll.Locations))
+	for name, blocks := range coll.Locations {
+		locations[name] = slices.Clone(blocks)
+	}
+
+	for _, change := range changes {
+
+		if !cidrLocationName.MatchString(change.LocationName) || len(change.CidrList) == 0 {
+			return nil, nil, ErrInvalidInput
+		}
+
+		for _, cidr := range change.CidrList {
+
+			block, err := parseCidrBlock(cidr)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			blocks := locations[change.LocationName]
+			switch change.Action {
+			case awstypes.CidrCollectionChangeActionPut:
+				for name, other := range locations {
+					if name != change.LocationName && slices.Contains(other, block) {
+						return nil, nil, fmt.Errorf("%w: %s is already in location %s", ErrInvalidInput, block, name)
+					}
+				}
+				if !slices.Contains(blocks, block) {
+					locations[change.LocationName] = append(blocks, block)
+				}
+
+			case awstypes.CidrCollectionChangeActionDeleteIfExists:
+				blocks = slices.DeleteFunc(blocks, func(b string) bool { return b == block })
+				if len(blocks) == 0 {
+					delete(locations, change.LocationName)
+				} else {
+					locations[change.LocationName] = blocks
+				}
+
+			default:
+				return nil, nil, ErrInvalidInput
+			}
+		}
+	}
+
+	var removed []string
+	for name := range coll.Locations {
+		if _, found := locations[name]; !found {
+			removed = append(removed, name)
+		}
+	}
+
+	return locations, removed, nil
+}
+
+// parseCidrBlock returns a block in its canonical form. Like Route53, IPv4 blocks can be at
+// most /24 and IPv6 blocks at most /48.
+func parseCidrBlock(cidr string) (string, error) {
+
+	ip, block, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s is not a CIDR block", ErrInvalidInput, cidr)
+	}
+
+	ones, _ := block.Mask.Size()
+	if (ip.To4() != nil && ones > 24) || (ip.To4() == nil && ones > 48) {
+		return "", fmt.Errorf("%w: the prefix of %s is too long", ErrInvalidInput, cidr)
+	}
+
+	return block.String(), nil
+}
+
+// cidrLocation returns the location with the longest CIDR block containing ip, or "".
+func cidrLocation(coll *CidrCollectionData, ip net.IP) string {
+
+	result := ""
+	longest := -1
+	for _, name := range slices.Sorted(maps.Keys(coll.Locations)) {
+		for _, cidr := range coll.Locations[name] {
+			if _, block, err := net.ParseCIDR(cidr); err == nil && block.Contains(ip) {
+				if ones, _ := block.Mask.Size(); ones > longest {
+					result, longest = name, ones
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// routeCidr answers with the record set of the client's location in the collection, or the
+// default location's record set. The client is the EDNS client subnet when the query has one.
+func (s *Service) routeCidr(rrsets []ResourceRecordSetData, query *DnsQuery) []ResourceRecordSetData {
+
+	ip := query.ClientIP
+	if query.ClientSubnet != nil {
+		ip = query.ClientSubnet.IP
+	}
+
+	location := ""
+	coll, err := s.dataStore.getCidrCollection(aws.ToString(rrsets[0].CidrRoutingConfig.CollectionId))
+	if err != nil {
+		log.Println("Error:", err)
+	} else if ip != nil {
+		location = cidrLocation(coll, ip)
+	}
+
+	var fallback []ResourceRecordSetData
+	for _, rrset := range rrsets {
+		switch aws.ToString(rrset.CidrRoutingConfig.LocationName) {
+		case location:
+			return []ResourceRecordSetData{rrset}
+		case cidrDefaultLocation:
+			fallback = []ResourceRecordSetData{rrset}
+		}
+	}
+
+	return fallback
+}
+
+// checkCidrRouting returns the problems with the CIDR collections and locations the record
+// sets of a change batch route to, in the format of validateChangeBatch.
+func (s *Service) checkCidrRouting(changes []ChangeData) error {
+
+	var messages []string
+	for _, change := range changes {
+
+		rrset := change.ResourceRecordSet
+		if rrset == nil || rrset.CidrRoutingConfig == nil || change.Action == awstypes.ChangeActionDelete {
+			continue
+		}
+
+		collectionId := aws.ToString(rrset.CidrRoutingConfig.CollectionId)
+		location := aws.ToString(rrset.CidrRoutingConfig.LocationName)
+
+		coll, err := s.dataStore.getCidrCollection(collectionId)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("CIDR collection with ID %s does not exist", collectionId))
+			continue
+		}
+
+		if _, found := coll.Locations[location]; !found && location != cidrDefaultLocation {
+			messages = append(messages,
+				fmt.Sprintf("CIDR location %s does not exist in CIDR collection %s", location, collectionId))
+		}
+	}
+
+	if len(messages) > 0 {
+		return &InvalidChangeBatchError{Messages: messages}
+	}
+	return nil
+}
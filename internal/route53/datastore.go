@@ -2,24 +2,41 @@ package route53
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"home-fern/internal/core"
 	"home-fern/internal/datastore"
 	"io"
-	"math"
+	"maps"
+	"net/url"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
 	"go.etcd.io/bbolt"
 )
 
 const (
-	HostedZonePrefix = "/hostedzone/"
-	ChangeInfoPrefix = "/change/"
-	RecordSetPrefix  = "/recordset/"
+	HostedZonePrefix  = "/hostedzone/"
+	ZoneNamePrefix    = "/zonename/"
+	ChangeInfoPrefix  = "/change/"
+	RecordSetPrefix   = "/recordset/"
+	HealthCheckPrefix = "/healthcheck/"
+
+	DelegationSetPrefix      = "/delegationset/"
+	QueryLoggingConfigPrefix = "/queryloggingconfig/"
+	DnssecPrefix             = "/dnssec/"
+	KeySigningKeyPrefix      = "/keysigningkey/"
+
+	TrafficPolicyPrefix         = "/trafficpolicy/"
+	TrafficPolicyInstancePrefix = "/trafficpolicyinstance/"
+	CidrCollectionPrefix        = "/cidrcollection/"
 )
 
 type dataStore struct {
@@ -43,22 +60,156 @@ func (ds *dataStore) deleteAll() error {
 	return nil
 }
 
+// reindex brings the keys of a store written by an earlier version up to date: it moves the
+// record sets to their current keys, rebuilds the name index entries of the hosted zones and
+// corrects their record set counts. It returns the number of record sets moved.
+func (ds *dataStore) reindex() (int, error) {
+	moved := 0
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		var zones []HostedZoneData
+		c := b.Cursor()
+		prefix := []byte(HostedZonePrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var hz HostedZoneData
+			if err := json.Unmarshal(v, &hz); err != nil {
+				return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+			}
+			zones = append(zones, hz)
+		}
+
+		// name index entries of an earlier key format are dropped, the current ones added below
+		current := make(map[string]bool)
+		for _, hz := range zones {
+			current[zoneNameKey(&hz)] = true
+		}
+		var stale [][]byte
+		prefix = []byte(ZoneNamePrefix)
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if !current[string(k)] {
+				stale = append(stale, slices.Clone(k))
+			}
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		for _, hz := range zones {
+			hzPrefix := RecordSetPrefix + strings.TrimPrefix(hz.Id, HostedZonePrefix)
+
+			count := 0
+			moves := make(map[string][]byte)
+			prefix := []byte(hzPrefix + "/")
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				var rrset ResourceRecordSetData
+				if err := json.Unmarshal(v, &rrset); err != nil {
+					return fmt.Errorf("failed to unmarshal record set: %w", err)
+				}
+				header, err := convertToKey(hz.Name, rrset.Name, rrset.Type, rrset.SetIdentifier)
+				if err != nil {
+					return err
+				}
+				if key := hzPrefix + header.rrkey; key != string(k) {
+					moves[string(k)] = slices.Clone(v)
+				}
+				count++
+			}
+
+			for oldKey, v := range moves {
+				var rrset ResourceRecordSetData
+				if err := json.Unmarshal(v, &rrset); err != nil {
+					return fmt.Errorf("failed to unmarshal record set: %w", err)
+				}
+				header, err := convertToKey(hz.Name, rrset.Name, rrset.Type, rrset.SetIdentifier)
+				if err != nil {
+					return err
+				}
+				if err := b.Delete([]byte(oldKey)); err != nil {
+					return err
+				}
+				if err := b.Put([]byte(hzPrefix+header.rrkey), v); err != nil {
+					return err
+				}
+			}
+			moved += len(moves)
+
+			if b.Get([]byte(zoneNameKey(&hz))) == nil {
+				if err := putJson(b, zoneNameKey(&hz), hz.Id); err != nil {
+					return err
+				}
+			}
+
+			if hz.ResourceRecordSetCount != int64(count) {
+				hz.ResourceRecordSetCount = int64(count)
+				if err := putJson(b, hz.Id, hz); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to reindex: %w", err)
+	}
+	return moved, nil
+}
+
 func (ds *dataStore) logKeys(w io.Writer) error {
 	return ds.ds.LogKeys(datastore.Route53, w)
 }
 
+// deleteHostedZone deletes a hosted zone which holds no record sets but its SOA and NS ones, with
+// its DNSSEC keys. The record sets are checked in the transaction deleting them.
 func (ds *dataStore) deleteHostedZone(id string, ci *ChangeInfoData) error {
+	if !strings.HasPrefix(id, HostedZonePrefix) {
+		id = HostedZonePrefix + id
+	}
+
 	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(id))
+		if v == nil {
+			return ErrNoSuchHostedZone
+		}
+		var hz HostedZoneData
+		if err := json.Unmarshal(v, &hz); err != nil {
+			return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+		}
+
+		records, err := zoneRecordSets(b, id)
+		if err != nil {
+			return err
+		}
+		for _, rrset := range records {
+			if normalizeDnsName(rrset.Name) != hz.Name ||
+				(rrset.Type != awstypes.RRTypeNs && rrset.Type != awstypes.RRTypeSoa) {
+				return ErrHostedZoneNotEmpty
+			}
+		}
+
 		prefix := []byte(RecordSetPrefix + strings.TrimPrefix(id, HostedZonePrefix) + "/")
 		c := b.Cursor()
-		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
 			if err := b.Delete(k); err != nil {
 				return err
 			}
 		}
 
-		if !strings.HasPrefix(id, HostedZonePrefix) {
-			id = HostedZonePrefix + id
+		// the zone's DNSSEC keys go with it
+		prefix = []byte(KeySigningKeyPrefix + strings.TrimPrefix(id, HostedZonePrefix) + "/")
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		if err := b.Delete([]byte(DnssecPrefix + strings.TrimPrefix(id, HostedZonePrefix))); err != nil {
+			return err
+		}
+
+		if err := b.Delete([]byte(zoneNameKey(&hz))); err != nil {
+			return err
 		}
 		if err := b.Delete([]byte(id)); err != nil {
 			return err
@@ -74,6 +225,12 @@ func (ds *dataStore) deleteHostedZone(id string, ci *ChangeInfoData) error {
 	})
 
 	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return ErrNoSuchHostedZone
+		}
+		if errors.Is(err, ErrNoSuchHostedZone) || errors.Is(err, ErrHostedZoneNotEmpty) {
+			return err
+		}
 		return fmt.Errorf("failed to delete hosted zone %s: %w", id, err)
 	}
 	return nil
@@ -111,6 +268,85 @@ func (ds *dataStore) findHostedZones(nameFilter *regexp.Regexp) ([]HostedZoneDat
 	return result, nil
 }
 
+// listHostedZones returns up to limit hosted zones which pass keep, in id order starting at
+// marker, and the zone following them.
+func (ds *dataStore) listHostedZones(
+	marker string, keep func(hz *HostedZoneData) bool, limit int) ([]HostedZoneData, *HostedZoneData, error) {
+
+	var result []HostedZoneData
+	var next *HostedZoneData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(HostedZonePrefix)
+		for k, v := c.Seek([]byte(HostedZonePrefix + strings.TrimPrefix(marker, HostedZonePrefix))); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var hz HostedZoneData
+			if err := json.Unmarshal(v, &hz); err != nil {
+				return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+			}
+			if keep != nil && !keep(&hz) {
+				continue
+			}
+			if len(result) == limit {
+				next = &hz
+				break
+			}
+			result = append(result, hz)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []HostedZoneData{}, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to list hosted zones: %w", err)
+	}
+	return result, next, nil
+}
+
+// listHostedZonesByName returns up to limit hosted zones in name order, starting at the zone
+// named dnsName with an id of at least hzId, and the zone following them.
+func (ds *dataStore) listHostedZonesByName(dnsName string, hzId string, limit int) ([]HostedZoneData, *HostedZoneData, error) {
+	var result []HostedZoneData
+	var next *HostedZoneData
+
+	start := ZoneNamePrefix
+	if dnsName != "" {
+		start += reverseName(dnsName) + " " + strings.TrimPrefix(hzId, HostedZonePrefix)
+	}
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(ZoneNamePrefix)
+		for k, v := c.Seek([]byte(start)); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var id string
+			if err := json.Unmarshal(v, &id); err != nil {
+				return fmt.Errorf("failed to unmarshal hosted zone id: %w", err)
+			}
+
+			var hz HostedZoneData
+			if err := json.Unmarshal(b.Get([]byte(id)), &hz); err != nil {
+				return fmt.Errorf("failed to unmarshal hosted zone %s: %w", id, err)
+			}
+			if len(result) == limit {
+				next = &hz
+				break
+			}
+			result = append(result, hz)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []HostedZoneData{}, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to list hosted zones by name: %w", err)
+	}
+	return result, next, nil
+}
+
 func (ds *dataStore) getChange(id string) (*ChangeInfoData, error) {
 	var result ChangeInfoData
 	if !strings.HasPrefix(id, ChangeInfoPrefix) {
@@ -171,27 +407,45 @@ func (ds *dataStore) getHostedZoneCount() (int, error) {
 	return count, nil
 }
 
-func (ds *dataStore) getRecordCount(id string) (int, error) {
-	prefix := RecordSetPrefix + strings.TrimPrefix(id, HostedZonePrefix) + "/"
-	count, err := ds.ds.GetPrefixCount(datastore.Route53, prefix)
+func (ds *dataStore) getResourceRecordSets(hzId string) ([]ResourceRecordSetData, error) {
+	var result []ResourceRecordSetData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		var err error
+		result, err = zoneRecordSets(b, hzId)
+		return err
+	})
+
 	if err != nil {
-		return -1, fmt.Errorf("failed to get record count for %s: %w", id, err)
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []ResourceRecordSetData{}, nil
+		}
+		return nil, fmt.Errorf("failed to get resource record sets for %s: %w", hzId, err)
 	}
-	return int(math.Max(2, float64(count))), nil
+	return result, nil
 }
 
-func (ds *dataStore) getResourceRecordSets(hzId string) ([]ResourceRecordSetData, error) {
+// listResourceRecordSets returns up to limit record sets of a hosted zone in the order of their
+// keys, starting at the key start relative to the zone, and the record set following them.
+func (ds *dataStore) listResourceRecordSets(
+	hzId string, start string, limit int) ([]ResourceRecordSetData, *ResourceRecordSetData, error) {
+
 	var result []ResourceRecordSetData
+	var next *ResourceRecordSetData
 	prefix := RecordSetPrefix + strings.TrimPrefix(hzId, HostedZonePrefix) + "/"
 
 	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
 		c := b.Cursor()
 		prefixBytes := []byte(prefix)
-		for k, v := c.Seek(prefixBytes); k != nil && bytes.HasPrefix(k, prefixBytes); k, v = c.Next() {
+		for k, v := c.Seek([]byte(prefix + strings.TrimPrefix(start, "/"))); k != nil && bytes.HasPrefix(k, prefixBytes); k, v = c.Next() {
 			var rr ResourceRecordSetData
 			if err := json.Unmarshal(v, &rr); err != nil {
 				return fmt.Errorf("failed to unmarshal record set: %w", err)
 			}
+			if len(result) == limit {
+				next = &rr
+				break
+			}
 			result = append(result, rr)
 		}
 		return nil
@@ -199,11 +453,11 @@ func (ds *dataStore) getResourceRecordSets(hzId string) ([]ResourceRecordSetData
 
 	if err != nil {
 		if errors.Is(err, datastore.ErrBucketNotFound) {
-			return []ResourceRecordSetData{}, nil
+			return []ResourceRecordSetData{}, nil, nil
 		}
-		return nil, fmt.Errorf("failed to get resource record sets for %s: %w", hzId, err)
+		return nil, nil, fmt.Errorf("failed to list resource record sets for %s: %w", hzId, err)
 	}
-	return result, nil
+	return result, next, nil
 }
 
 func (ds *dataStore) putHostedZone(hz *HostedZoneData, changes []ChangeData, ci *ChangeInfoData) error {
@@ -216,14 +470,24 @@ func (ds *dataStore) putHostedZone(hz *HostedZoneData, changes []ChangeData, ci
 	if cerr != nil {
 		return cerr
 	}
-	if len(curzones) > 0 {
+	if slices.ContainsFunc(curzones, func(cur HostedZoneData) bool { return hostedZonesOverlap(&cur, hz) }) {
 		return ErrHostedZoneAlreadyExists
 	}
 
+	ci.HostedZoneId = hz.Id
+	for _, change := range changes {
+		ci.Added = append(ci.Added, *change.ResourceRecordSet)
+	}
+	hz.ResourceRecordSetCount = int64(len(changes))
+
 	data := []datastore.PutData{{
 		Key:       hz.Id,
 		Data:      hz,
 		Overwrite: false,
+	}, {
+		Key:       zoneNameKey(hz),
+		Data:      hz.Id,
+		Overwrite: false,
 	}, {
 		Key:       ci.Id,
 		Data:      ci,
@@ -243,79 +507,1514 @@ func (ds *dataStore) putHostedZone(hz *HostedZoneData, changes []ChangeData, ci
 	return nil
 }
 
-func (ds *dataStore) putRecordSets(hz *HostedZoneData, changes []ChangeData, ci *ChangeInfoData) error {
-	data, pderr := convertToPutData(hz, changes)
-	if pderr != nil {
-		return pderr
-	}
-	data = append(data, datastore.PutData{
-		Key:       ci.Id,
-		Data:      ci,
-		Overwrite: false,
+// putRecordSets applies the changes to hz, and to the PTR records of the reverse zones when hz
+// has the AutoPtrTag. It returns the reverse zones it changed.
+func (ds *dataStore) putRecordSets(hz *HostedZoneData, changes []ChangeData, ci *ChangeInfoData) ([]HostedZoneData, error) {
+	return ds.changeRecordSets(hz, ci, func([]ResourceRecordSetData) ([]ChangeData, error) {
+		return changes, nil
+	})
+}
+
+// changeRecordSets applies the changes which change returns for the current record sets of hz,
+// the way putRecordSets does. Reading the record sets and writing the changes is one transaction,
+// so no other change comes in between. Without changes the zone is left as it is.
+func (ds *dataStore) changeRecordSets(hz *HostedZoneData, ci *ChangeInfoData,
+	change func(records []ResourceRecordSetData) ([]ChangeData, error)) ([]HostedZoneData, error) {
+	var reverseZones []HostedZoneData
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		if b.Get([]byte(hz.Id)) == nil {
+			return ErrNoSuchHostedZone
+		}
+
+		records, err := zoneRecordSets(b, hz.Id)
+		if err != nil {
+			return err
+		}
+
+		changes, err := change(records)
+		if err != nil || len(changes) == 0 {
+			return err
+		}
+
+		reverseZones, err = applyZoneChanges(b, hz, changes, ci)
+		return err
 	})
 
-	err := ds.ds.PutKeys(datastore.Route53, data)
 	if err != nil {
 		if errors.Is(err, datastore.ErrKeyExists) {
-			return ErrInvalidInput
+			return nil, ErrInvalidInput
+		}
+		if errors.Is(err, ErrInvalidChangeBatch) || errors.Is(err, ErrNoSuchHostedZone) {
+			return nil, err
 		}
-		return fmt.Errorf("failed to put record sets: %w", err)
+		return nil, fmt.Errorf("failed to put record sets: %w", err)
 	}
-	return nil
+	return reverseZones, nil
 }
 
-func (ds *dataStore) updateHostedZone(hz *HostedZoneData) error {
-	data := []datastore.PutData{{
-		Key:       hz.Id,
-		Data:      hz,
-		Overwrite: true,
-	}}
-	err := ds.ds.PutKeys(datastore.Route53, data)
-	if err != nil {
-		return fmt.Errorf("failed to update hosted zone: %w", err)
+// zoneRecordSets returns the record sets of a hosted zone in the order of their keys.
+func zoneRecordSets(b *bbolt.Bucket, hzId string) ([]ResourceRecordSetData, error) {
+	var result []ResourceRecordSetData
+
+	c := b.Cursor()
+	prefix := []byte(RecordSetPrefix + strings.TrimPrefix(hzId, HostedZonePrefix) + "/")
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var rr ResourceRecordSetData
+		if err := json.Unmarshal(v, &rr); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal record set: %w", err)
+		}
+		result = append(result, rr)
 	}
-	return nil
+	return result, nil
 }
 
-func convertToPutData(hz *HostedZoneData, changes []ChangeData) ([]datastore.PutData, error) {
-	hzid := strings.TrimPrefix(hz.Id, HostedZonePrefix)
-	result := make([]datastore.PutData, 0)
+func (ds *dataStore) findZoneChanges(hzId string) ([]ChangeInfoData, error) {
+	var result []ChangeInfoData
 
-	for _, change := range changes {
-		header, err := convertToKey(hz.Name, change.ResourceRecordSet.Name, change.ResourceRecordSet.Type)
-		if err != nil {
-			return nil, err
-		}
-		change.ResourceRecordSet.Name = header.rrname
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		var err error
+		result, err = zoneChanges(b, hzId)
+		return err
+	})
 
-		result = append(result, datastore.PutData{
-			Key:       RecordSetPrefix + hzid + header.rrkey,
-			Data:      change.ResourceRecordSet,
-			Delete:    change.Action == awstypes.ChangeActionDelete,
-			Overwrite: change.Action == awstypes.ChangeActionUpsert,
-		})
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []ChangeInfoData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find changes for %s: %w", hzId, err)
 	}
 	return result, nil
 }
 
-func convertToKey(domainp string, rrname string, rrtype awstypes.RRType) (*recordKey, error) {
-	lwrname := strings.ToLower(rrname)
-	if !strings.HasSuffix(lwrname, ".") {
-		lwrname = lwrname + "."
+// rollbackRecordSets undoes the changes made to a zone after changeId, in one transaction. The inverse
+// changes are applied as a new change so the zone's serial keeps increasing. Record sets of traffic
+// policy instances are left as they are. It returns the reverse zones whose PTR records followed.
+func (ds *dataStore) rollbackRecordSets(hz *HostedZoneData, changeId string, ci *ChangeInfoData) ([]HostedZoneData, error) {
+	var reverseZones []HostedZoneData
+	if !strings.HasPrefix(changeId, ChangeInfoPrefix) {
+		changeId = ChangeInfoPrefix + changeId
 	}
 
-	if lwrname != domainp && !strings.HasSuffix(lwrname, "."+domainp) {
-		return nil, ErrInvalidChangeBatch
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		changes, err := zoneChanges(b, hz.Id)
+		if err != nil {
+			return err
+		}
+
+		target := slices.IndexFunc(changes, func(change ChangeInfoData) bool { return change.Id == changeId })
+		if target < 0 {
+			return core.ErrNotFound
+		}
+
+		keyOf := func(rrset ResourceRecordSetData) (string, error) {
+			header, err := convertToKey(hz.Name, rrset.Name, rrset.Type, rrset.SetIdentifier)
+			if err != nil {
+				return "", err
+			}
+			return header.rrkey, nil
+		}
+
+		current := make(map[string]ResourceRecordSetData)
+		prefix := []byte(RecordSetPrefix + strings.TrimPrefix(hz.Id, HostedZonePrefix) + "/")
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var rrset ResourceRecordSetData
+			if err := json.Unmarshal(v, &rrset); err != nil {
+				return fmt.Errorf("failed to unmarshal record set: %w", err)
+			}
+			key, err := keyOf(rrset)
+			if err != nil {
+				return err
+			}
+			current[key] = rrset
+		}
+
+		// walk back through the later changes, newest first
+		wanted := maps.Clone(current)
+		for i := len(changes) - 1; i > target; i-- {
+			for _, rrset := range changes[i].Added {
+				key, err := keyOf(rrset)
+				if err != nil {
+					return err
+				}
+				delete(wanted, key)
+			}
+			for _, rrset := range changes[i].Removed {
+				key, err := keyOf(rrset)
+				if err != nil {
+					return err
+				}
+				wanted[key] = rrset
+			}
+		}
+
+		// record sets of traffic policy instances are left to their instances
+		for _, records := range []map[string]ResourceRecordSetData{current, wanted} {
+			for key, rrset := range records {
+				if owned(rrset) {
+					delete(current, key)
+					delete(wanted, key)
+				}
+			}
+		}
+
+		// the SOA stays, applying the changes increments its serial
+		var deletes, upserts []ChangeData
+		for _, key := range slices.Sorted(maps.Keys(current)) {
+			if _, found := wanted[key]; !found && current[key].Type != awstypes.RRTypeSoa {
+				rrset := current[key]
+				deletes = append(deletes, ChangeData{Action: awstypes.ChangeActionDelete, ResourceRecordSet: &rrset})
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(wanted)) {
+			existing, found := current[key]
+			if wanted[key].Type != awstypes.RRTypeSoa && (!found || !reflect.DeepEqual(existing, wanted[key])) {
+				rrset := wanted[key]
+				upserts = append(upserts, ChangeData{Action: awstypes.ChangeActionUpsert, ResourceRecordSet: &rrset})
+			}
+		}
+
+		if len(deletes)+len(upserts) == 0 {
+			return fmt.Errorf("zone %s is unchanged since %s: %w", hz.Name, changeId, ErrInvalidInput)
+		}
+
+		reverseZones, err = applyZoneChanges(b, hz, append(deletes, upserts...), ci)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) || errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrInvalidChangeBatch) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to roll back %s: %w", hz.Name, err)
 	}
+	return reverseZones, nil
+}
 
-	rrkey := strings.Replace(lwrname, domainp, "", -1)
-	if rrkey == "" {
-		rrkey = "@"
+// zoneChanges returns the changes of a zone in the order they were applied. Changes of earlier
+// versions, without a sequence, come first in the order they were submitted.
+func zoneChanges(b *bbolt.Bucket, hzId string) ([]ChangeInfoData, error) {
+	var result []ChangeInfoData
+	if !strings.HasPrefix(hzId, HostedZonePrefix) {
+		hzId = HostedZonePrefix + hzId
 	}
 
-	result := recordKey{
-		rrname: lwrname,
-		rrkey:  "/" + strings.TrimSuffix(rrkey, ".") + "/" + strings.ToLower(string(rrtype)),
+	c := b.Cursor()
+	prefix := []byte(ChangeInfoPrefix)
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var ci ChangeInfoData
+		if err := json.Unmarshal(v, &ci); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal change info: %w", err)
+		}
+		if ci.HostedZoneId == hzId {
+			result = append(result, ci)
+		}
 	}
-	return &result, nil
+
+	slices.SortStableFunc(result, func(a, b ChangeInfoData) int {
+		if a.Sequence != 0 || b.Sequence != 0 {
+			return cmp.Compare(a.Sequence, b.Sequence)
+		}
+		return cmp.Or(strings.Compare(a.SubmittedAt, b.SubmittedAt), cmp.Compare(a.Serial, b.Serial))
+	})
+	return result, nil
+}
+
+func (ds *dataStore) deleteHealthCheck(id string) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		key := []byte(HealthCheckPrefix + id)
+		if b.Get(key) == nil {
+			return ErrNoSuchHealthCheck
+		}
+		return b.Delete(key)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrNoSuchHealthCheck) {
+			return ErrNoSuchHealthCheck
+		}
+		return fmt.Errorf("failed to delete health check %s: %w", id, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) findHealthChecks() ([]HealthCheckData, error) {
+	var result []HealthCheckData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(HealthCheckPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var hc HealthCheckData
+			if err := json.Unmarshal(v, &hc); err != nil {
+				return fmt.Errorf("failed to unmarshal health check: %w", err)
+			}
+			result = append(result, hc)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []HealthCheckData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find health checks: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getHealthCheck(id string) (*HealthCheckData, error) {
+	var result HealthCheckData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(HealthCheckPrefix + id))
+		if v == nil {
+			return ErrNoSuchHealthCheck
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, ErrNoSuchHealthCheck
+		}
+		if errors.Is(err, ErrNoSuchHealthCheck) {
+			return nil, ErrNoSuchHealthCheck
+		}
+		return nil, fmt.Errorf("failed to get health check %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+func (ds *dataStore) putHealthCheck(hc *HealthCheckData, overwrite bool) error {
+	data := []datastore.PutData{{
+		Key:       HealthCheckPrefix + hc.Id,
+		Data:      hc,
+		Overwrite: overwrite,
+	}}
+	err := ds.ds.PutKeys(datastore.Route53, data)
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) {
+			return ErrHealthCheckAlreadyExists
+		}
+		return fmt.Errorf("failed to put health check: %w", err)
+	}
+	return nil
+}
+
+func (ds *dataStore) deleteDelegationSet(id string) error {
+	if !strings.HasPrefix(id, DelegationSetPrefix) {
+		id = DelegationSetPrefix + id
+	}
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		if b.Get([]byte(id)) == nil {
+			return ErrNoSuchDelegationSet
+		}
+
+		c := b.Cursor()
+		prefix := []byte(HostedZonePrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var hz HostedZoneData
+			if err := json.Unmarshal(v, &hz); err != nil {
+				return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+			}
+			if hz.DelegationSet.Id == id {
+				return ErrDelegationSetInUse
+			}
+		}
+
+		return b.Delete([]byte(id))
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrNoSuchDelegationSet) || errors.Is(err, ErrDelegationSetInUse) {
+			return err
+		}
+		return fmt.Errorf("failed to delete delegation set %s: %w", id, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) findDelegationSets() ([]DelegationSetData, error) {
+	var result []DelegationSetData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(DelegationSetPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var set DelegationSetData
+			if err := json.Unmarshal(v, &set); err != nil {
+				return fmt.Errorf("failed to unmarshal delegation set: %w", err)
+			}
+			result = append(result, set)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []DelegationSetData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find delegation sets: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getDelegationSet(id string) (*DelegationSetData, error) {
+	var result DelegationSetData
+	if !strings.HasPrefix(id, DelegationSetPrefix) {
+		id = DelegationSetPrefix + id
+	}
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(id))
+		if v == nil {
+			return ErrNoSuchDelegationSet
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, ErrNoSuchDelegationSet
+		}
+		if errors.Is(err, ErrNoSuchDelegationSet) {
+			return nil, ErrNoSuchDelegationSet
+		}
+		return nil, fmt.Errorf("failed to get delegation set %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// putDelegationSet stores a new delegation set. When it's made from a hosted zone's name servers,
+// the zone is switched to the new set in the same transaction.
+func (ds *dataStore) putDelegationSet(set *DelegationSetData, hz *HostedZoneData) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		if b.Get([]byte(set.Id)) != nil {
+			return datastore.ErrKeyExists
+		}
+
+		if hz != nil {
+			hz.DelegationSet = *set
+			if err := putJson(b, hz.Id, hz); err != nil {
+				return err
+			}
+		}
+
+		return putJson(b, set.Id, set)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) {
+			return ErrDelegationSetAlreadyExists
+		}
+		return fmt.Errorf("failed to put delegation set: %w", err)
+	}
+	return nil
+}
+
+func (ds *dataStore) deleteQueryLoggingConfig(id string) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		key := []byte(QueryLoggingConfigPrefix + id)
+		if b.Get(key) == nil {
+			return ErrNoSuchQueryLoggingConfig
+		}
+		return b.Delete(key)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrNoSuchQueryLoggingConfig) {
+			return ErrNoSuchQueryLoggingConfig
+		}
+		return fmt.Errorf("failed to delete query logging config %s: %w", id, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) findQueryLoggingConfigs() ([]QueryLoggingConfigData, error) {
+	var result []QueryLoggingConfigData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(QueryLoggingConfigPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var cfg QueryLoggingConfigData
+			if err := json.Unmarshal(v, &cfg); err != nil {
+				return fmt.Errorf("failed to unmarshal query logging config: %w", err)
+			}
+			result = append(result, cfg)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []QueryLoggingConfigData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find query logging configs: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getQueryLoggingConfig(id string) (*QueryLoggingConfigData, error) {
+	var result QueryLoggingConfigData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(QueryLoggingConfigPrefix + id))
+		if v == nil {
+			return ErrNoSuchQueryLoggingConfig
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, ErrNoSuchQueryLoggingConfig
+		}
+		if errors.Is(err, ErrNoSuchQueryLoggingConfig) {
+			return nil, ErrNoSuchQueryLoggingConfig
+		}
+		return nil, fmt.Errorf("failed to get query logging config %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// putQueryLoggingConfig stores a new query logging config, a hosted zone has at most one.
+func (ds *dataStore) putQueryLoggingConfig(cfg *QueryLoggingConfigData) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(QueryLoggingConfigPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var existing QueryLoggingConfigData
+			if err := json.Unmarshal(v, &existing); err != nil {
+				return fmt.Errorf("failed to unmarshal query logging config: %w", err)
+			}
+			if existing.HostedZoneId == cfg.HostedZoneId {
+				return ErrQueryLoggingConfigExists
+			}
+		}
+
+		return putJson(b, QueryLoggingConfigPrefix+cfg.Id, cfg)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrQueryLoggingConfigExists) {
+			return ErrQueryLoggingConfigExists
+		}
+		return fmt.Errorf("failed to put query logging config: %w", err)
+	}
+	return nil
+}
+
+// getDnssec returns a hosted zone's DNSSEC status, zones start out not signing.
+func (ds *dataStore) getDnssec(hzId string) (*DnssecData, error) {
+	hzId = strings.TrimPrefix(hzId, HostedZonePrefix)
+	result := DnssecData{
+		HostedZoneId:   hzId,
+		ServeSignature: dnssecNotSigning,
+	}
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(DnssecPrefix + hzId))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil && !errors.Is(err, datastore.ErrBucketNotFound) {
+		return nil, fmt.Errorf("failed to get dnssec of %s: %w", hzId, err)
+	}
+	return &result, nil
+}
+
+// wrapZoneSigningKeys passes the DNSSEC status of the zones with an unwrapped zone signing key
+// to wrap, and stores them. It returns the number of keys wrapped.
+func (ds *dataStore) wrapZoneSigningKeys(wrap func(dnssec *DnssecData) error) (int, error) {
+	wrapped := 0
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		var updates []DnssecData
+		c := b.Cursor()
+		prefix := []byte(DnssecPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var dnssec DnssecData
+			if err := json.Unmarshal(v, &dnssec); err != nil {
+				return fmt.Errorf("failed to unmarshal dnssec: %w", err)
+			}
+			if dnssec.ZoneSigningKey == "" {
+				continue
+			}
+			if err := wrap(&dnssec); err != nil {
+				return err
+			}
+			updates = append(updates, dnssec)
+		}
+
+		for _, dnssec := range updates {
+			if err := putJson(b, DnssecPrefix+dnssec.HostedZoneId, &dnssec); err != nil {
+				return err
+			}
+		}
+		wrapped = len(updates)
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, datastore.ErrBucketNotFound) {
+		return 0, fmt.Errorf("failed to wrap zone signing keys: %w", err)
+	}
+	return wrapped, nil
+}
+
+func (ds *dataStore) putDnssec(dnssec *DnssecData, ci *ChangeInfoData) error {
+	data := []datastore.PutData{{
+		Key:       DnssecPrefix + dnssec.HostedZoneId,
+		Data:      dnssec,
+		Overwrite: true,
+	}, {
+		Key:       ci.Id,
+		Data:      ci,
+		Overwrite: false,
+	}}
+	err := ds.ds.PutKeys(datastore.Route53, data)
+	if err != nil {
+		return fmt.Errorf("failed to put dnssec of %s: %w", dnssec.HostedZoneId, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) deleteKeySigningKey(ksk *KeySigningKeyData, ci *ChangeInfoData) error {
+	data := []datastore.PutData{{
+		Key:    KeySigningKeyPrefix + ksk.HostedZoneId + "/" + ksk.Name,
+		Delete: true,
+	}, {
+		Key:       ci.Id,
+		Data:      ci,
+		Overwrite: false,
+	}}
+	err := ds.ds.PutKeys(datastore.Route53, data)
+	if err != nil {
+		return fmt.Errorf("failed to delete key signing key %s: %w", ksk.Name, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) findKeySigningKeys(hzId string) ([]KeySigningKeyData, error) {
+	var result []KeySigningKeyData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(KeySigningKeyPrefix + strings.TrimPrefix(hzId, HostedZonePrefix) + "/")
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var ksk KeySigningKeyData
+			if err := json.Unmarshal(v, &ksk); err != nil {
+				return fmt.Errorf("failed to unmarshal key signing key: %w", err)
+			}
+			result = append(result, ksk)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []KeySigningKeyData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find key signing keys: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getKeySigningKey(hzId string, name string) (*KeySigningKeyData, error) {
+	var result KeySigningKeyData
+	key := KeySigningKeyPrefix + strings.TrimPrefix(hzId, HostedZonePrefix) + "/" + name
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(key))
+		if v == nil {
+			return ErrNoSuchKeySigningKey
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, ErrNoSuchKeySigningKey
+		}
+		if errors.Is(err, ErrNoSuchKeySigningKey) {
+			return nil, ErrNoSuchKeySigningKey
+		}
+		return nil, fmt.Errorf("failed to get key signing key %s: %w", key, err)
+	}
+	return &result, nil
+}
+
+// putKeySigningKey stores a key signing key with the change info, overwrite is false for new keys.
+func (ds *dataStore) putKeySigningKey(ksk *KeySigningKeyData, overwrite bool, ci *ChangeInfoData) error {
+	data := []datastore.PutData{{
+		Key:       KeySigningKeyPrefix + ksk.HostedZoneId + "/" + ksk.Name,
+		Data:      ksk,
+		Overwrite: overwrite,
+	}, {
+		Key:       ci.Id,
+		Data:      ci,
+		Overwrite: false,
+	}}
+	err := ds.ds.PutKeys(datastore.Route53, data)
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) {
+			return ErrKeySigningKeyAlreadyExists
+		}
+		return fmt.Errorf("failed to put key signing key %s: %w", ksk.Name, err)
+	}
+	return nil
+}
+
+// deleteTrafficPolicy deletes one version of a traffic policy unless an instance uses it.
+func (ds *dataStore) deleteTrafficPolicy(id string, version int32) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		key := []byte(fmt.Sprintf("%s%s/%d", TrafficPolicyPrefix, id, version))
+		if b.Get(key) == nil {
+			return ErrNoSuchTrafficPolicy
+		}
+
+		c := b.Cursor()
+		prefix := []byte(TrafficPolicyInstancePrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var instance TrafficPolicyInstanceData
+			if err := json.Unmarshal(v, &instance); err != nil {
+				return fmt.Errorf("failed to unmarshal traffic policy instance: %w", err)
+			}
+			if instance.TrafficPolicyId == id && instance.TrafficPolicyVersion == version {
+				return ErrTrafficPolicyInUse
+			}
+		}
+
+		return b.Delete(key)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return ErrNoSuchTrafficPolicy
+		}
+		if errors.Is(err, ErrNoSuchTrafficPolicy) || errors.Is(err, ErrTrafficPolicyInUse) {
+			return err
+		}
+		return fmt.Errorf("failed to delete traffic policy %s: %w", id, err)
+	}
+	return nil
+}
+
+// findTrafficPolicies returns every version of every traffic policy.
+func (ds *dataStore) findTrafficPolicies() ([]TrafficPolicyData, error) {
+	var result []TrafficPolicyData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(TrafficPolicyPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var tp TrafficPolicyData
+			if err := json.Unmarshal(v, &tp); err != nil {
+				return fmt.Errorf("failed to unmarshal traffic policy: %w", err)
+			}
+			result = append(result, tp)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []TrafficPolicyData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find traffic policies: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getTrafficPolicy(id string, version int32) (*TrafficPolicyData, error) {
+	var result TrafficPolicyData
+	key := fmt.Sprintf("%s%s/%d", TrafficPolicyPrefix, id, version)
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(key))
+		if v == nil {
+			return ErrNoSuchTrafficPolicy
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, ErrNoSuchTrafficPolicy
+		}
+		if errors.Is(err, ErrNoSuchTrafficPolicy) {
+			return nil, ErrNoSuchTrafficPolicy
+		}
+		return nil, fmt.Errorf("failed to get traffic policy %s: %w", key, err)
+	}
+	return &result, nil
+}
+
+// putTrafficPolicy stores a traffic policy version, overwrite is false for new versions.
+// The first version of a policy needs a name no other policy has.
+func (ds *dataStore) putTrafficPolicy(tp *TrafficPolicyData, overwrite bool) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		key := fmt.Sprintf("%s%s/%d", TrafficPolicyPrefix, tp.Id, tp.Version)
+		if !overwrite && b.Get([]byte(key)) != nil {
+			return datastore.ErrKeyExists
+		}
+
+		if tp.Version == 1 && !overwrite {
+			c := b.Cursor()
+			prefix := []byte(TrafficPolicyPrefix)
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				var existing TrafficPolicyData
+				if err := json.Unmarshal(v, &existing); err != nil {
+					return fmt.Errorf("failed to unmarshal traffic policy: %w", err)
+				}
+				if existing.Name == tp.Name {
+					return ErrTrafficPolicyAlreadyExists
+				}
+			}
+		}
+
+		return putJson(b, key, tp)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) || errors.Is(err, ErrTrafficPolicyAlreadyExists) {
+			return ErrTrafficPolicyAlreadyExists
+		}
+		return fmt.Errorf("failed to put traffic policy %s: %w", tp.Id, err)
+	}
+	return nil
+}
+
+// deleteTrafficPolicyInstance removes an instance along with a change deleting its record sets.
+// It returns the number of record sets deleted.
+func (ds *dataStore) deleteTrafficPolicyInstance(
+	instance *TrafficPolicyInstanceData, hz *HostedZoneData, ci *ChangeInfoData) (int, error) {
+	var changes []ChangeData
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		key := []byte(TrafficPolicyInstancePrefix + instance.Id)
+		if b.Get(key) == nil {
+			return ErrNoSuchTrafficPolicyInstance
+		}
+
+		records, err := zoneRecordSets(b, hz.Id)
+		if err != nil {
+			return err
+		}
+		for _, rrset := range records {
+			if aws.ToString(rrset.TrafficPolicyInstanceId) == instance.Id {
+				changes = append(changes, ChangeData{Action: awstypes.ChangeActionDelete, ResourceRecordSet: &rrset})
+			}
+		}
+
+		if len(changes) > 0 {
+			if err := applyRecordChanges(b, hz, changes, ci); err != nil {
+				return err
+			}
+		}
+
+		return b.Delete(key)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrNoSuchTrafficPolicyInstance) || errors.Is(err, ErrInvalidChangeBatch) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("failed to delete traffic policy instance %s: %w", instance.Id, err)
+	}
+	return len(changes), nil
+}
+
+func (ds *dataStore) findTrafficPolicyInstances() ([]TrafficPolicyInstanceData, error) {
+	var result []TrafficPolicyInstanceData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(TrafficPolicyInstancePrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var instance TrafficPolicyInstanceData
+			if err := json.Unmarshal(v, &instance); err != nil {
+				return fmt.Errorf("failed to unmarshal traffic policy instance: %w", err)
+			}
+			result = append(result, instance)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []TrafficPolicyInstanceData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find traffic policy instances: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getTrafficPolicyInstance(id string) (*TrafficPolicyInstanceData, error) {
+	var result TrafficPolicyInstanceData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(TrafficPolicyInstancePrefix + id))
+		if v == nil {
+			return ErrNoSuchTrafficPolicyInstance
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return nil, ErrNoSuchTrafficPolicyInstance
+		}
+		if errors.Is(err, ErrNoSuchTrafficPolicyInstance) {
+			return nil, ErrNoSuchTrafficPolicyInstance
+		}
+		return nil, fmt.Errorf("failed to get traffic policy instance %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// putTrafficPolicyInstance stores an instance and applies the changes which change returns for the
+// zone's current record sets, in one transaction. A new instance (overwrite is false) needs a name
+// and type no other instance of the zone has.
+func (ds *dataStore) putTrafficPolicyInstance(instance *TrafficPolicyInstanceData, overwrite bool,
+	hz *HostedZoneData, change func(records []ResourceRecordSetData) ([]ChangeData, error), ci *ChangeInfoData) error {
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		if !overwrite {
+			c := b.Cursor()
+			prefix := []byte(TrafficPolicyInstancePrefix)
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				var existing TrafficPolicyInstanceData
+				if err := json.Unmarshal(v, &existing); err != nil {
+					return fmt.Errorf("failed to unmarshal traffic policy instance: %w", err)
+				}
+				if existing.HostedZoneId == instance.HostedZoneId && existing.Name == instance.Name &&
+					existing.TrafficPolicyType == instance.TrafficPolicyType {
+					return ErrTrafficPolicyInstanceExists
+				}
+			}
+		}
+
+		records, err := zoneRecordSets(b, hz.Id)
+		if err != nil {
+			return err
+		}
+
+		changes, err := change(records)
+		if err != nil {
+			return err
+		}
+
+		if err := applyRecordChanges(b, hz, changes, ci); err != nil {
+			return err
+		}
+
+		return putJson(b, TrafficPolicyInstancePrefix+instance.Id, instance)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrKeyExists) {
+			return ErrInvalidInput
+		}
+		if errors.Is(err, ErrTrafficPolicyInstanceExists) || errors.Is(err, ErrInvalidChangeBatch) {
+			return err
+		}
+		return fmt.Errorf("failed to put traffic policy instance %s: %w", instance.Id, err)
+	}
+	return nil
+}
+
+// updateHostedZone reads a hosted zone, changes it with update and writes it back in one
+// transaction, so changes made in between, like the record set count, aren't lost. The change
+// info, when there is one, is stored with it.
+func (ds *dataStore) updateHostedZone(
+	id string, update func(hz *HostedZoneData) error, ci *ChangeInfoData) (*HostedZoneData, error) {
+	var result HostedZoneData
+	if !strings.HasPrefix(id, HostedZonePrefix) {
+		id = HostedZonePrefix + id
+	}
+
+	var updateErr error
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(id))
+		if v == nil {
+			return ErrNoSuchHostedZone
+		}
+		if err := json.Unmarshal(v, &result); err != nil {
+			return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+		}
+
+		if updateErr = update(&result); updateErr != nil {
+			return updateErr
+		}
+
+		if err := putJson(b, result.Id, &result); err != nil {
+			return err
+		}
+
+		if ci == nil {
+			return nil
+		}
+		if b.Get([]byte(ci.Id)) != nil {
+			return datastore.ErrKeyExists
+		}
+		return putJson(b, ci.Id, ci)
+	})
+
+	if updateErr != nil {
+		return nil, updateErr
+	}
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) || errors.Is(err, ErrNoSuchHostedZone) {
+			return nil, ErrNoSuchHostedZone
+		}
+		return nil, fmt.Errorf("failed to update hosted zone %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// importHostedZone writes an imported hosted zone over the stored one, with its name index entry,
+// and upserts its record sets once they're valid. The record set count is that of the stored record sets, not the
+// one imported.
+func (ds *dataStore) importHostedZone(hz *HostedZoneData, changes []ChangeData, ci *ChangeInfoData) ([]HostedZoneData, error) {
+	var reverseZones []HostedZoneData
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		if v := b.Get([]byte(hz.Id)); v != nil {
+			var stored HostedZoneData
+			if err := json.Unmarshal(v, &stored); err != nil {
+				return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+			}
+			if err := b.Delete([]byte(zoneNameKey(&stored))); err != nil {
+				return err
+			}
+		}
+
+		records, err := zoneRecordSets(b, hz.Id)
+		if err != nil {
+			return err
+		}
+		hz.ResourceRecordSetCount = int64(len(records))
+
+		// the imported record sets are validated against the ones they're applied to
+		if len(changes) > 0 {
+			if err := validateChangeBatch(hz, records, changes); err != nil {
+				return err
+			}
+		}
+
+		if err := putJson(b, hz.Id, hz); err != nil {
+			return err
+		}
+		if err := putJson(b, zoneNameKey(hz), hz.Id); err != nil {
+			return err
+		}
+
+		reverseZones, err = applyZoneChanges(b, hz, changes, ci)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrInvalidChangeBatch) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to import hosted zone %s: %w", hz.Name, err)
+	}
+	return reverseZones, nil
+}
+
+func convertToPutData(hz *HostedZoneData, changes []ChangeData) ([]datastore.PutData, error) {
+	hzid := strings.TrimPrefix(hz.Id, HostedZonePrefix)
+	result := make([]datastore.PutData, 0)
+
+	for _, change := range changes {
+		header, err := convertToKey(hz.Name, change.ResourceRecordSet.Name, change.ResourceRecordSet.Type,
+			change.ResourceRecordSet.SetIdentifier)
+		if err != nil {
+			return nil, err
+		}
+		change.ResourceRecordSet.Name = header.rrname
+
+		result = append(result, datastore.PutData{
+			Key:       RecordSetPrefix + hzid + header.rrkey,
+			Data:      change.ResourceRecordSet,
+			Delete:    change.Action == awstypes.ChangeActionDelete,
+			Overwrite: change.Action == awstypes.ChangeActionUpsert,
+		})
+	}
+	return result, nil
+}
+
+// applyZoneChanges applies changes to hz with applyRecordChanges and, when hz has the AutoPtrTag,
+// syncs the PTR records of the reverse zones. It returns the reverse zones it changed.
+func applyZoneChanges(b *bbolt.Bucket, hz *HostedZoneData, changes []ChangeData, ci *ChangeInfoData) ([]HostedZoneData, error) {
+	if err := applyRecordChanges(b, hz, changes, ci); err != nil {
+		return nil, err
+	}
+	if !autoPtr(hz) {
+		return nil, nil
+	}
+
+	return syncPtrRecords(b, hz, ci)
+}
+
+// applyRecordChanges writes changes and the change info within the caller's transaction.
+// The change info records the replaced and written record sets, and unless the batch
+// sets the SOA itself the zone's SOA serial is incremented.
+func applyRecordChanges(b *bbolt.Bucket, hz *HostedZoneData, changes []ChangeData, ci *ChangeInfoData) error {
+	hzid := strings.TrimPrefix(hz.Id, HostedZonePrefix)
+	soaChanged := false
+	added := 0
+	ci.ChangeBatch = &ChangeBatchWrapper{Changes: changes, Comment: core.StringOrNil(ci.Comment)}
+
+	for _, change := range changes {
+		header, err := convertToKey(hz.Name, change.ResourceRecordSet.Name, change.ResourceRecordSet.Type,
+			change.ResourceRecordSet.SetIdentifier)
+		if err != nil {
+			return err
+		}
+		change.ResourceRecordSet.Name = header.rrname
+		key := []byte(RecordSetPrefix + hzid + header.rrkey)
+
+		if alias := change.ResourceRecordSet.AliasTarget; alias != nil {
+			alias.DNSName = aws.String(strings.ToLower(dns.Fqdn(aws.ToString(alias.DNSName))))
+		}
+
+		if v := b.Get(key); v != nil {
+			if change.Action == awstypes.ChangeActionCreate {
+				return datastore.ErrKeyExists
+			}
+
+			var existing ResourceRecordSetData
+			if err := json.Unmarshal(v, &existing); err != nil {
+				return fmt.Errorf("failed to unmarshal record set: %w", err)
+			}
+			if msg := ownerConflict(header.rrname, &existing, change.ResourceRecordSet); msg != "" {
+				return &InvalidChangeBatchError{Messages: []string{msg}}
+			}
+			ci.Removed = append(ci.Removed, existing)
+			added--
+		}
+
+		if change.ResourceRecordSet.Type == awstypes.RRTypeSoa {
+			soaChanged = true
+		}
+
+		if change.Action == awstypes.ChangeActionDelete {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := putJson(b, string(key), change.ResourceRecordSet); err != nil {
+			return err
+		}
+		ci.Added = append(ci.Added, *change.ResourceRecordSet)
+		added++
+	}
+
+	// aliases are checked once the whole batch is applied, targets may be part of it
+	for _, change := range changes {
+		if change.Action == awstypes.ChangeActionDelete || change.ResourceRecordSet.AliasTarget == nil {
+			continue
+		}
+
+		header, err := convertToKey(hz.Name, change.ResourceRecordSet.Name, change.ResourceRecordSet.Type, nil)
+		if err != nil {
+			return err
+		}
+
+		visited := map[string]bool{RecordSetPrefix + hzid + header.rrkey: true}
+		if err := checkAliasTarget(b, change.ResourceRecordSet, visited); err != nil {
+			return err
+		}
+	}
+
+	soaKey := RecordSetPrefix + hzid + apexKey(hz.Name, awstypes.RRTypeSoa)
+	if v := b.Get([]byte(soaKey)); v != nil {
+		var soa ResourceRecordSetData
+		if err := json.Unmarshal(v, &soa); err != nil {
+			return fmt.Errorf("failed to unmarshal soa: %w", err)
+		}
+
+		if !soaChanged {
+			bumped, err := bumpSoaSerial(hz.Name, soa)
+			if err != nil {
+				return err
+			}
+
+			if err := putJson(b, soaKey, bumped); err != nil {
+				return err
+			}
+			ci.Removed = append(ci.Removed, soa)
+			ci.Added = append(ci.Added, *bumped)
+			soa = *bumped
+		}
+
+		if parsed, err := parseSoa(hz.Name, aws.ToString(soa.ResourceRecords[0].Value)); err == nil {
+			ci.Serial = parsed.Serial
+		}
+	}
+
+	if added != 0 {
+		if err := addRecordCount(b, hz, added); err != nil {
+			return err
+		}
+	}
+
+	// the order of the changes, serials can go down or wrap around
+	sequence, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	ci.Sequence = sequence
+
+	ci.HostedZoneId = hz.Id
+	if b.Get([]byte(ci.Id)) != nil {
+		return datastore.ErrKeyExists
+	}
+	return putJson(b, ci.Id, ci)
+}
+
+// addRecordCount adds n to the record set count of the stored hosted zone, and of hz.
+func addRecordCount(b *bbolt.Bucket, hz *HostedZoneData, n int) error {
+	v := b.Get([]byte(hz.Id))
+	if v == nil {
+		return ErrNoSuchHostedZone
+	}
+
+	var stored HostedZoneData
+	if err := json.Unmarshal(v, &stored); err != nil {
+		return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+	}
+	stored.ResourceRecordSetCount += int64(n)
+	hz.ResourceRecordSetCount = stored.ResourceRecordSetCount
+
+	return putJson(b, stored.Id, stored)
+}
+
+// checkAliasTarget follows an alias through the home-fern hosted zones and fails when
+// a target doesn't exist or the aliases loop. visited holds the record keys on the path.
+func checkAliasTarget(b *bbolt.Bucket, rrset *ResourceRecordSetData, visited map[string]bool) error {
+	target := rrset.AliasTarget
+	dnsName := aws.ToString(target.DNSName)
+
+	v := b.Get([]byte(HostedZonePrefix + strings.TrimPrefix(aws.ToString(target.HostedZoneId), HostedZonePrefix)))
+	if v == nil {
+		// not one of our zones, e.g. a load balancer
+		return nil
+	}
+
+	var hz HostedZoneData
+	if err := json.Unmarshal(v, &hz); err != nil {
+		return fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+	}
+
+	header, err := convertToKey(hz.Name, dnsName, rrset.Type, nil)
+	if err != nil {
+		return &InvalidChangeBatchError{Messages: []string{fmt.Sprintf("Tried to create an alias that targets %s, "+
+			"type %s in zone %s, but the alias target name does not lie within the target zone", dnsName, rrset.Type, strings.TrimPrefix(hz.Id, HostedZonePrefix))}}
+	}
+
+	prefix := RecordSetPrefix + strings.TrimPrefix(hz.Id, HostedZonePrefix) + header.rrkey
+	if visited[prefix] {
+		return &InvalidChangeBatchError{Messages: []string{fmt.Sprintf("Tried to create an alias that targets %s, "+
+			"type %s in zone %s, but the alias target creates a loop", dnsName, rrset.Type, strings.TrimPrefix(hz.Id, HostedZonePrefix))}}
+	}
+	visited[prefix] = true
+	defer delete(visited, prefix)
+
+	found := false
+	c := b.Cursor()
+	for k, v := c.Seek([]byte(prefix)); k != nil; k, v = c.Next() {
+		if string(k) != prefix && !strings.HasPrefix(string(k), prefix+" ") {
+			break
+		}
+		found = true
+
+		var next ResourceRecordSetData
+		if err := json.Unmarshal(v, &next); err != nil {
+			return fmt.Errorf("failed to unmarshal record set: %w", err)
+		}
+		if next.AliasTarget != nil {
+			if err := checkAliasTarget(b, &next, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !found {
+		return &InvalidChangeBatchError{Messages: []string{fmt.Sprintf("Tried to create an alias that targets %s, "+
+			"type %s in zone %s, but that target was not found", dnsName, rrset.Type, strings.TrimPrefix(hz.Id, HostedZonePrefix))}}
+	}
+	return nil
+}
+
+// bumpSoaSerial returns a copy of the SOA record set with the serial incremented.
+func bumpSoaSerial(zoneName string, rrset ResourceRecordSetData) (*ResourceRecordSetData, error) {
+	if len(rrset.ResourceRecords) == 0 {
+		return nil, ErrInvalidChangeBatch
+	}
+
+	soa, err := parseSoa(zoneName, aws.ToString(rrset.ResourceRecords[0].Value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse soa: %w", err)
+	}
+
+	// serial arithmetic wraps, RFC 1982
+	soa.Serial++
+	value := fmt.Sprintf("%s %s %d %d %d %d %d",
+		soa.Ns, soa.Mbox, soa.Serial, soa.Refresh, soa.Retry, soa.Expire, soa.Minttl)
+
+	rrset.ResourceRecords = []awstypes.ResourceRecord{{Value: aws.String(value)}}
+	return &rrset, nil
+}
+
+// deleteCidrCollection removes a collection which has no locations and isn't used by any record set.
+func (ds *dataStore) deleteCidrCollection(id string) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(CidrCollectionPrefix + id))
+		if v == nil {
+			return ErrNoSuchCidrCollection
+		}
+
+		var coll CidrCollectionData
+		if err := json.Unmarshal(v, &coll); err != nil {
+			return err
+		}
+		if len(coll.Locations) > 0 {
+			return ErrCidrCollectionInUse
+		}
+
+		used, err := cidrLocationsInUse(b, id)
+		if err != nil {
+			return err
+		}
+		if len(used) > 0 {
+			return ErrCidrCollectionInUse
+		}
+
+		return b.Delete([]byte(CidrCollectionPrefix + id))
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrNoSuchCidrCollection) || errors.Is(err, ErrCidrCollectionInUse) {
+			return err
+		}
+		return fmt.Errorf("failed to delete cidr collection %s: %w", id, err)
+	}
+	return nil
+}
+
+func (ds *dataStore) findCidrCollections() ([]CidrCollectionData, error) {
+	var result []CidrCollectionData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(CidrCollectionPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var coll CidrCollectionData
+			if err := json.Unmarshal(v, &coll); err != nil {
+				return fmt.Errorf("failed to unmarshal cidr collection: %w", err)
+			}
+			result = append(result, coll)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) {
+			return []CidrCollectionData{}, nil
+		}
+		return nil, fmt.Errorf("failed to find cidr collections: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *dataStore) getCidrCollection(id string) (*CidrCollectionData, error) {
+	var result CidrCollectionData
+
+	err := ds.ds.View(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(CidrCollectionPrefix + id))
+		if v == nil {
+			return ErrNoSuchCidrCollection
+		}
+		return json.Unmarshal(v, &result)
+	})
+
+	if err != nil {
+		if errors.Is(err, datastore.ErrBucketNotFound) || errors.Is(err, ErrNoSuchCidrCollection) {
+			return nil, ErrNoSuchCidrCollection
+		}
+		return nil, fmt.Errorf("failed to get cidr collection %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// putCidrCollection stores a new collection, collection names are unique.
+func (ds *dataStore) putCidrCollection(coll *CidrCollectionData) error {
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		c := b.Cursor()
+		prefix := []byte(CidrCollectionPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var existing CidrCollectionData
+			if err := json.Unmarshal(v, &existing); err != nil {
+				return fmt.Errorf("failed to unmarshal cidr collection: %w", err)
+			}
+			if existing.Name == coll.Name {
+				return ErrCidrCollectionAlreadyExists
+			}
+		}
+
+		return putJson(b, CidrCollectionPrefix+coll.Id, coll)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrCidrCollectionAlreadyExists) {
+			return ErrCidrCollectionAlreadyExists
+		}
+		return fmt.Errorf("failed to put cidr collection: %w", err)
+	}
+	return nil
+}
+
+// updateCidrCollection replaces version of a collection with coll, unless it changed in the
+// meantime or a record set routes to one of the removed locations.
+func (ds *dataStore) updateCidrCollection(
+	coll *CidrCollectionData, version int64, removed []string, ci *ChangeInfoData) error {
+
+	err := ds.ds.Update(datastore.Route53, func(b *bbolt.Bucket) error {
+		v := b.Get([]byte(CidrCollectionPrefix + coll.Id))
+		if v == nil {
+			return ErrNoSuchCidrCollection
+		}
+
+		var existing CidrCollectionData
+		if err := json.Unmarshal(v, &existing); err != nil {
+			return err
+		}
+		if existing.Version != version {
+			return ErrCidrCollectionVersionMismatch
+		}
+
+		used, err := cidrLocationsInUse(b, coll.Id)
+		if err != nil {
+			return err
+		}
+		for _, location := range removed {
+			if used[location] {
+				return fmt.Errorf("%w: location %s is used by a record set", ErrCidrBlockInUse, location)
+			}
+		}
+
+		if err := putJson(b, CidrCollectionPrefix+coll.Id, coll); err != nil {
+			return err
+		}
+		return putJson(b, ci.Id, ci)
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrNoSuchCidrCollection) || errors.Is(err, ErrCidrCollectionVersionMismatch) ||
+			errors.Is(err, ErrCidrBlockInUse) {
+			return err
+		}
+		return fmt.Errorf("failed to update cidr collection %s: %w", coll.Id, err)
+	}
+	return nil
+}
+
+// cidrLocationsInUse returns the locations of a collection which record sets route to.
+func cidrLocationsInUse(b *bbolt.Bucket, collectionId string) (map[string]bool, error) {
+	result := make(map[string]bool)
+
+	c := b.Cursor()
+	prefix := []byte(RecordSetPrefix)
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var rrset ResourceRecordSetData
+		if err := json.Unmarshal(v, &rrset); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal record set: %w", err)
+		}
+		if cfg := rrset.CidrRoutingConfig; cfg != nil && aws.ToString(cfg.CollectionId) == collectionId {
+			result[aws.ToString(cfg.LocationName)] = true
+		}
+	}
+	return result, nil
+}
+
+// owned reports whether a record set was created by a traffic policy instance.
+func owned(rrset ResourceRecordSetData) bool {
+	return aws.ToString(rrset.TrafficPolicyInstanceId) != ""
+}
+
+func putJson(b *bbolt.Bucket, key string, data interface{}) error {
+	jsonbytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), jsonbytes)
+}
+
+// convertToKey returns the record set key relative to the hosted zone, record sets
+// with a routing policy are told apart by their SetIdentifier. The key starts with the
+// name with its labels reversed, and a space which sorts before any character of a label,
+// so a zone's keys are in the order Route53 lists its record sets in: by reversed name,
+// then type, then SetIdentifier.
+func convertToKey(domainp string, rrname string, rrtype awstypes.RRType, setIdentifier *string) (*recordKey, error) {
+	lwrname := strings.ToLower(rrname)
+	if !strings.HasSuffix(lwrname, ".") {
+		lwrname = lwrname + "."
+	}
+
+	if lwrname != domainp && !strings.HasSuffix(lwrname, "."+domainp) {
+		return nil, ErrInvalidChangeBatch
+	}
+
+	result := recordKey{
+		rrname: lwrname,
+		rrkey:  recordSetKey(lwrname, rrtype, aws.ToString(setIdentifier)),
+	}
+	return &result, nil
+}
+
+// recordSetKey returns the key of a record set relative to its hosted zone, or for seeking
+// to the first key at or after it when rrtype or setIdentifier are empty.
+func recordSetKey(name string, rrtype awstypes.RRType, setIdentifier string) string {
+	result := "/" + reverseName(name) + " " + strings.ToLower(string(rrtype))
+	if setIdentifier != "" {
+		result += " " + url.PathEscape(setIdentifier)
+	}
+	return result
+}
+
+func apexKey(zoneName string, rrtype awstypes.RRType) string {
+	return recordSetKey(zoneName, rrtype, "")
+}
+
+// zoneNameKey returns the key of the hosted zone in the by name index, which is ordered the
+// way ListHostedZonesByName lists zones: by reversed name, then id.
+func zoneNameKey(hz *HostedZoneData) string {
+	return ZoneNamePrefix + reverseName(hz.Name) + " " + strings.TrimPrefix(hz.Id, HostedZonePrefix)
+}
+
+// reverseName returns name with its labels reversed, each ended by a zero byte:
+// "www.example.com." becomes "com\x00example\x00www\x00". The zero byte sorts before
+// any character of a label, so a name sorts before the names below it, and those before the
+// names with a longer label it starts: "a", "b.a", "a-b".
+func reverseName(name string) string {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	slices.Reverse(labels)
+
+	var result strings.Builder
+	for _, label := range labels {
+		result.WriteString(label)
+		result.WriteByte(0)
+	}
+	return result.String()
 }
@@ -0,0 +1,78 @@
+package route53
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestRecordSetKeyOrder(t *testing.T) {
+	type rrset struct {
+		name          string
+		rrtype        awstypes.RRType
+		setIdentifier string
+	}
+
+	// in the order Route53 lists them: by name with its labels reversed, then type, then SetIdentifier
+	want := []rrset{
+		{"example.com.", awstypes.RRTypeNs, ""},
+		{"example.com.", awstypes.RRTypeSoa, ""},
+		{"*.example.com.", awstypes.RRTypeA, ""},
+		{"-x.example.com.", awstypes.RRTypeA, ""},
+		{"a.example.com.", awstypes.RRTypeA, "one"},
+		{"a.example.com.", awstypes.RRTypeA, "two"},
+		{"a.example.com.", awstypes.RRTypeAaaa, ""},
+		{"a.example.com.", awstypes.RRTypeTxt, ""},
+		{"*.a.example.com.", awstypes.RRTypeCname, ""},
+		{"-.a.example.com.", awstypes.RRTypeA, ""},
+		{"b.a.example.com.", awstypes.RRTypeA, ""},
+		{"c.b.a.example.com.", awstypes.RRTypeA, ""},
+		{"a-b.example.com.", awstypes.RRTypeA, ""},
+		{"a0.example.com.", awstypes.RRTypeA, ""},
+		{"ab.example.com.", awstypes.RRTypeA, ""},
+		{"B.example.com.", awstypes.RRTypeA, ""},
+		{"www.example.com.", awstypes.RRTypeA, ""},
+	}
+
+	keys := make([]string, len(want))
+	for i, r := range want {
+		keys[i] = recordSetKey(r.name, r.rrtype, r.setIdentifier)
+	}
+	if !slices.IsSorted(keys) {
+		sorted := slices.Clone(keys)
+		slices.Sort(sorted)
+		t.Errorf("record set keys are out of order, sorted they are %q", sorted)
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"www.example.com.", "com\x00example\x00www\x00"},
+		{"WWW.Example.COM", "com\x00example\x00www\x00"},
+		{"*.example.com.", "com\x00example\x00*\x00"},
+		{".", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reverseName(tt.name); got != tt.want {
+				t.Errorf("reverseName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	// zones are listed by reversed name, then id
+	names := []string{"com.", "example.com.", "a.example.com.", "a-b.example.com.", "example-a.com.", "example.org."}
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = zoneNameKey(&HostedZoneData{Id: HostedZonePrefix + "Z1", Name: name})
+	}
+	if !slices.IsSorted(keys) {
+		t.Errorf("zone name keys are out of order: %q", strings.Join(keys, ", "))
+	}
+}
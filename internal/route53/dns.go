@@ -0,0 +1,273 @@
+package route53
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+// Forwarder answers the queries home-fern forwards to other name servers. It returns a nil
+// message for queries home-fern answers itself; zone is the most specific hosted zone the
+// client sees, or "" when there is none.
+type Forwarder interface {
+	Forward(r *dns.Msg, clientIP net.IP, zone string) (*dns.Msg, error)
+}
+
+// DnsServer answers DNS queries over UDP and TCP from the hosted zones in the datastore.
+type DnsServer struct {
+	service   *Service
+	forwarder Forwarder
+	addr      string
+}
+
+func NewDnsServer(service *Service, forwarder Forwarder, addr string) *DnsServer {
+
+	result := DnsServer{
+		service:   service,
+		forwarder: forwarder,
+		addr:      addr,
+	}
+
+	service.onZoneChanged(result.notifyZone)
+
+	return &result
+}
+
+// ListenAndServe starts the UDP and TCP listeners and blocks until one of them fails.
+func (d *DnsServer) ListenAndServe() error {
+
+	errs := make(chan error, 2)
+
+	for _, network := range []string{"udp", "tcp"} {
+
+		server := &dns.Server{
+			Addr:          d.addr,
+			Net:           network,
+			Handler:       d,
+			TsigSecret:    d.service.tsigSecrets(),
+			MsgAcceptFunc: acceptMsg,
+		}
+
+		go func() {
+			errs <- fmt.Errorf("dns %s listener: %w", network, server.ListenAndServe())
+		}()
+	}
+
+	return <-errs
+}
+
+func (d *DnsServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	if r.Opcode == dns.OpcodeUpdate {
+		d.serveUpdate(w, r)
+		return
+	}
+
+	if r.Opcode != dns.OpcodeQuery {
+		m.SetRcode(r, dns.RcodeNotImplemented)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	if len(r.Question) != 1 {
+		m.SetRcode(r, dns.RcodeFormatError)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	q := r.Question[0]
+	if q.Qclass != dns.ClassINET {
+		m.SetRcode(r, dns.RcodeRefused)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	if q.Qtype == dns.TypeAXFR || q.Qtype == dns.TypeIXFR {
+		d.serveTransfer(w, r)
+		return
+	}
+
+	clientIP := remoteIP(w.RemoteAddr())
+	hz, err := d.service.findVisibleZone(normalizeDnsName(q.Name), clientIP)
+	if err != nil {
+		log.Println("Error:", err)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	if d.forward(w, r, clientIP, hz) {
+		return
+	}
+
+	query := DnsQuery{
+		Name:         q.Name,
+		Type:         awstypes.RRType(dns.TypeToString[q.Qtype]),
+		ClientIP:     clientIP,
+		ClientSubnet: clientSubnet(r),
+		Protocol:     "UDP",
+	}
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		query.Protocol = "TCP"
+	}
+
+	answer, err := d.service.Resolve(&query, hz)
+	if err != nil {
+		log.Println("Error:", err)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	d.service.logQuery(&query, answer)
+
+	m.Rcode = answer.Rcode
+	m.Authoritative = answer.Authoritative
+	m.Answer = toDnsRRs(answer.Answer)
+	m.Ns = toDnsRRs(answer.Authority)
+	m.Extra = toDnsRRs(answer.Additional)
+
+	do := r.IsEdns0() != nil && r.IsEdns0().Do()
+	if err := d.service.dnssecAnswer(&query, answer, m, do); err != nil {
+		log.Println("Error:", err)
+		m = new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+	}
+
+	d.writeMsg(w, r, m)
+}
+
+// forward answers the query through the forwarder when a resolver rule applies to it, hz is
+// the hosted zone visible to the client for the query, or nil. It returns false when the query
+// is answered from the hosted zones.
+func (d *DnsServer) forward(w dns.ResponseWriter, r *dns.Msg, clientIP net.IP, hz *HostedZoneData) bool {
+
+	if d.forwarder == nil {
+		return false
+	}
+
+	zone := ""
+	if hz != nil {
+		zone = hz.Name
+	}
+	reply, err := d.forwarder.Forward(r, clientIP, zone)
+
+	if err != nil {
+		log.Println("Error:", err)
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		d.writeMsg(w, r, m)
+		return true
+	}
+
+	if reply == nil {
+		return false
+	}
+
+	// writeMsg sets the EDNS options for the client
+	reply.Extra = slices.DeleteFunc(reply.Extra, func(rr dns.RR) bool { return rr.Header().Rrtype == dns.TypeOPT })
+	d.writeMsg(w, r, reply)
+
+	return true
+}
+
+// acceptMsg extends the default accept function, which rejects UPDATE messages.
+func acceptMsg(dh dns.Header) dns.MsgAcceptAction {
+
+	const qr = 1 << 15
+	if dh.Bits&qr == 0 && int(dh.Bits>>11)&0xF == dns.OpcodeUpdate {
+		return dns.MsgAccept
+	}
+
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
+func (d *DnsServer) writeMsg(w dns.ResponseWriter, r *dns.Msg, m *dns.Msg) {
+
+	size := dns.MinMsgSize
+	if opt := r.IsEdns0(); opt != nil {
+		size = int(opt.UDPSize())
+		m.SetEdns0(dns.DefaultMsgSize, opt.Do())
+	}
+
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		m.Truncate(size)
+	}
+
+	// answers to signed requests are signed with the same key, the TSIG record goes last
+	if tsig := r.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		log.Printf("DNS write to %s failed: %v", w.RemoteAddr(), err)
+	}
+}
+
+// toDnsRRs turns stored record sets into wire records; values which
+// don't parse are logged and skipped rather than failing the whole answer.
+func toDnsRRs(rrsets []ResourceRecordSetData) []dns.RR {
+
+	var result []dns.RR
+	for _, rrset := range rrsets {
+
+		for _, rr := range rrset.ResourceRecords {
+
+			line := fmt.Sprintf("%s %d IN %s %s",
+				normalizeDnsName(rrset.Name), aws.ToInt64(rrset.TTL), rrset.Type, aws.ToString(rr.Value))
+
+			drr, err := dns.NewRR(line)
+			if err != nil || drr == nil {
+				log.Printf("Skipping invalid record %q: %v", line, err)
+				continue
+			}
+
+			result = append(result, drr)
+		}
+	}
+
+	return result
+}
+
+// clientSubnet returns the EDNS Client Subnet (RFC 7871) of a query, if it has one.
+func clientSubnet(r *dns.Msg) *net.IPNet {
+
+	opt := r.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			bits := 32
+			if subnet.Family == 2 {
+				bits = 128
+			}
+			mask := net.CIDRMask(int(subnet.SourceNetmask), bits)
+			return &net.IPNet{IP: subnet.Address.Mask(mask), Mask: mask}
+		}
+	}
+
+	return nil
+}
+
+func remoteIP(addr net.Addr) net.IP {
+
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+
+	return nil
+}
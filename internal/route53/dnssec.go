@@ -0,0 +1,504 @@
+package route53
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+const (
+	dnssecSigning    = "SIGNING"
+	dnssecNotSigning = "NOT_SIGNING"
+
+	kskActive       = "ACTIVE"
+	kskInactive     = "INACTIVE"
+	kskActionNeeded = "ACTION_NEEDED"
+
+	// maxKeySigningKeys is how many key signing keys Route53 allows per zone.
+	maxKeySigningKeys = 2
+
+	// signatureValidity is how long the signatures in an answer stay valid,
+	// inception is backdated by signatureSkew for clients with slow clocks.
+	signatureValidity = 14 * 24 * time.Hour
+	signatureSkew     = time.Hour
+)
+
+var kskNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{3,128}$`)
+
+// zoneSigner holds the keys which sign one hosted zone's answers.
+type zoneSigner struct {
+	zone    string
+	zsk     *dns.DNSKEY
+	zskKey  crypto.Signer
+	ksks    []*dns.DNSKEY
+	kskKeys []crypto.Signer
+}
+
+// signerCache holds the zone signers of the hosted zones, nil for the zones which aren't signed,
+// until the DNSSEC status or the key signing keys of a zone change. A signer read before a
+// change isn't cached after it.
+type signerCache struct {
+	mu         sync.Mutex
+	signers    map[string]*zoneSigner
+	generation uint64
+}
+
+func newSignerCache() *signerCache {
+	return &signerCache{signers: make(map[string]*zoneSigner)}
+}
+
+// get returns the cached signer of a zone, and the generation to put a signer read now with.
+func (c *signerCache) get(hzId string) (*zoneSigner, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	zs, ok := c.signers[strings.TrimPrefix(hzId, HostedZonePrefix)]
+	return zs, ok, c.generation
+}
+
+func (c *signerCache) put(hzId string, zs *zoneSigner, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation == c.generation {
+		c.signers[strings.TrimPrefix(hzId, HostedZonePrefix)] = zs
+	}
+}
+
+func (c *signerCache) invalidate(hzId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.signers, strings.TrimPrefix(hzId, HostedZonePrefix))
+	c.generation++
+}
+
+// newZoneSigningKey generates the ECDSA P-256 key home-fern signs a zone's record sets with,
+// wrapped by the KMS root key.
+func (s *Service) newZoneSigningKey(hzId string) ([]byte, error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate zone signing key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal zone signing key: %w", err)
+	}
+
+	return s.kms.WrapKey(zoneSigningKeyName(hzId), der)
+}
+
+// zoneSigningKeyName is the name a zone signing key is wrapped with, it doesn't unwrap as
+// another zone's.
+func zoneSigningKeyName(hzId string) string {
+	return "route53/zonesigningkey/" + strings.TrimPrefix(hzId, HostedZonePrefix)
+}
+
+// wrapZoneSigningKeys wraps the zone signing keys earlier versions stored unwrapped.
+func (s *Service) wrapZoneSigningKeys() (int, error) {
+	return s.dataStore.wrapZoneSigningKeys(func(dnssec *DnssecData) error {
+		der, err := base64.StdEncoding.DecodeString(dnssec.ZoneSigningKey)
+		if err != nil {
+			return fmt.Errorf("invalid zone signing key of %s: %w", dnssec.HostedZoneId, err)
+		}
+
+		dnssec.WrappedZoneSigningKey, err = s.kms.WrapKey(zoneSigningKeyName(dnssec.HostedZoneId), der)
+		if err != nil {
+			return err
+		}
+		dnssec.ZoneSigningKey = ""
+		return nil
+	})
+}
+
+// newDnskey returns the DNSKEY record of an ECDSA P-256 public key.
+func newDnskey(zoneName string, flags uint16, ttl uint32, pub crypto.PublicKey) (*dns.DNSKEY, error) {
+
+	ecKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("dnssec keys must be ECDSA P-256 keys")
+	}
+
+	point, err := ecKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zoneName, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: ttl},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+		// the uncompressed point without its 0x04 prefix
+		PublicKey: base64.StdEncoding.EncodeToString(point.Bytes()[1:]),
+	}, nil
+}
+
+// keySigningKey returns the DNSKEY and the KMS signer of a key signing key.
+func (s *Service) keySigningKey(zoneName string, ksk *KeySigningKeyData) (*dns.DNSKEY, crypto.Signer, error) {
+
+	signer, spec, err := s.kms.Signer(ksk.KmsArn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("key signing key %s: %w", ksk.Name, ErrInvalidKMSArn)
+	}
+
+	if spec != kmstypes.KeySpecEccNistP256 {
+		return nil, nil, fmt.Errorf("key signing key %s has key spec %s: %w", ksk.Name, spec, ErrInvalidKMSArn)
+	}
+
+	dnskey, err := newDnskey(zoneName, dns.ZONE|dns.SEP, 3600, signer.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dnskey, signer, nil
+}
+
+// toKeySigningKey describes a key signing key the way GetDNSSEC returns it,
+// with the DS record to publish at the parent zone.
+func (s *Service) toKeySigningKey(hz *HostedZoneData, ksk *KeySigningKeyData) awstypes.KeySigningKey {
+
+	result := awstypes.KeySigningKey{
+		Name:             aws.String(ksk.Name),
+		KmsArn:           aws.String(ksk.KmsArn),
+		Status:           aws.String(ksk.Status),
+		CreatedDate:      aws.Time(ksk.CreatedDate),
+		LastModifiedDate: aws.Time(ksk.LastModifiedDate),
+	}
+
+	dnskey, _, err := s.keySigningKey(hz.Name, ksk)
+	if err != nil {
+		result.Status = aws.String(kskActionNeeded)
+		result.StatusMessage = aws.String(err.Error())
+		return result
+	}
+
+	ds := dnskey.ToDS(dns.SHA256)
+
+	result.Flag = int32(dnskey.Flags)
+	result.SigningAlgorithmMnemonic = aws.String(dns.AlgorithmToString[dnskey.Algorithm])
+	result.SigningAlgorithmType = int32(dnskey.Algorithm)
+	result.DigestAlgorithmMnemonic = aws.String("SHA-256")
+	result.DigestAlgorithmType = int32(ds.DigestType)
+	result.KeyTag = int32(dnskey.KeyTag())
+	result.DigestValue = aws.String(strings.ToUpper(ds.Digest))
+	result.PublicKey = aws.String(dnskey.PublicKey)
+	result.DSRecord = aws.String(fmt.Sprintf("%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType, strings.ToUpper(ds.Digest)))
+	result.DNSKEYRecord = aws.String(fmt.Sprintf("%d %d %d %s", dnskey.Flags, dnskey.Protocol, dnskey.Algorithm, dnskey.PublicKey))
+
+	return result
+}
+
+// zoneSigner returns the keys of a signing zone, or nil when the zone isn't signed.
+func (s *Service) zoneSigner(hz *HostedZoneData) (*zoneSigner, error) {
+
+	zs, ok, generation := s.signers.get(hz.Id)
+	if ok {
+		return zs, nil
+	}
+
+	zs, err := s.readZoneSigner(hz)
+	if err != nil {
+		return nil, err
+	}
+
+	s.signers.put(hz.Id, zs, generation)
+	return zs, nil
+}
+
+// readZoneSigner reads the keys of a signing zone, unwrapping its zone signing key.
+func (s *Service) readZoneSigner(hz *HostedZoneData) (*zoneSigner, error) {
+
+	dnssec, err := s.dataStore.getDnssec(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if dnssec.ServeSignature != dnssecSigning {
+		return nil, nil
+	}
+
+	var der []byte
+	if dnssec.WrappedZoneSigningKey != nil {
+		der, err = s.kms.UnwrapKey(zoneSigningKeyName(hz.Id), dnssec.WrappedZoneSigningKey)
+	} else {
+		// not yet wrapped, when there was no root key to wrap it with at start up
+		der, err = base64.StdEncoding.DecodeString(dnssec.ZoneSigningKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid zone signing key of %s: %w", hz.Name, err)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zone signing key of %s: %w", hz.Name, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("invalid zone signing key of %s", hz.Name)
+	}
+
+	zsk, err := newDnskey(hz.Name, dns.ZONE, 3600, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &zoneSigner{
+		zone:   hz.Name,
+		zsk:    zsk,
+		zskKey: signer,
+	}
+
+	ksks, err := s.dataStore.findKeySigningKeys(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ksks {
+		if ksks[i].Status != kskActive {
+			continue
+		}
+
+		dnskey, kskSigner, err := s.keySigningKey(hz.Name, &ksks[i])
+		if err != nil {
+			return nil, err
+		}
+		result.ksks = append(result.ksks, dnskey)
+		result.kskKeys = append(result.kskKeys, kskSigner)
+	}
+
+	return result, nil
+}
+
+// dnssecAnswer answers DNSKEY queries of a signing zone and, when the client set the DO bit,
+// adds the RRSIG records and the NSEC record denying a name or type to the message.
+func (s *Service) dnssecAnswer(query *DnsQuery, answer *DnsAnswer, m *dns.Msg, do bool) error {
+
+	if answer.Zone == nil {
+		return nil
+	}
+
+	zs, err := s.zoneSigner(answer.Zone)
+	if err != nil || zs == nil {
+		return err
+	}
+
+	name := normalizeDnsName(query.Name)
+	if name == zs.zone && query.Type == awstypes.RRType("DNSKEY") {
+		m.Rcode = dns.RcodeSuccess
+		m.Answer = zs.dnskeys()
+		m.Ns = nil
+	}
+
+	if !do {
+		return nil
+	}
+
+	// CNAME chains can lead into other zones, their records are signed by those zones
+	signers := map[string]*zoneSigner{zs.zone: zs}
+	signerFor := func(owner string) (*zoneSigner, error) {
+		owner = normalizeDnsName(owner)
+		if isSubdomain(owner, zs.zone) {
+			return zs, nil
+		}
+		zr, err := s.loadZoneRecords(owner, query.ClientIP)
+		if err != nil || zr == nil {
+			return nil, err
+		}
+		if signer, found := signers[zr.zone.Name]; found {
+			return signer, nil
+		}
+		signer, err := s.zoneSigner(zr.zone)
+		signers[zr.zone.Name] = signer
+		return signer, err
+	}
+
+	sign := func(rrs []dns.RR) ([]dns.RR, error) {
+		var result []dns.RR
+		for _, rrset := range splitRRsets(rrs) {
+			signer, err := signerFor(rrset[0].Header().Name)
+			if err != nil {
+				return nil, err
+			}
+			if signer == nil {
+				result = append(result, rrset...)
+				continue
+			}
+			signed, err := signer.sign(rrset)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, signed...)
+		}
+		return result, nil
+	}
+
+	if len(m.Answer) > 0 {
+		if m.Answer, err = sign(m.Answer); err != nil {
+			return err
+		}
+		m.Ns, err = sign(m.Ns)
+		return err
+	}
+
+	zr, err := s.loadHostedZoneRecords(answer.Zone)
+	if err != nil {
+		return err
+	}
+
+	// a referral proves the child zone's DS records, or their absence, the NS records stay unsigned
+	if !answer.Authoritative {
+		if len(m.Ns) == 0 {
+			return nil
+		}
+		cut := normalizeDnsName(m.Ns[0].Header().Name)
+		var ds []dns.RR
+		for _, rrset := range zr.names[cut] {
+			if rrset.Type == awstypes.RRTypeDs {
+				ds = append(ds, toDnsRRs([]ResourceRecordSetData{rrset})...)
+			}
+		}
+		if len(ds) == 0 {
+			ds = []dns.RR{zs.nsec(cut, m.Ns[0].Header().Ttl, zoneTypes(zr, cut))}
+		}
+		signed, err := zs.sign(ds)
+		if err != nil {
+			return err
+		}
+		m.Ns = append(m.Ns, signed...)
+		return nil
+	}
+
+	// names which don't exist are answered as having no records of any type,
+	// so an NXDOMAIN becomes a NODATA answer like Route53's
+	ttl := uint32(300)
+	for _, rr := range m.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			ttl = min(soa.Hdr.Ttl, soa.Minttl)
+		}
+	}
+
+	var types []uint16
+	if m.Rcode == dns.RcodeNameError {
+		m.Rcode = dns.RcodeSuccess
+	} else {
+		types = zoneTypes(zr, name)
+	}
+
+	m.Ns, err = sign(append(m.Ns, zs.nsec(name, ttl, types)))
+	return err
+}
+
+// zoneTypes returns the record types at a name, including the ones of a matching wildcard.
+func zoneTypes(zr *zoneRecords, name string) []uint16 {
+
+	rrsets, found := zr.names[name]
+	if !found {
+		rrsets, _ = zr.findWildcard(name)
+	}
+
+	var result []uint16
+	for _, rrset := range rrsets {
+		rrtype := dns.StringToType[string(rrset.Type)]
+		if rrtype != 0 && !slices.Contains(result, rrtype) {
+			result = append(result, rrtype)
+		}
+	}
+	return result
+}
+
+// dnskeys returns the zone's DNSKEY record set.
+func (zs *zoneSigner) dnskeys() []dns.RR {
+
+	result := []dns.RR{zs.zsk}
+	for _, ksk := range zs.ksks {
+		result = append(result, ksk)
+	}
+	return result
+}
+
+// sign returns the records followed by an RRSIG for each of their record sets. The DNSKEY
+// record set is signed by the key signing keys, everything else by the zone signing key.
+func (zs *zoneSigner) sign(rrs []dns.RR) ([]dns.RR, error) {
+
+	result := append([]dns.RR(nil), rrs...)
+	now := time.Now()
+
+	for _, rrset := range splitRRsets(rrs) {
+
+		keys := []*dns.DNSKEY{zs.zsk}
+		signers := []crypto.Signer{zs.zskKey}
+		if rrset[0].Header().Rrtype == dns.TypeDNSKEY {
+			keys, signers = zs.ksks, zs.kskKeys
+		}
+
+		for i, key := range keys {
+			sig := &dns.RRSIG{
+				Hdr:        dns.RR_Header{Ttl: rrset[0].Header().Ttl},
+				Algorithm:  key.Algorithm,
+				Inception:  uint32(now.Add(-signatureSkew).Unix()),
+				Expiration: uint32(now.Add(signatureValidity).Unix()),
+				KeyTag:     key.KeyTag(),
+				SignerName: zs.zone,
+			}
+
+			if err := sig.Sign(signers[i], rrset); err != nil {
+				return nil, fmt.Errorf("failed to sign %s %s: %w",
+					rrset[0].Header().Name, dns.TypeToString[rrset[0].Header().Rrtype], err)
+			}
+			result = append(result, sig)
+		}
+	}
+
+	return result, nil
+}
+
+// nsec returns the minimally covering NSEC record Route53 uses to deny a name or type,
+// it only covers the queried name so a negative answer needs no zone walk.
+func (zs *zoneSigner) nsec(name string, ttl uint32, types []uint16) *dns.NSEC {
+
+	bitmap := append([]uint16{dns.TypeRRSIG, dns.TypeNSEC}, types...)
+	if name == zs.zone {
+		bitmap = append(bitmap, dns.TypeDNSKEY)
+	}
+	slices.Sort(bitmap)
+
+	return &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl},
+		NextDomain: "\\000." + name,
+		TypeBitMap: bitmap,
+	}
+}
+
+// splitRRsets groups records by owner name and type, keeping the order they first appear in.
+func splitRRsets(rrs []dns.RR) [][]dns.RR {
+
+	var result [][]dns.RR
+	index := make(map[string]int)
+
+	for _, rr := range rrs {
+		key := strings.ToLower(rr.Header().Name) + "/" + dns.TypeToString[rr.Header().Rrtype]
+		if i, found := index[key]; found {
+			result[i] = append(result[i], rr)
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, []dns.RR{rr})
+	}
+
+	return result
+}
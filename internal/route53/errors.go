@@ -1,11 +1,64 @@
 package route53
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
-	ErrHostedZoneAlreadyExists = errors.New("hosted zone already exists")
-	ErrNoSuchHostedZone        = errors.New("no such hosted zone")
-	ErrHostedZoneNotEmpty      = errors.New("hosted zone not empty")
-	ErrInvalidChangeBatch      = errors.New("invalid change batch")
-	ErrInvalidInput            = errors.New("invalid input")
+	ErrHostedZoneAlreadyExists       = errors.New("hosted zone already exists")
+	ErrNoSuchHostedZone              = errors.New("no such hosted zone")
+	ErrHostedZoneNotEmpty            = errors.New("hosted zone not empty")
+	ErrInvalidChangeBatch            = errors.New("invalid change batch")
+	ErrInvalidInput                  = errors.New("invalid input")
+	ErrNoSuchHealthCheck             = errors.New("no such health check")
+	ErrHealthCheckAlreadyExists      = errors.New("health check already exists")
+	ErrHealthCheckVersionMismatch    = errors.New("health check version mismatch")
+	ErrNoSuchDelegationSet           = errors.New("no such delegation set")
+	ErrDelegationSetAlreadyExists    = errors.New("delegation set already created")
+	ErrDelegationSetInUse            = errors.New("delegation set in use")
+	ErrDelegationSetReusable         = errors.New("delegation set already reusable")
+	ErrInvalidVPCId                  = errors.New("invalid vpc id")
+	ErrPublicZoneVPCAssociation      = errors.New("public zone vpc association")
+	ErrVPCAssociationNotFound        = errors.New("vpc association not found")
+	ErrLastVPCAssociation            = errors.New("last vpc association")
+	ErrConflictingDomainExists       = errors.New("conflicting domain exists")
+	ErrNoSuchQueryLoggingConfig      = errors.New("no such query logging config")
+	ErrQueryLoggingConfigExists      = errors.New("query logging config already exists")
+	ErrNoSuchLogGroup                = errors.New("no such log group")
+	ErrNoSuchKeySigningKey           = errors.New("no such key signing key")
+	ErrKeySigningKeyAlreadyExists    = errors.New("key signing key already exists")
+	ErrInvalidKeySigningKeyName      = errors.New("invalid key signing key name")
+	ErrInvalidKeySigningKeyStatus    = errors.New("invalid key signing key status")
+	ErrKeySigningKeyInUse            = errors.New("key signing key in use")
+	ErrNoActiveKeySigningKey         = errors.New("no active key signing key")
+	ErrTooManyKeySigningKeys         = errors.New("too many key signing keys")
+	ErrInvalidKMSArn                 = errors.New("invalid kms arn")
+	ErrDNSSECNotSupported            = errors.New("dnssec not supported for private zones")
+	ErrNoSuchTrafficPolicy           = errors.New("no such traffic policy")
+	ErrTrafficPolicyAlreadyExists    = errors.New("traffic policy already exists")
+	ErrTrafficPolicyInUse            = errors.New("traffic policy in use")
+	ErrNoSuchTrafficPolicyInstance   = errors.New("no such traffic policy instance")
+	ErrTrafficPolicyInstanceExists   = errors.New("traffic policy instance already exists")
+	ErrConflictingTypes              = errors.New("conflicting types")
+	ErrInvalidTrafficPolicyDocument  = errors.New("invalid traffic policy document")
+	ErrNoSuchCidrCollection          = errors.New("no such cidr collection")
+	ErrCidrCollectionAlreadyExists   = errors.New("cidr collection already exists")
+	ErrCidrCollectionVersionMismatch = errors.New("cidr collection version mismatch")
+	ErrCidrCollectionInUse           = errors.New("cidr collection in use")
+	ErrNoSuchCidrLocation            = errors.New("no such cidr location")
+	ErrCidrBlockInUse                = errors.New("cidr block in use")
 )
+
+// InvalidChangeBatchError carries the messages Route53 returns with a rejected change batch.
+type InvalidChangeBatchError struct {
+	Messages []string
+}
+
+func (e *InvalidChangeBatchError) Error() string {
+	return strings.Join(e.Messages, "; ")
+}
+
+func (e *InvalidChangeBatchError) Unwrap() error {
+	return ErrInvalidChangeBatch
+}
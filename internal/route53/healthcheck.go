@@ -0,0 +1,368 @@
+package route53
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+const (
+	healthCheckTick    = time.Second
+	healthCheckTimeout = 4 * time.Second
+
+	// searchStringWindow is how much of the response body Route53 searches for SearchString.
+	searchStringWindow = 5120
+)
+
+// healthState is the in-memory status of one health check; a check changes state after
+// FailureThreshold consecutive observations which disagree with the current state.
+type healthState struct {
+	healthy   bool
+	inverted  bool
+	successes int32
+	failures  int32
+	report    string
+	checked   time.Time
+	next      time.Time
+	running   bool
+}
+
+type healthChecker struct {
+	mu     sync.Mutex
+	states map[string]*healthState
+}
+
+func newHealthChecker() *healthChecker {
+	return &healthChecker{states: make(map[string]*healthState)}
+}
+
+// RunHealthChecks probes the configured health checks at their request interval. It doesn't return.
+func (s *Service) RunHealthChecks() {
+
+	ticker := time.NewTicker(healthCheckTick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+
+		checks, err := s.dataStore.findHealthChecks()
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+
+		s.health.schedule(checks, time.Now())
+	}
+}
+
+// isHealthy reports the status used for routing. Unknown health checks are healthy, as in Route53.
+func (s *Service) isHealthy(id string) bool {
+	return s.health.isHealthy(id)
+}
+
+func (h *healthChecker) schedule(checks []HealthCheckData, now time.Time) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id := range h.states {
+		if !slices.ContainsFunc(checks, func(hc HealthCheckData) bool { return hc.Id == id }) {
+			delete(h.states, id)
+		}
+	}
+
+	var calculated []HealthCheckData
+	for _, hc := range checks {
+
+		state, found := h.states[hc.Id]
+		if !found {
+			state = &healthState{healthy: true, report: "Pending: waiting for the first check"}
+			h.states[hc.Id] = state
+		}
+
+		cfg := hc.HealthCheckConfig
+		state.inverted = aws.ToBool(cfg.Inverted) && !aws.ToBool(cfg.Disabled)
+
+		switch {
+		case aws.ToBool(cfg.Disabled):
+			state.healthy = true
+			state.report = "Success: Health check is disabled"
+			state.checked = now
+
+		case cfg.Type == awstypes.HealthCheckTypeCalculated:
+			calculated = append(calculated, hc)
+
+		case cfg.Type == awstypes.HealthCheckTypeCloudwatchMetric || cfg.Type == awstypes.HealthCheckTypeRecoveryControl:
+			if cfg.InsufficientDataHealthStatus == awstypes.InsufficientDataHealthStatusUnhealthy {
+				state.healthy = false
+			} else if cfg.InsufficientDataHealthStatus != awstypes.InsufficientDataHealthStatusLastKnownStatus {
+				state.healthy = true
+			}
+			state.report = fmt.Sprintf("Insufficient data: %s health checks are not evaluated", cfg.Type)
+			state.checked = now
+
+		case !state.running && !now.Before(state.next):
+			state.running = true
+			state.next = now.Add(time.Duration(aws.ToInt32(cfg.RequestInterval)) * time.Second)
+			go h.probe(hc)
+		}
+	}
+
+	for _, hc := range calculated {
+		h.calculate(hc, now)
+	}
+}
+
+func (h *healthChecker) probe(hc HealthCheckData) {
+
+	ok, report := probeTarget(&hc.HealthCheckConfig)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, found := h.states[hc.Id]
+	if !found {
+		return
+	}
+
+	threshold := aws.ToInt32(hc.HealthCheckConfig.FailureThreshold)
+
+	state.running = false
+	state.report = report
+	state.checked = time.Now().UTC()
+
+	if ok {
+		state.successes++
+		state.failures = 0
+		if !state.healthy && state.successes >= threshold {
+			state.healthy = true
+		}
+	} else {
+		state.failures++
+		state.successes = 0
+		if state.healthy && state.failures >= threshold {
+			state.healthy = false
+		}
+	}
+}
+
+// calculate evaluates a CALCULATED health check from its children; callers hold mu.
+func (h *healthChecker) calculate(hc HealthCheckData, now time.Time) {
+
+	healthy := 0
+	for _, child := range hc.HealthCheckConfig.ChildHealthChecks {
+		if h.statusOf(child) {
+			healthy++
+		}
+	}
+
+	state := h.states[hc.Id]
+	state.healthy = int32(healthy) >= aws.ToInt32(hc.HealthCheckConfig.HealthThreshold)
+	state.checked = now
+	state.report = fmt.Sprintf("%d of %d child health checks are healthy",
+		healthy, len(hc.HealthCheckConfig.ChildHealthChecks))
+
+	if state.healthy {
+		state.report = "Success: " + state.report
+	} else {
+		state.report = "Failure: " + state.report
+	}
+}
+
+func (h *healthChecker) isHealthy(id string) bool {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.statusOf(id)
+}
+
+// statusOf applies Inverted to the observed state; callers hold mu.
+func (h *healthChecker) statusOf(id string) bool {
+
+	state, found := h.states[id]
+	if !found {
+		return true
+	}
+
+	return state.healthy != state.inverted
+}
+
+func (h *healthChecker) observation(id string) (string, time.Time) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, found := h.states[id]
+	if !found {
+		return "Pending: waiting for the first check", time.Now().UTC()
+	}
+
+	return state.report, state.checked
+}
+
+// probeTarget runs one HTTP, HTTPS or TCP check and returns a Route53 style status report.
+func probeTarget(cfg *HealthCheckConfigData) (bool, string) {
+
+	host := aws.ToString(cfg.IPAddress)
+	if host == "" {
+		host = aws.ToString(cfg.FullyQualifiedDomainName)
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(int(aws.ToInt32(cfg.Port))))
+
+	if cfg.Type == awstypes.HealthCheckTypeTcp {
+		conn, err := net.DialTimeout("tcp", addr, healthCheckTimeout)
+		if err != nil {
+			return false, "Failure: " + err.Error()
+		}
+		conn.Close()
+		return true, "Success: TCP connection established"
+	}
+
+	scheme := "http"
+	if cfg.Type == awstypes.HealthCheckTypeHttps || cfg.Type == awstypes.HealthCheckTypeHttpsStrMatch {
+		scheme = "https"
+	}
+
+	path := aws.ToString(cfg.ResourcePath)
+	if path == "" {
+		path = "/"
+	}
+
+	req, err := http.NewRequest(http.MethodGet, scheme+"://"+addr+path, nil)
+	if err != nil {
+		return false, "Failure: " + err.Error()
+	}
+	if fqdn := aws.ToString(cfg.FullyQualifiedDomainName); fqdn != "" {
+		req.Host = fqdn
+	}
+	req.Header.Set("User-Agent", "Amazon-Route53-Health-Check-Service (home-fern)")
+
+	// like Route53, certificates aren't validated
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if aws.ToBool(cfg.EnableSNI) {
+		tlsConfig.ServerName = aws.ToString(cfg.FullyQualifiedDomainName)
+	}
+
+	client := http.Client{
+		Timeout:   healthCheckTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, "Failure: " + err.Error()
+	}
+	defer resp.Body.Close()
+
+	status := fmt.Sprintf("HTTP Status Code %d, %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return false, "Failure: " + status
+	}
+
+	if cfg.Type == awstypes.HealthCheckTypeHttpStrMatch || cfg.Type == awstypes.HealthCheckTypeHttpsStrMatch {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, searchStringWindow))
+		if err != nil {
+			return false, "Failure: " + err.Error()
+		}
+		if !strings.Contains(string(body), aws.ToString(cfg.SearchString)) {
+			return false, "Failure: " + status + ". The search string was not found in the response body."
+		}
+	}
+
+	return true, "Success: " + status
+}
+
+// newResourceId returns a random UUID, the id format Route53 uses for health checks
+// and query logging configs.
+func newResourceId() string {
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// normalizeHealthCheckConfig applies the Route53 defaults and rejects configs which can't be probed.
+func normalizeHealthCheckConfig(cfg *HealthCheckConfigData) error {
+
+	if cfg.RequestInterval == nil {
+		cfg.RequestInterval = aws.Int32(30)
+	}
+	if cfg.FailureThreshold == nil {
+		cfg.FailureThreshold = aws.Int32(3)
+	}
+
+	if interval := aws.ToInt32(cfg.RequestInterval); interval != 10 && interval != 30 {
+		return fmt.Errorf("request interval %d: %w", interval, ErrInvalidInput)
+	}
+	if threshold := aws.ToInt32(cfg.FailureThreshold); threshold < 1 || threshold > 10 {
+		return fmt.Errorf("failure threshold %d: %w", threshold, ErrInvalidInput)
+	}
+
+	switch cfg.Type {
+	case awstypes.HealthCheckTypeCalculated:
+		if len(cfg.ChildHealthChecks) == 0 || len(cfg.ChildHealthChecks) > 256 {
+			return fmt.Errorf("calculated health check needs child health checks: %w", ErrInvalidInput)
+		}
+		if threshold := aws.ToInt32(cfg.HealthThreshold); threshold < 0 || threshold > 256 {
+			return fmt.Errorf("health threshold %d: %w", threshold, ErrInvalidInput)
+		}
+		return nil
+
+	case awstypes.HealthCheckTypeCloudwatchMetric, awstypes.HealthCheckTypeRecoveryControl:
+		return nil
+
+	case awstypes.HealthCheckTypeHttp, awstypes.HealthCheckTypeHttpStrMatch, awstypes.HealthCheckTypeTcp:
+		if cfg.Port == nil && cfg.Type != awstypes.HealthCheckTypeTcp {
+			cfg.Port = aws.Int32(80)
+		}
+
+	case awstypes.HealthCheckTypeHttps, awstypes.HealthCheckTypeHttpsStrMatch:
+		if cfg.Port == nil {
+			cfg.Port = aws.Int32(443)
+		}
+
+	default:
+		return fmt.Errorf("health check type %q: %w", cfg.Type, ErrInvalidInput)
+	}
+
+	if port := aws.ToInt32(cfg.Port); port < 1 || port > 65535 {
+		return fmt.Errorf("port %d: %w", port, ErrInvalidInput)
+	}
+
+	if ip := aws.ToString(cfg.IPAddress); ip != "" && net.ParseIP(ip) == nil {
+		return fmt.Errorf("ip address %s: %w", ip, ErrInvalidInput)
+	}
+	if aws.ToString(cfg.IPAddress) == "" && aws.ToString(cfg.FullyQualifiedDomainName) == "" {
+		return fmt.Errorf("health check needs an ip address or domain name: %w", ErrInvalidInput)
+	}
+
+	if path := aws.ToString(cfg.ResourcePath); path != "" && !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("resource path %s: %w", path, ErrInvalidInput)
+	}
+
+	if cfg.Type == awstypes.HealthCheckTypeHttpStrMatch || cfg.Type == awstypes.HealthCheckTypeHttpsStrMatch {
+		if search := aws.ToString(cfg.SearchString); search == "" || len(search) > 255 {
+			return fmt.Errorf("search string: %w", ErrInvalidInput)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package route53
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"home-fern/internal/core"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+	"go.etcd.io/bbolt"
+)
+
+// AutoPtrTag is the hosted zone tag which, set to "true", makes changes to the zone's A and AAAA
+// records maintain the matching PTR records in the reverse hosted zones.
+const AutoPtrTag = "home-fern:auto-ptr"
+
+// ptrNames are the forward names which a change removed from, and added to, a reverse name.
+type ptrNames struct {
+	removed []string
+	added   []string
+	ttl     *int64
+}
+
+func autoPtr(hz *HostedZoneData) bool {
+	return slices.ContainsFunc(hz.Tags, func(tag core.ResourceTag) bool {
+		return tag.Key == AutoPtrTag && strings.EqualFold(tag.Value, "true")
+	})
+}
+
+// syncPtrRecords makes the PTR records of the reverse hosted zones follow the addresses a change
+// of hz added and removed, each reverse zone gets a change of its own. PTR records which point
+// to another name are left alone, and PTR changes the reverse zone rejects are skipped, both
+// reported in the comment of the change. It returns the reverse zones it changed.
+func syncPtrRecords(b *bbolt.Bucket, hz *HostedZoneData, ci *ChangeInfoData) ([]HostedZoneData, error) {
+
+	names := make(map[string]*ptrNames)
+	collect := func(rrsets []ResourceRecordSetData, added bool) {
+		for _, rrset := range rrsets {
+			if (rrset.Type != awstypes.RRTypeA && rrset.Type != awstypes.RRTypeAaaa) || strings.HasPrefix(rrset.Name, "*") {
+				continue
+			}
+			for _, rr := range rrset.ResourceRecords {
+				reverse, err := dns.ReverseAddr(aws.ToString(rr.Value))
+				if err != nil {
+					continue
+				}
+				entry, found := names[reverse]
+				if !found {
+					entry = &ptrNames{}
+					names[reverse] = entry
+				}
+				if added {
+					entry.added = append(entry.added, rrset.Name)
+					entry.ttl = rrset.TTL
+				} else {
+					entry.removed = append(entry.removed, rrset.Name)
+				}
+			}
+		}
+	}
+	collect(ci.Removed, false)
+	collect(ci.Added, true)
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	zones, err := readReverseZones(b, hz)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string][]ChangeData)
+	var conflicts []string
+
+	for _, reverse := range slices.Sorted(maps.Keys(names)) {
+
+		zone := reverseZoneFor(zones, reverse)
+		if zone == nil {
+			continue
+		}
+
+		header, err := convertToKey(zone.Name, reverse, awstypes.RRTypePtr, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var existing *ResourceRecordSetData
+		current := ""
+		if v := b.Get([]byte(RecordSetPrefix + strings.TrimPrefix(zone.Id, HostedZonePrefix) + header.rrkey)); v != nil {
+			existing = &ResourceRecordSetData{}
+			if err := json.Unmarshal(v, existing); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal record set: %w", err)
+			}
+			values := make([]string, 0, len(existing.ResourceRecords))
+			for _, rr := range existing.ResourceRecords {
+				values = append(values, strings.ToLower(dns.Fqdn(aws.ToString(rr.Value))))
+			}
+			current = strings.Join(values, " ")
+		}
+
+		entry := names[reverse]
+		target := current
+		if slices.Contains(entry.removed, current) && !slices.Contains(entry.added, current) {
+			target = ""
+		}
+		for _, name := range entry.added {
+			if target == "" {
+				target = name
+			} else if target != name {
+				conflicts = append(conflicts, fmt.Sprintf("%s points to %s, not %s", reverse, target, name))
+			}
+		}
+
+		if target == current {
+			continue
+		}
+
+		if target == "" {
+			changes[zone.Id] = append(changes[zone.Id], ChangeData{Action: awstypes.ChangeActionDelete, ResourceRecordSet: existing})
+			continue
+		}
+
+		ttl := entry.ttl
+		if ttl == nil {
+			ttl = aws.Int64(300)
+		}
+		changes[zone.Id] = append(changes[zone.Id], ChangeData{
+			Action: awstypes.ChangeActionUpsert,
+			ResourceRecordSet: &ResourceRecordSetData{
+				Name:            reverse,
+				Type:            awstypes.RRTypePtr,
+				TTL:             ttl,
+				ResourceRecords: []awstypes.ResourceRecord{{Value: aws.String(target)}},
+			},
+		})
+	}
+
+	var result []HostedZoneData
+	for _, zone := range zones {
+		if len(changes[zone.Id]) == 0 {
+			continue
+		}
+
+		records, err := zoneRecordSets(b, zone.Id)
+		if err != nil {
+			return nil, err
+		}
+		var valid []ChangeData
+		for _, change := range changes[zone.Id] {
+			if err := validateChangeBatch(&zone, records, []ChangeData{change}); err != nil {
+				conflicts = append(conflicts, err.Error())
+				continue
+			}
+			valid = append(valid, change)
+		}
+		if len(valid) == 0 {
+			continue
+		}
+
+		zoneCi := ChangeInfoData{
+			Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+			Status:      awstypes.ChangeStatusInsync,
+			SubmittedAt: ci.SubmittedAt,
+			Comment:     fmt.Sprintf("PTR records of %s", strings.TrimPrefix(ci.Id, ChangeInfoPrefix)),
+		}
+		if err := applyRecordChanges(b, &zone, valid, &zoneCi); err != nil {
+			return nil, err
+		}
+		result = append(result, zone)
+	}
+
+	if len(conflicts) > 0 {
+		comment := "PTR conflicts: " + strings.Join(conflicts, "; ")
+		if ci.Comment != "" {
+			comment = ci.Comment + " (" + comment + ")"
+		}
+		ci.Comment = comment
+		if err := putJson(b, ci.Id, ci); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// readReverseZones returns the in-addr.arpa and ip6.arpa hosted zones which are private when
+// hz is, longest name first.
+func readReverseZones(b *bbolt.Bucket, hz *HostedZoneData) ([]HostedZoneData, error) {
+	var result []HostedZoneData
+
+	c := b.Cursor()
+	prefix := []byte(HostedZonePrefix)
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var zone HostedZoneData
+		if err := json.Unmarshal(v, &zone); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal hosted zone: %w", err)
+		}
+		if zone.Id == hz.Id || zone.Config.PrivateZone != hz.Config.PrivateZone {
+			continue
+		}
+		if dns.IsSubDomain("in-addr.arpa.", zone.Name) || dns.IsSubDomain("ip6.arpa.", zone.Name) {
+			result = append(result, zone)
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b HostedZoneData) int {
+		return dns.CountLabel(b.Name) - dns.CountLabel(a.Name)
+	})
+
+	return result, nil
+}
+
+// reverseZoneFor returns the most specific of zones containing name.
+func reverseZoneFor(zones []HostedZoneData, name string) *HostedZoneData {
+	for i := range zones {
+		if dns.IsSubDomain(zones[i].Name, name) {
+			return &zones[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package route53
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// queryLogVersion is the Route53 query log format the lines are written in.
+const queryLogVersion = "1.0"
+
+// queryLogger keeps the query log files open between queries, and the query logging configs
+// of the hosted zones until they change.
+type queryLogger struct {
+	mu      sync.Mutex
+	files   map[string]*os.File
+	configs map[string][]QueryLoggingConfigData
+}
+
+func newQueryLogger() *queryLogger {
+	return &queryLogger{files: make(map[string]*os.File)}
+}
+
+// zoneConfigs returns the query logging configs of a hosted zone, reading all of them with
+// find when they aren't cached.
+func (q *queryLogger) zoneConfigs(zoneId string, find func() ([]QueryLoggingConfigData, error)) ([]QueryLoggingConfigData, error) {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.configs == nil {
+		configs, err := find()
+		if err != nil {
+			return nil, err
+		}
+		q.configs = make(map[string][]QueryLoggingConfigData)
+		for _, cfg := range configs {
+			q.configs[cfg.HostedZoneId] = append(q.configs[cfg.HostedZoneId], cfg)
+		}
+	}
+
+	return q.configs[zoneId], nil
+}
+
+// configsChanged drops the cached query logging configs, they're read again at the next query.
+func (q *queryLogger) configsChanged() {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.configs = nil
+}
+
+func (q *queryLogger) write(path string, line string) error {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	f, found := q.files[path]
+	if !found {
+		var err error
+		f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			return fmt.Errorf("failed to open query log %s: %w", path, err)
+		}
+		q.files[path] = f
+	}
+
+	_, err := f.WriteString(line + "\n")
+	return err
+}
+
+// logQuery writes one line per query to the log groups of the zone's query logging configs,
+// using the Route53 format: version, time, zone, name, type, rcode, protocol, edge, resolver, subnet.
+func (s *Service) logQuery(query *DnsQuery, answer *DnsAnswer) {
+
+	if answer.Zone == nil {
+		return
+	}
+
+	zoneId := strings.TrimPrefix(answer.Zone.Id, HostedZonePrefix)
+
+	configs, err := s.queryLog.zoneConfigs(zoneId, s.dataStore.findQueryLoggingConfigs)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+
+	if len(configs) == 0 {
+		return
+	}
+
+	subnet := "-"
+	if query.ClientSubnet != nil {
+		subnet = query.ClientSubnet.String()
+	}
+
+	resolver := "-"
+	if query.ClientIP != nil {
+		resolver = query.ClientIP.String()
+	}
+
+	line := strings.Join([]string{
+		queryLogVersion,
+		time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		zoneId,
+		strings.TrimSuffix(query.Name, "."),
+		string(query.Type),
+		dns.RcodeToString[answer.Rcode],
+		query.Protocol,
+		"home-fern",
+		resolver,
+		subnet,
+	}, " ")
+
+	for _, cfg := range configs {
+
+		name, _ := logGroupName(cfg.CloudWatchLogsLogGroupArn)
+		group, found := s.logGroups[name]
+		if !found {
+			continue
+		}
+
+		if group.Path == "" {
+			log.Printf("%s: %s", group.Name, line)
+			continue
+		}
+
+		if err := s.queryLog.write(group.Path, line); err != nil {
+			log.Println("Error:", err)
+		}
+	}
+}
+
+// logGroupName returns the name in a log group ARN, arn:aws:logs:region:account:log-group:name[:*].
+func logGroupName(arn string) (string, bool) {
+
+	_, name, found := strings.Cut(arn, ":log-group:")
+	if !strings.HasPrefix(arn, "arn:") || !found || name == "" {
+		return "", false
+	}
+
+	return strings.TrimSuffix(name, ":*"), true
+}
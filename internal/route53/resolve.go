@@ -0,0 +1,446 @@
+package route53
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+// maxCnameChain limits how many CNAMEs are followed inside home-fern zones.
+const maxCnameChain = 8
+
+type DnsQuery struct {
+	Name         string
+	Type         awstypes.RRType
+	ClientIP     net.IP
+	ClientSubnet *net.IPNet
+	Protocol     string
+}
+
+type DnsAnswer struct {
+	Rcode         int
+	Authoritative bool
+	Zone          *HostedZoneData
+	Answer        []ResourceRecordSetData
+	Authority     []ResourceRecordSetData
+	Additional    []ResourceRecordSetData
+}
+
+// zoneRecords holds a hosted zone's record sets grouped by owner name.
+type zoneRecords struct {
+	zone  *HostedZoneData
+	names map[string][]ResourceRecordSetData
+	// nodes holds the names which exist in the zone, with record sets or, as empty
+	// non-terminals, with names below them
+	nodes map[string]bool
+}
+
+// Resolve answers a query from the records of hz, the hosted zone findVisibleZone found for it,
+// REFUSED when there is none. It is the lookup used by the DNS frontend; Rcode carries the
+// dns.Rcode* value.
+func (s *Service) Resolve(query *DnsQuery, hz *HostedZoneData) (*DnsAnswer, error) {
+
+	name := normalizeDnsName(query.Name)
+
+	if hz == nil {
+		return &DnsAnswer{Rcode: dns.RcodeRefused}, nil
+	}
+
+	zr, err := s.loadHostedZoneRecords(hz)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DnsAnswer{
+		Rcode:         dns.RcodeSuccess,
+		Authoritative: true,
+		Zone:          zr.zone,
+	}
+
+	err = s.resolveInZone(zr, name, query, result, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Service) resolveInZone(
+	zr *zoneRecords, name string, query *DnsQuery, result *DnsAnswer, depth int) error {
+
+	if cut := zr.findDelegation(name); cut != nil {
+
+		// below a zone cut we only refer the client to the child name servers
+		result.Authoritative = depth > 0 && result.Authoritative
+		result.Authority = append(result.Authority, *cut)
+		result.Additional = append(result.Additional, zr.glue(cut)...)
+		return nil
+	}
+
+	// an empty non-terminal exists without records, it gets a NODATA answer
+	rrsets := zr.names[name]
+	if !zr.nodes[name] {
+
+		var found bool
+		rrsets, found = zr.findWildcard(name)
+		if !found {
+			if depth == 0 {
+				result.Rcode = dns.RcodeNameError
+			}
+			result.Authority = append(result.Authority, s.negativeSoa(zr))
+			return nil
+		}
+	}
+
+	// record sets with a routing policy share name and type, the policy picks the answers
+	var types []awstypes.RRType
+	byType := make(map[awstypes.RRType][]ResourceRecordSetData)
+
+	for _, rrset := range rrsets {
+
+		if rrset.AliasTarget != nil {
+			if query.Type != awstypes.RRType("ANY") && rrset.Type != query.Type && rrset.Type != awstypes.RRTypeCname {
+				continue
+			}
+
+			expanded, err := s.resolveAlias(rrset, query, 0)
+			if err != nil {
+				return err
+			}
+
+			// targets outside of home-fern zones can't be answered
+			if expanded == nil {
+				continue
+			}
+			rrset = *expanded
+		}
+
+		if _, found := byType[rrset.Type]; !found {
+			types = append(types, rrset.Type)
+		}
+		byType[rrset.Type] = append(byType[rrset.Type], rrset)
+	}
+
+	var answers []ResourceRecordSetData
+	var cname *ResourceRecordSetData
+
+	for _, rrtype := range types {
+		if query.Type == awstypes.RRType("ANY") || rrtype == query.Type {
+			answers = append(answers, s.routeRecordSets(byType[rrtype], query)...)
+		}
+	}
+
+	if selected := s.routeRecordSets(byType[awstypes.RRTypeCname], query); len(selected) > 0 {
+		cname = &selected[0]
+	}
+
+	if len(answers) > 0 {
+		result.Answer = append(result.Answer, answers...)
+		return nil
+	}
+
+	if cname == nil {
+		result.Authority = append(result.Authority, s.negativeSoa(zr))
+		return nil
+	}
+
+	result.Answer = append(result.Answer, *cname)
+
+	if depth >= maxCnameChain || len(cname.ResourceRecords) == 0 {
+		return nil
+	}
+
+	target := normalizeDnsName(aws.ToString(cname.ResourceRecords[0].Value))
+
+	next := zr
+	if !isSubdomain(target, zr.zone.Name) {
+
+		var err error
+		next, err = s.loadZoneRecords(target, query.ClientIP)
+		if err != nil {
+			return err
+		}
+
+		// targets outside of home-fern are left to the client's resolver
+		if next == nil {
+			return nil
+		}
+	}
+
+	return s.resolveInZone(next, target, query, result, depth+1)
+}
+
+// loadZoneRecords returns the records of the most specific hosted zone containing name which
+// the client can see, or nil when home-fern is not authoritative for the name. A private zone
+// hides a public zone of the same name from clients in its VPCs.
+func (s *Service) loadZoneRecords(name string, clientIP net.IP) (*zoneRecords, error) {
+
+	match, err := s.findVisibleZone(name, clientIP)
+	if err != nil || match == nil {
+		return nil, err
+	}
+
+	return s.loadHostedZoneRecords(match)
+}
+
+// findVisibleZone returns the hosted zone loadZoneRecords answers from, or nil.
+func (s *Service) findVisibleZone(name string, clientIP net.IP) (*HostedZoneData, error) {
+
+	zones, err := s.dataStore.findHostedZones(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *HostedZoneData
+	for i := range zones {
+
+		if !isSubdomain(name, zones[i].Name) || !s.zoneVisible(&zones[i], clientIP) {
+			continue
+		}
+
+		if match == nil || len(zones[i].Name) > len(match.Name) ||
+			(zones[i].Name == match.Name && zones[i].Config.PrivateZone) {
+			match = &zones[i]
+		}
+	}
+
+	return match, nil
+}
+
+func (s *Service) loadHostedZoneRecords(hz *HostedZoneData) (*zoneRecords, error) {
+
+	records, err := s.dataStore.getResourceRecordSets(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newZoneRecords(hz, records), nil
+}
+
+// newZoneRecords groups the record sets of hz by name.
+func newZoneRecords(hz *HostedZoneData, records []ResourceRecordSetData) *zoneRecords {
+
+	result := zoneRecords{
+		zone:  hz,
+		names: make(map[string][]ResourceRecordSetData),
+		nodes: make(map[string]bool),
+	}
+
+	for _, rr := range records {
+		owner := normalizeDnsName(rr.Name)
+		result.names[owner] = append(result.names[owner], rr)
+
+		for node := owner; !result.nodes[node] && isSubdomain(node, hz.Name); node = parentDnsName(node) {
+			result.nodes[node] = true
+		}
+	}
+
+	return &result
+}
+
+// resolveAlias returns the alias record set carrying the records of its target, or nil when
+// the target isn't in a home-fern hosted zone. The alias keeps its own name and routing policy.
+func (s *Service) resolveAlias(
+	alias ResourceRecordSetData, query *DnsQuery, depth int) (*ResourceRecordSetData, error) {
+
+	targets, _, err := s.aliasTargets(&alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []ResourceRecordSetData
+	for _, target := range targets {
+
+		if target.AliasTarget == nil {
+			candidates = append(candidates, target)
+			continue
+		}
+
+		if depth >= maxCnameChain {
+			continue
+		}
+
+		expanded, err := s.resolveAlias(target, query, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if expanded != nil {
+			candidates = append(candidates, *expanded)
+		}
+	}
+
+	selected := s.routeRecordSets(candidates, query)
+	if len(selected) == 0 {
+		return nil, nil
+	}
+
+	result := alias
+	result.TTL = selected[0].TTL
+	result.ResourceRecords = nil
+	for _, rrset := range selected {
+		result.ResourceRecords = append(result.ResourceRecords, rrset.ResourceRecords...)
+	}
+
+	return &result, nil
+}
+
+// aliasTargets returns the record sets an alias points at; local is false when
+// the target hosted zone isn't one of ours.
+func (s *Service) aliasTargets(alias *ResourceRecordSetData) ([]ResourceRecordSetData, bool, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(alias.AliasTarget.HostedZoneId))
+	if err != nil {
+		if errors.Is(err, ErrNoSuchHostedZone) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	zr, err := s.loadHostedZoneRecords(hz)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var result []ResourceRecordSetData
+	for _, rrset := range zr.names[normalizeDnsName(aws.ToString(alias.AliasTarget.DNSName))] {
+		if rrset.Type == alias.Type {
+			result = append(result, rrset)
+		}
+	}
+
+	return result, true, nil
+}
+
+// findDelegation returns the NS record set of a zone cut between the apex and name.
+func (zr *zoneRecords) findDelegation(name string) *ResourceRecordSetData {
+
+	labels := dns.SplitDomainName(strings.TrimSuffix(name, zr.zone.Name))
+
+	// walk from just below the apex down to name itself
+	for i := len(labels) - 1; i >= 0; i-- {
+		owner := strings.Join(labels[i:], ".") + "." + zr.zone.Name
+		for _, rrset := range zr.names[owner] {
+			if rrset.Type == awstypes.RRTypeNs {
+				return &rrset
+			}
+		}
+	}
+
+	return nil
+}
+
+// findWildcard looks for "*" at the closest encloser of name, as in RFC 4592. Names which exist,
+// empty non-terminals too, aren't matched by a wildcard.
+func (zr *zoneRecords) findWildcard(name string) ([]ResourceRecordSetData, bool) {
+
+	if zr.nodes[name] {
+		return nil, false
+	}
+
+	encloser := parentDnsName(name)
+	for !zr.nodes[encloser] {
+		if !isSubdomain(encloser, zr.zone.Name) {
+			return nil, false
+		}
+		encloser = parentDnsName(encloser)
+	}
+
+	rrsets, found := zr.names["*."+encloser]
+	if !found {
+		return nil, false
+	}
+
+	result := make([]ResourceRecordSetData, 0, len(rrsets))
+	for _, rrset := range rrsets {
+		rrset.Name = name
+		result = append(result, rrset)
+	}
+	return result, true
+}
+
+func (zr *zoneRecords) glue(ns *ResourceRecordSetData) []ResourceRecordSetData {
+
+	var result []ResourceRecordSetData
+	for _, rr := range ns.ResourceRecords {
+
+		target := normalizeDnsName(aws.ToString(rr.Value))
+		for _, rrset := range zr.names[target] {
+			if rrset.Type == awstypes.RRTypeA || rrset.Type == awstypes.RRTypeAaaa {
+				result = append(result, rrset)
+			}
+		}
+	}
+
+	return result
+}
+
+// negativeSoa is the zone SOA with the TTL lowered to the negative caching TTL (RFC 2308).
+func (s *Service) negativeSoa(zr *zoneRecords) ResourceRecordSetData {
+
+	result := ResourceRecordSetData{
+		Name:            zr.zone.Name,
+		Type:            awstypes.RRTypeSoa,
+		TTL:             aws.Int64(900),
+		ResourceRecords: []awstypes.ResourceRecord{{Value: aws.String(s.soaDefault)}},
+	}
+
+	for _, rrset := range zr.names[zr.zone.Name] {
+		if rrset.Type == awstypes.RRTypeSoa && len(rrset.ResourceRecords) > 0 {
+			result = rrset
+			break
+		}
+	}
+
+	soa, err := parseSoa(zr.zone.Name, aws.ToString(result.ResourceRecords[0].Value))
+	if err == nil && int64(soa.Minttl) < aws.ToInt64(result.TTL) {
+		result.TTL = aws.Int64(int64(soa.Minttl))
+	}
+
+	return result
+}
+
+func parseSoa(zoneName string, value string) (*dns.SOA, error) {
+
+	rr, err := dns.NewRR(fmt.Sprintf("%s 0 IN SOA %s", zoneName, value))
+	if err != nil {
+		return nil, err
+	}
+
+	soa, ok := rr.(*dns.SOA)
+	if !ok {
+		return nil, fmt.Errorf("not an SOA record: %s", value)
+	}
+
+	return soa, nil
+}
+
+// normalizeDnsName lower cases name, makes it fully qualified and
+// turns the Route53 octal escape for "*" back into a wildcard label.
+func normalizeDnsName(name string) string {
+
+	result := strings.ToLower(dns.Fqdn(name))
+	if strings.HasPrefix(result, "\\052.") {
+		result = "*" + strings.TrimPrefix(result, "\\052")
+	}
+
+	return result
+}
+
+func parentDnsName(name string) string {
+
+	i, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+
+	return name[i:]
+}
+
+func isSubdomain(name string, zone string) bool {
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
@@ -0,0 +1,79 @@
+package route53
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+func TestResolveInZone(t *testing.T) {
+	rrset := func(name string, rrtype awstypes.RRType, values ...string) ResourceRecordSetData {
+		result := ResourceRecordSetData{Name: name, Type: rrtype, TTL: aws.Int64(300)}
+		for _, value := range values {
+			result.ResourceRecords = append(result.ResourceRecords, awstypes.ResourceRecord{Value: aws.String(value)})
+		}
+		return result
+	}
+
+	zone := &HostedZoneData{Id: "/hostedzone/Z1", Name: "example.com."}
+	records := []ResourceRecordSetData{
+		rrset("example.com.", awstypes.RRTypeSoa, "ns-1.example.com. admin.example.com. 1 3600 180 604800 1800"),
+		rrset("example.com.", awstypes.RRTypeNs, "ns-1.example.com."),
+		rrset("a.b.example.com.", awstypes.RRTypeA, "192.0.2.1"),
+		rrset("z.y.example.com.", awstypes.RRTypeA, "192.0.2.2"),
+	}
+	wildcard := rrset("*.example.com.", awstypes.RRTypeA, "192.0.2.9")
+
+	tests := []struct {
+		name     string
+		wildcard bool
+		query    string
+		rcode    int
+		answer   string
+	}{
+		{"name", false, "a.b.example.com.", dns.RcodeSuccess, "192.0.2.1"},
+		{"empty non-terminal", false, "b.example.com.", dns.RcodeSuccess, ""},
+		{"missing name", false, "c.example.com.", dns.RcodeNameError, ""},
+		{"below a name", false, "x.a.b.example.com.", dns.RcodeNameError, ""},
+		{"wildcard", true, "c.example.com.", dns.RcodeSuccess, "192.0.2.9"},
+		{"wildcard below a missing name", true, "x.c.example.com.", dns.RcodeSuccess, "192.0.2.9"},
+		{"wildcard doesn't match an empty non-terminal", true, "b.example.com.", dns.RcodeSuccess, ""},
+		{"wildcard stops at an empty non-terminal", true, "x.y.example.com.", dns.RcodeNameError, ""},
+		{"wildcard stops at a name", true, "x.a.b.example.com.", dns.RcodeNameError, ""},
+	}
+
+	s := &Service{soaDefault: "ns-1.example.com. admin.example.com. 1 7200 900 1209600 86400"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zr := newZoneRecords(zone, records)
+			if tt.wildcard {
+				zr = newZoneRecords(zone, append(records[:len(records):len(records)], wildcard))
+			}
+
+			result := &DnsAnswer{Authoritative: true, Zone: zone}
+			query := &DnsQuery{Name: tt.query, Type: awstypes.RRTypeA}
+			if err := s.resolveInZone(zr, tt.query, query, result, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Rcode != tt.rcode {
+				t.Errorf("rcode = %s, want %s", dns.RcodeToString[result.Rcode], dns.RcodeToString[tt.rcode])
+			}
+
+			if tt.answer == "" {
+				if len(result.Answer) > 0 || len(result.Authority) != 1 || result.Authority[0].Type != awstypes.RRTypeSoa {
+					t.Errorf("answer = %v, authority = %v, want the SOA only", result.Answer, result.Authority)
+				}
+				return
+			}
+
+			if len(result.Answer) != 1 || aws.ToString(result.Answer[0].ResourceRecords[0].Value) != tt.answer ||
+				result.Answer[0].Name != tt.query {
+				t.Errorf("answer = %v, want %s %s", result.Answer, tt.query, tt.answer)
+			}
+		})
+	}
+}
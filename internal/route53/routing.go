@@ -0,0 +1,155 @@
+package route53
+
+import (
+	"log"
+	"math/rand/v2"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// maxMultiValueAnswers is how many records a multivalue answer returns at most.
+const maxMultiValueAnswers = 8
+
+// routeRecordSets selects the record sets answering a query from the record sets
+// of one name and type, following the routing policy they were created with.
+func (s *Service) routeRecordSets(rrsets []ResourceRecordSetData, query *DnsQuery) []ResourceRecordSetData {
+
+	if len(rrsets) == 0 || rrsets[0].SetIdentifier == nil {
+		return rrsets
+	}
+
+	policy := rrsets[0]
+	switch {
+	case policy.Failover != "":
+		return s.routeFailover(rrsets)
+	case aws.ToBool(policy.MultiValueAnswer):
+		return s.routeMultiValue(rrsets)
+	case policy.Weight != nil:
+		return s.routeWeighted(rrsets)
+	case policy.CidrRoutingConfig != nil:
+		return s.routeCidr(rrsets, query)
+	}
+
+	// latency and geo policies need data home-fern doesn't have, any healthy record set will do
+	return s.healthyRecordSets(rrsets)[:1]
+}
+
+// routeFailover answers with the primary while it's healthy. When both are unhealthy
+// Route53 answers with the primary.
+func (s *Service) routeFailover(rrsets []ResourceRecordSetData) []ResourceRecordSetData {
+
+	var primary, secondary *ResourceRecordSetData
+	for i := range rrsets {
+		switch rrsets[i].Failover {
+		case awstypes.ResourceRecordSetFailoverPrimary:
+			primary = &rrsets[i]
+		case awstypes.ResourceRecordSetFailoverSecondary:
+			secondary = &rrsets[i]
+		}
+	}
+
+	switch {
+	case primary != nil && s.recordSetHealthy(primary):
+		return []ResourceRecordSetData{*primary}
+	case secondary != nil && s.recordSetHealthy(secondary):
+		return []ResourceRecordSetData{*secondary}
+	case primary != nil:
+		return []ResourceRecordSetData{*primary}
+	case secondary != nil:
+		return []ResourceRecordSetData{*secondary}
+	}
+
+	return nil
+}
+
+// routeWeighted picks one healthy record set with a probability of its share of the total weight.
+// Record sets with weight 0 are only used when no other record set is available.
+func (s *Service) routeWeighted(rrsets []ResourceRecordSetData) []ResourceRecordSetData {
+
+	candidates := s.healthyRecordSets(rrsets)
+
+	var total int64
+	for _, rrset := range candidates {
+		total += aws.ToInt64(rrset.Weight)
+	}
+
+	if total == 0 {
+		return []ResourceRecordSetData{candidates[rand.IntN(len(candidates))]}
+	}
+
+	pick := rand.Int64N(total)
+	for _, rrset := range candidates {
+		pick -= aws.ToInt64(rrset.Weight)
+		if pick < 0 {
+			return []ResourceRecordSetData{rrset}
+		}
+	}
+
+	return candidates[:1]
+}
+
+// routeMultiValue answers with up to eight randomly chosen healthy record sets.
+func (s *Service) routeMultiValue(rrsets []ResourceRecordSetData) []ResourceRecordSetData {
+
+	result := s.healthyRecordSets(rrsets)
+	rand.Shuffle(len(result), func(i, j int) {
+		result[i], result[j] = result[j], result[i]
+	})
+
+	return result[:min(len(result), maxMultiValueAnswers)]
+}
+
+// healthyRecordSets drops record sets whose health check failed. Like Route53, when
+// every record set is unhealthy they are all considered healthy.
+func (s *Service) healthyRecordSets(rrsets []ResourceRecordSetData) []ResourceRecordSetData {
+
+	var result []ResourceRecordSetData
+	for i := range rrsets {
+		if s.recordSetHealthy(&rrsets[i]) {
+			result = append(result, rrsets[i])
+		}
+	}
+
+	if len(result) == 0 {
+		return append(result, rrsets...)
+	}
+
+	return result
+}
+
+// recordSetHealthy is true for record sets without a health check. Aliases which evaluate
+// target health also need a healthy record set at the target.
+func (s *Service) recordSetHealthy(rrset *ResourceRecordSetData) bool {
+	return s.recordSetHealthyAt(rrset, 0)
+}
+
+func (s *Service) recordSetHealthyAt(rrset *ResourceRecordSetData, depth int) bool {
+
+	if rrset.HealthCheckId != nil && !s.isHealthy(*rrset.HealthCheckId) {
+		return false
+	}
+
+	if rrset.AliasTarget == nil || !rrset.AliasTarget.EvaluateTargetHealth || depth >= maxCnameChain {
+		return true
+	}
+
+	targets, local, err := s.aliasTargets(rrset)
+	if err != nil {
+		log.Println("Error:", err)
+		return true
+	}
+
+	// targets outside of home-fern are assumed healthy
+	if !local {
+		return true
+	}
+
+	for i := range targets {
+		if s.recordSetHealthyAt(&targets[i], depth+1) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,38 +1,217 @@
 package route53
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
 	"home-fern/internal/core"
 	"home-fern/internal/datastore"
+	"home-fern/internal/kms"
 	"io"
 	"log"
+	"maps"
+	"math"
+	"net"
+	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	aws53 "github.com/aws/aws-sdk-go-v2/service/route53"
 	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
 )
 
 type Service struct {
-	dataStore  *dataStore
-	soaDefault string
-	nsRecords  []string
+	dataStore     *dataStore
+	soaDefault    string
+	nsRecords     []string
+	tsigKeys      map[string]core.DnsTsigKey
+	zoneConfigs   map[string]core.DnsZoneConfig
+	nsGroups      []core.DnsDelegationSet
+	vpcs          map[string]core.DnsVpc
+	logGroups     map[string]core.DnsLogGroup
+	queryLog      *queryLogger
+	zoneListeners []func(hz *HostedZoneData)
+	health        *healthChecker
+	kms           *kms.Service
+	signers       *signerCache
 }
 
-func NewService(dns *core.DnsDefaults, ds *datastore.Datastore) *Service {
+func NewService(dns *core.DnsDefaults, ds *datastore.Datastore, kmsService *kms.Service) *Service {
 	dataStore := newDataStore(ds)
 
 	result := Service{
-		dataStore:  dataStore,
-		soaDefault: dns.Soa,
-		nsRecords:  dns.NameServers,
+		dataStore:   dataStore,
+		soaDefault:  dns.Soa,
+		nsRecords:   dns.NameServers,
+		tsigKeys:    make(map[string]core.DnsTsigKey),
+		zoneConfigs: make(map[string]core.DnsZoneConfig),
+		nsGroups:    dns.DelegationSets,
+		vpcs:        make(map[string]core.DnsVpc),
+		logGroups:   make(map[string]core.DnsLogGroup),
+		queryLog:    newQueryLogger(),
+		health:      newHealthChecker(),
+		kms:         kmsService,
+		signers:     newSignerCache(),
+	}
+
+	for _, key := range dns.TsigKeys {
+		result.tsigKeys[normalizeDnsName(key.Name)] = key
+	}
+
+	for _, zone := range dns.Zones {
+		result.zoneConfigs[normalizeDnsName(zone.Name)] = zone
+	}
+
+	for _, vpc := range dns.Vpcs {
+		result.vpcs[vpc.Id] = vpc
+	}
+
+	for _, group := range dns.LogGroups {
+		result.logGroups[group.Name] = group
+	}
+
+	if moved, err := dataStore.reindex(); err != nil {
+		log.Println("Error:", err)
+	} else if moved > 0 {
+		log.Println("Reindexed", moved, "record sets")
+	}
+
+	if wrapped, err := result.wrapZoneSigningKeys(); err != nil {
+		log.Println("Error:", err)
+	} else if wrapped > 0 {
+		log.Println("Wrapped", wrapped, "zone signing keys")
 	}
 
 	return &result
 }
 
+func (s *Service) ActivateKeySigningKey(
+	request *aws53.ActivateKeySigningKeyInput) (*aws53.ActivateKeySigningKeyOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	ksk, err := s.dataStore.getKeySigningKey(hz.Id, aws.ToString(request.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err = s.keySigningKey(hz.Name, ksk); err != nil {
+		return nil, err
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Change is complete.",
+	}
+
+	ksk.Status = kskActive
+	ksk.LastModifiedDate = time.Now().UTC()
+
+	err = s.dataStore.putKeySigningKey(ksk, true, &ci)
+	if err != nil {
+		return nil, err
+	}
+	s.signers.invalidate(hz.Id)
+
+	return &aws53.ActivateKeySigningKeyOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}, nil
+}
+
+func (s *Service) AssociateVPCWithHostedZone(
+	request *aws53.AssociateVPCWithHostedZoneInput) (*aws53.AssociateVPCWithHostedZoneOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	vpc, err := s.checkVPC(request.VPC)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hz.Config.PrivateZone {
+		return nil, ErrPublicZoneVPCAssociation
+	}
+
+	zones, err := s.dataStore.findHostedZones(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range zones {
+		if zones[i].Id != hz.Id && zones[i].Name == hz.Name && hasVPC(&zones[i], vpc) {
+			return nil, ErrConflictingDomainExists
+		}
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     aws.ToString(request.Comment),
+	}
+
+	_, err = s.dataStore.updateHostedZone(hz.Id, func(hz *HostedZoneData) error {
+		if !hasVPC(hz, vpc) {
+			hz.VPCs = append(hz.VPCs, vpc)
+		}
+		return nil
+	}, &ci)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.AssociateVPCWithHostedZoneOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}, nil
+}
+
+func (s *Service) ChangeCidrCollection(
+	request *ChangeCidrCollectionRequest) (*aws53.ChangeCidrCollectionOutput, error) {
+
+	coll, err := s.dataStore.getCidrCollection(request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.CollectionVersion != nil && *request.CollectionVersion != coll.Version {
+		return nil, ErrCidrCollectionVersionMismatch
+	}
+
+	locations, removed, err := applyCidrChanges(coll, request.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := *coll
+	changed.Locations = locations
+	changed.Version++
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	err = s.dataStore.updateCidrCollection(&changed, coll.Version, removed, &ci)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.ChangeCidrCollectionOutput{Id: aws.String(ci.Id)}, nil
+}
+
 func (s *Service) ChangeResourceRecordSets(
 	request *ChangeResourceRecordSetsRequest) (*aws53.ChangeResourceRecordSetsOutput, error) {
 
@@ -41,6 +220,20 @@ func (s *Service) ChangeResourceRecordSets(
 		return nil, err
 	}
 
+	// only the traffic policy code sets the instance owning a record set
+	for _, change := range request.ChangeBatch.Changes {
+		if rrset := change.ResourceRecordSet; rrset != nil && rrset.TrafficPolicyInstanceId != nil {
+			return nil, &InvalidChangeBatchError{Messages: []string{fmt.Sprintf("Tried to change resource record set %s "+
+				"with a TrafficPolicyInstanceId, use the traffic policy instance instead",
+				describeRecordSet(strings.ToLower(dns.Fqdn(rrset.Name)), rrset))}}
+		}
+	}
+
+	err = s.checkCidrRouting(request.ChangeBatch.Changes)
+	if err != nil {
+		return nil, err
+	}
+
 	ci := ChangeInfoData{
 		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
 		Status:      awstypes.ChangeStatusInsync,
@@ -48,11 +241,19 @@ func (s *Service) ChangeResourceRecordSets(
 		Comment:     aws.ToString(request.ChangeBatch.Comment),
 	}
 
-	err = s.dataStore.putRecordSets(hz, request.ChangeBatch.Changes, &ci)
+	// the batch is validated against the record sets it's applied to
+	reverseZones, err := s.dataStore.changeRecordSets(hz, &ci, func(records []ResourceRecordSetData) ([]ChangeData, error) {
+		return request.ChangeBatch.Changes, validateChangeBatch(hz, records, request.ChangeBatch.Changes)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	s.zoneChanged(hz)
+	for i := range reverseZones {
+		s.zoneChanged(&reverseZones[i])
+	}
+
 	result := aws53.ChangeResourceRecordSetsOutput{
 		ChangeInfo: ci.toChangeInfo(),
 	}
@@ -63,41 +264,112 @@ func (s *Service) ChangeResourceRecordSets(
 func (s *Service) ChangeTagsForResource(
 	request *aws53.ChangeTagsForResourceInput) (*aws53.ChangeTagsForResourceOutput, error) {
 
-	if request.ResourceType != awstypes.TagResourceTypeHostedzone {
+	switch request.ResourceType {
+	case awstypes.TagResourceTypeHostedzone:
+		_, err := s.dataStore.updateHostedZone(aws.ToString(request.ResourceId), func(hz *HostedZoneData) error {
+			hz.Tags = changeTags(hz.Tags, request)
+			return nil
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+	case awstypes.TagResourceTypeHealthcheck:
+		hc, err := s.dataStore.getHealthCheck(aws.ToString(request.ResourceId))
+		if err != nil {
+			return nil, err
+		}
+
+		hc.Tags = changeTags(hc.Tags, request)
+
+		err = s.dataStore.putHealthCheck(hc, true)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, ErrInvalidInput
+	}
+
+	return &aws53.ChangeTagsForResourceOutput{}, nil
+}
+
+func (s *Service) CreateCidrCollection(request *aws53.CreateCidrCollectionInput) (*CidrCollectionData, error) {
+
+	name := aws.ToString(request.Name)
+	callerReference := aws.ToString(request.CallerReference)
+	if !cidrCollectionName.MatchString(name) || callerReference == "" || len(callerReference) > 64 {
 		return nil, ErrInvalidInput
 	}
 
-	hz, err := s.dataStore.getHostedZone(aws.ToString(request.ResourceId))
+	collections, err := s.dataStore.findCidrCollections()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, tag := range request.RemoveTagKeys {
-
-		for i, mytag := range hz.Tags {
-			if mytag.Key == tag {
-				hz.Tags = slices.Delete(hz.Tags, i, i+1)
-				break
-			}
+	// a retried request gets the collection created the first time
+	for i := range collections {
+		if collections[i].Name == name && collections[i].CallerReference == callerReference {
+			return &collections[i], nil
 		}
 	}
 
-	for _, tag := range request.AddTags {
+	id := newResourceId()
+	coll := CidrCollectionData{
+		Arn:             "arn:aws:route53:::cidrcollection/" + id,
+		Id:              id,
+		Name:            name,
+		Version:         1,
+		CallerReference: callerReference,
+	}
 
-		newtag := core.ResourceTag{
-			Key:   aws.ToString(tag.Key),
-			Value: aws.ToString(tag.Value),
+	err = s.dataStore.putCidrCollection(&coll)
+	if err != nil {
+		return nil, err
+	}
+
+	return &coll, nil
+}
+
+func (s *Service) CreateHealthCheck(request *CreateHealthCheckRequest) (*HealthCheckData, error) {
+
+	if request.CallerReference == "" {
+		return nil, ErrInvalidInput
+	}
+
+	cfg := request.HealthCheckConfig
+	if err := normalizeHealthCheckConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	checks, err := s.dataStore.findHealthChecks()
+	if err != nil {
+		return nil, err
+	}
+
+	// a retried request with the same caller reference gets the existing health check
+	for _, hc := range checks {
+		if hc.CallerReference == request.CallerReference {
+			if !reflect.DeepEqual(hc.HealthCheckConfig, cfg) {
+				return nil, ErrHealthCheckAlreadyExists
+			}
+			return &hc, nil
 		}
+	}
 
-		hz.Tags = append(hz.Tags, newtag)
+	hc := HealthCheckData{
+		Id:                 newResourceId(),
+		CallerReference:    request.CallerReference,
+		HealthCheckConfig:  cfg,
+		HealthCheckVersion: 1,
 	}
 
-	err = s.dataStore.updateHostedZone(hz)
+	err = s.dataStore.putHealthCheck(&hc, false)
 	if err != nil {
 		return nil, err
 	}
 
-	return &aws53.ChangeTagsForResourceOutput{}, nil
+	return &hc, nil
 }
 
 func (s *Service) CreateHostedZone(
@@ -115,22 +387,48 @@ func (s *Service) CreateHostedZone(
 		Id:              HostedZonePrefix + core.GenerateRandomString(14),
 		Name:            strings.ToLower(aws.ToString(zone.Name)),
 		DelegationSet: DelegationSetData{
-			Id:          aws.ToString(zone.DelegationSetId),
 			NameServers: s.nsRecords,
 		},
 	}
 
+	soa := s.soaDefault
+	if aws.ToString(zone.DelegationSetId) != "" {
+		set, err := s.dataStore.getDelegationSet(aws.ToString(zone.DelegationSetId))
+		if err != nil {
+			return nil, err
+		}
+		hz.DelegationSet = *set
+
+		// like Route53, the SOA names the delegation set's first name server
+		if fields := strings.Fields(soa); len(fields) > 0 && len(set.NameServers) > 0 {
+			fields[0] = dns.Fqdn(set.NameServers[0])
+			soa = strings.Join(fields, " ")
+		}
+	}
+
 	if zone.HostedZoneConfig != nil {
 		hz.Config.Comment = aws.ToString(zone.HostedZoneConfig.Comment)
 		hz.Config.PrivateZone = zone.HostedZoneConfig.PrivateZone
 	}
 
+	// as in Route53, a zone created with a VPC is private and a private zone needs one
+	if zone.VPC != nil {
+		vpc, err := s.checkVPC(zone.VPC)
+		if err != nil {
+			return nil, err
+		}
+		hz.Config.PrivateZone = true
+		hz.VPCs = []awstypes.VPC{vpc}
+	} else if hz.Config.PrivateZone {
+		return nil, ErrInvalidVPCId
+	}
+
 	if !strings.HasSuffix(hz.Name, ".") {
 		hz.Name = hz.Name + "."
 	}
 
-	nsResourceRecords := make([]awstypes.ResourceRecord, 0, len(s.nsRecords))
-	for _, ns := range s.nsRecords {
+	nsResourceRecords := make([]awstypes.ResourceRecord, 0, len(hz.DelegationSet.NameServers))
+	for _, ns := range hz.DelegationSet.NameServers {
 		nsResourceRecords = append(nsResourceRecords, awstypes.ResourceRecord{Value: aws.String(ns)})
 	}
 
@@ -140,13 +438,14 @@ func (s *Service) CreateHostedZone(
 			Name:            hz.Name,
 			Type:            awstypes.RRTypeSoa,
 			TTL:             aws.Int64(900),
-			ResourceRecords: []awstypes.ResourceRecord{{Value: aws.String(s.soaDefault)}},
+			ResourceRecords: []awstypes.ResourceRecord{{Value: aws.String(soa)}},
 		},
 	}, {
 		Action: awstypes.ChangeActionCreate,
 		ResourceRecordSet: &ResourceRecordSetData{
 			Name:            hz.Name,
 			Type:            awstypes.RRTypeNs,
+			TTL:             aws.Int64(172800),
 			ResourceRecords: nsResourceRecords,
 		},
 	}}
@@ -157,7 +456,7 @@ func (s *Service) CreateHostedZone(
 	}
 
 	result := aws53.CreateHostedZoneOutput{
-		HostedZone:    hz.toHostedZone(2),
+		HostedZone:    hz.toHostedZone(),
 		ChangeInfo:    ci.toChangeInfo(),
 		DelegationSet: hz.DelegationSet.toDelegationSet(),
 	}
@@ -165,34 +464,59 @@ func (s *Service) CreateHostedZone(
 	return &result, nil
 }
 
-func (s *Service) DeleteHostedZone(
-	zone *aws53.DeleteHostedZoneInput) (*aws53.DeleteHostedZoneOutput, error) {
+func (s *Service) CreateKeySigningKey(
+	request *aws53.CreateKeySigningKeyInput) (*aws53.CreateKeySigningKeyOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
 
-	hz, herr := s.dataStore.getHostedZone(aws.ToString(zone.Id))
-	if herr != nil {
-		return nil, herr
+	if hz.Config.PrivateZone {
+		return nil, ErrDNSSECNotSupported
 	}
 
-	count, cerr := s.dataStore.getRecordCount(hz.Id)
-	if cerr != nil {
-		return nil, cerr
+	if !kskNamePattern.MatchString(aws.ToString(request.Name)) {
+		return nil, ErrInvalidKeySigningKeyName
 	}
 
-	if count > 2 {
-		return nil, ErrHostedZoneNotEmpty
-	} else if count > 0 {
-		recs, rerr := s.dataStore.getResourceRecordSets(hz.Id)
-		if rerr != nil {
-			return nil, rerr
-		}
+	status := aws.ToString(request.Status)
+	if status != kskActive && status != kskInactive {
+		return nil, ErrInvalidKeySigningKeyStatus
+	}
 
-		for _, rec := range recs {
-			if rec.Type != awstypes.RRTypeNs && rec.Type != awstypes.RRTypeSoa {
-				return nil, ErrHostedZoneNotEmpty
-			}
+	ksks, err := s.dataStore.findKeySigningKeys(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ksk := KeySigningKeyData{
+		HostedZoneId:     strings.TrimPrefix(hz.Id, HostedZonePrefix),
+		Name:             aws.ToString(request.Name),
+		KmsArn:           aws.ToString(request.KeyManagementServiceArn),
+		Status:           status,
+		CreatedDate:      now,
+		LastModifiedDate: now,
+	}
+
+	dnskey, _, err := s.keySigningKey(hz.Name, &ksk)
+	if err != nil {
+		return nil, err
+	}
+
+	// the same KMS key can be named by its id, alias or ARN, the public keys tell
+	for i := range ksks {
+		other, _, err := s.keySigningKey(hz.Name, &ksks[i])
+		if ksks[i].Name == ksk.Name || (err == nil && other.PublicKey == dnskey.PublicKey) {
+			return nil, ErrKeySigningKeyAlreadyExists
 		}
 	}
 
+	if len(ksks) >= maxKeySigningKeys {
+		return nil, ErrTooManyKeySigningKeys
+	}
+
 	ci := ChangeInfoData{
 		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
 		Status:      awstypes.ChangeStatusInsync,
@@ -200,278 +524,1560 @@ func (s *Service) DeleteHostedZone(
 		Comment:     "Change is complete.",
 	}
 
-	err := s.dataStore.deleteHostedZone(aws.ToString(zone.Id), &ci)
+	err = s.dataStore.putKeySigningKey(&ksk, false, &ci)
 	if err != nil {
 		return nil, err
 	}
+	s.signers.invalidate(hz.Id)
 
-	result := &aws53.DeleteHostedZoneOutput{
-		ChangeInfo: ci.toChangeInfo(),
+	result := s.toKeySigningKey(hz, &ksk)
+
+	return &aws53.CreateKeySigningKeyOutput{
+		ChangeInfo:    ci.toChangeInfo(),
+		KeySigningKey: &result,
+		Location:      aws.String("https://route53.amazonaws.com/2013-04-01/keysigningkey/" + ksk.HostedZoneId + "/" + ksk.Name),
+	}, nil
+}
+
+func (s *Service) CreateQueryLoggingConfig(
+	request *aws53.CreateQueryLoggingConfigInput) (*QueryLoggingConfigData, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	name, ok := logGroupName(aws.ToString(request.CloudWatchLogsLogGroupArn))
+	if !ok {
+		return nil, ErrInvalidInput
+	}
+
+	if _, found := s.logGroups[name]; !found {
+		return nil, ErrNoSuchLogGroup
+	}
+
+	cfg := QueryLoggingConfigData{
+		Id:                        newResourceId(),
+		HostedZoneId:              strings.TrimPrefix(hz.Id, HostedZonePrefix),
+		CloudWatchLogsLogGroupArn: aws.ToString(request.CloudWatchLogsLogGroupArn),
+	}
+
+	err = s.dataStore.putQueryLoggingConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.queryLog.configsChanged()
+
+	return &cfg, nil
+}
+
+func (s *Service) CreateReusableDelegationSet(
+	request *aws53.CreateReusableDelegationSetInput) (*DelegationSetData, error) {
+
+	callerReference := aws.ToString(request.CallerReference)
+	if callerReference == "" {
+		return nil, ErrInvalidInput
+	}
+
+	sets, err := s.dataStore.findDelegationSets()
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.ContainsFunc(sets, func(set DelegationSetData) bool { return set.CallerReference == callerReference }) {
+		return nil, ErrDelegationSetAlreadyExists
+	}
+
+	set := DelegationSetData{
+		Id:              DelegationSetPrefix + "N" + core.GenerateRandomString(13),
+		CallerReference: callerReference,
+		NameServers:     s.nsGroupFor(callerReference),
+	}
+
+	// a delegation set made from a hosted zone keeps the zone's name servers
+	var hz *HostedZoneData
+	if aws.ToString(request.HostedZoneId) != "" {
+		hz, err = s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+		if err != nil {
+			return nil, err
+		}
+		if hz.DelegationSet.Id != "" {
+			return nil, ErrDelegationSetReusable
+		}
+		set.NameServers = hz.DelegationSet.NameServers
+	}
+
+	err = s.dataStore.putDelegationSet(&set, hz)
+	if err != nil {
+		return nil, err
+	}
+
+	return &set, nil
 }
 
-func (s *Service) GetHostedZoneCount() (*aws53.GetHostedZoneCountOutput, error) {
+func (s *Service) CreateTrafficPolicy(request *aws53.CreateTrafficPolicyInput) (*TrafficPolicyData, error) {
+
+	name := aws.ToString(request.Name)
+	if name == "" || len(name) > 512 || len(aws.ToString(request.Comment)) > 1024 {
+		return nil, ErrInvalidInput
+	}
+
+	doc, err := parseTrafficPolicyDocument(aws.ToString(request.Document))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := TrafficPolicyData{
+		Id:       newResourceId(),
+		Version:  1,
+		Name:     name,
+		Type:     doc.RecordType,
+		Document: aws.ToString(request.Document),
+		Comment:  aws.ToString(request.Comment),
+	}
+
+	err = s.dataStore.putTrafficPolicy(&tp, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tp, nil
+}
+
+func (s *Service) CreateTrafficPolicyInstance(
+	request *aws53.CreateTrafficPolicyInstanceInput) (*TrafficPolicyInstanceData, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	tp, err := s.dataStore.getTrafficPolicy(
+		aws.ToString(request.TrafficPolicyId), aws.ToInt32(request.TrafficPolicyVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	name := normalizeDnsName(aws.ToString(request.Name))
+	ttl := aws.ToInt64(request.TTL)
+	if !isSubdomain(name, hz.Name) || request.TTL == nil || ttl < 0 || ttl > math.MaxInt32 {
+		return nil, ErrInvalidInput
+	}
+
+	instance := TrafficPolicyInstanceData{
+		Id:                   newResourceId(),
+		HostedZoneId:         strings.TrimPrefix(hz.Id, HostedZonePrefix),
+		Name:                 name,
+		TTL:                  ttl,
+		State:                trafficPolicyInstanceApplied,
+		TrafficPolicyId:      tp.Id,
+		TrafficPolicyVersion: tp.Version,
+		TrafficPolicyType:    tp.Type,
+	}
+
+	// checked before the record sets, which would conflict with the existing instance's
+	instances, err := s.dataStore.findTrafficPolicyInstances()
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(instances, func(existing TrafficPolicyInstanceData) bool {
+		return compareTrafficPolicyInstances(existing, instance) == 0
+	}) {
+		return nil, ErrTrafficPolicyInstanceExists
+	}
+
+	err = s.applyTrafficPolicy(hz, &instance, tp, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instance, nil
+}
+
+func (s *Service) CreateTrafficPolicyVersion(
+	request *aws53.CreateTrafficPolicyVersionInput) (*TrafficPolicyData, error) {
+
+	versions, err := s.trafficPolicyVersions(aws.ToString(request.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(aws.ToString(request.Comment)) > 1024 {
+		return nil, ErrInvalidInput
+	}
+
+	doc, err := parseTrafficPolicyDocument(aws.ToString(request.Document))
+	if err != nil {
+		return nil, err
+	}
+
+	latest := versions[len(versions)-1]
+	tp := TrafficPolicyData{
+		Id:       latest.Id,
+		Version:  latest.Version + 1,
+		Name:     latest.Name,
+		Type:     doc.RecordType,
+		Document: aws.ToString(request.Document),
+		Comment:  aws.ToString(request.Comment),
+	}
+
+	err = s.dataStore.putTrafficPolicy(&tp, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tp, nil
+}
+
+func (s *Service) DeactivateKeySigningKey(
+	request *aws53.DeactivateKeySigningKeyInput) (*aws53.DeactivateKeySigningKeyOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	ksk, err := s.dataStore.getKeySigningKey(hz.Id, aws.ToString(request.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	dnssec, err := s.dataStore.getDnssec(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	ksks, err := s.dataStore.findKeySigningKeys(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// a signing zone needs an active key signing key
+	active := slices.ContainsFunc(ksks, func(other KeySigningKeyData) bool {
+		return other.Name != ksk.Name && other.Status == kskActive
+	})
+	if dnssec.ServeSignature == dnssecSigning && ksk.Status == kskActive && !active {
+		return nil, ErrKeySigningKeyInUse
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Change is complete.",
+	}
+
+	ksk.Status = kskInactive
+	ksk.LastModifiedDate = time.Now().UTC()
+
+	err = s.dataStore.putKeySigningKey(ksk, true, &ci)
+	if err != nil {
+		return nil, err
+	}
+	s.signers.invalidate(hz.Id)
+
+	return &aws53.DeactivateKeySigningKeyOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}, nil
+}
+
+func (s *Service) DeleteCidrCollection(
+	request *aws53.DeleteCidrCollectionInput) (*aws53.DeleteCidrCollectionOutput, error) {
+
+	err := s.dataStore.deleteCidrCollection(aws.ToString(request.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.DeleteCidrCollectionOutput{}, nil
+}
+
+func (s *Service) DeleteHealthCheck(
+	request *aws53.DeleteHealthCheckInput) (*aws53.DeleteHealthCheckOutput, error) {
+
+	err := s.dataStore.deleteHealthCheck(aws.ToString(request.HealthCheckId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.DeleteHealthCheckOutput{}, nil
+}
+
+func (s *Service) DeleteHostedZone(
+	zone *aws53.DeleteHostedZoneInput) (*aws53.DeleteHostedZoneOutput, error) {
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Change is complete.",
+	}
+
+	err := s.dataStore.deleteHostedZone(aws.ToString(zone.Id), &ci)
+	if err != nil {
+		return nil, err
+	}
+	s.signers.invalidate(aws.ToString(zone.Id))
+
+	result := &aws53.DeleteHostedZoneOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}
+
+	return result, nil
+}
+
+func (s *Service) DeleteKeySigningKey(
+	request *aws53.DeleteKeySigningKeyInput) (*aws53.DeleteKeySigningKeyOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	ksk, err := s.dataStore.getKeySigningKey(hz.Id, aws.ToString(request.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	if ksk.Status == kskActive {
+		return nil, ErrInvalidKeySigningKeyStatus
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Change is complete.",
+	}
+
+	err = s.dataStore.deleteKeySigningKey(ksk, &ci)
+	if err != nil {
+		return nil, err
+	}
+	s.signers.invalidate(hz.Id)
+
+	return &aws53.DeleteKeySigningKeyOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}, nil
+}
+
+func (s *Service) DeleteQueryLoggingConfig(
+	request *aws53.DeleteQueryLoggingConfigInput) (*aws53.DeleteQueryLoggingConfigOutput, error) {
+
+	err := s.dataStore.deleteQueryLoggingConfig(aws.ToString(request.Id))
+	if err != nil {
+		return nil, err
+	}
+	s.queryLog.configsChanged()
+
+	return &aws53.DeleteQueryLoggingConfigOutput{}, nil
+}
+
+func (s *Service) DeleteReusableDelegationSet(
+	request *aws53.DeleteReusableDelegationSetInput) (*aws53.DeleteReusableDelegationSetOutput, error) {
+
+	err := s.dataStore.deleteDelegationSet(aws.ToString(request.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.DeleteReusableDelegationSetOutput{}, nil
+}
+
+func (s *Service) DeleteTrafficPolicy(
+	request *aws53.DeleteTrafficPolicyInput) (*aws53.DeleteTrafficPolicyOutput, error) {
+
+	err := s.dataStore.deleteTrafficPolicy(aws.ToString(request.Id), aws.ToInt32(request.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.DeleteTrafficPolicyOutput{}, nil
+}
+
+func (s *Service) DeleteTrafficPolicyInstance(
+	request *aws53.DeleteTrafficPolicyInstanceInput) (*aws53.DeleteTrafficPolicyInstanceOutput, error) {
+
+	instance, err := s.dataStore.getTrafficPolicyInstance(aws.ToString(request.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	hz, err := s.dataStore.getHostedZone(instance.HostedZoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Delete traffic policy instance " + instance.Id,
+	}
+
+	deleted, err := s.dataStore.deleteTrafficPolicyInstance(instance, hz, &ci)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted > 0 {
+		s.zoneChanged(hz)
+	}
+
+	return &aws53.DeleteTrafficPolicyInstanceOutput{}, nil
+}
+
+func (s *Service) DisableHostedZoneDNSSEC(
+	request *aws53.DisableHostedZoneDNSSECInput) (*aws53.DisableHostedZoneDNSSECOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	dnssec, err := s.dataStore.getDnssec(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Change is complete.",
+	}
+
+	// the zone signing key is kept for when signing is enabled again
+	dnssec.ServeSignature = dnssecNotSigning
+
+	err = s.dataStore.putDnssec(dnssec, &ci)
+	if err != nil {
+		return nil, err
+	}
+	s.signers.invalidate(hz.Id)
+
+	return &aws53.DisableHostedZoneDNSSECOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}, nil
+}
+
+func (s *Service) DisassociateVPCFromHostedZone(
+	request *aws53.DisassociateVPCFromHostedZoneInput) (*aws53.DisassociateVPCFromHostedZoneOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     aws.ToString(request.Comment),
+	}
+
+	_, err = s.dataStore.updateHostedZone(hz.Id, func(hz *HostedZoneData) error {
+		if request.VPC == nil || !hasVPC(hz, *request.VPC) {
+			return ErrVPCAssociationNotFound
+		}
+
+		if len(hz.VPCs) == 1 {
+			return ErrLastVPCAssociation
+		}
+
+		hz.VPCs = slices.DeleteFunc(hz.VPCs, func(vpc awstypes.VPC) bool {
+			return aws.ToString(vpc.VPCId) == aws.ToString(request.VPC.VPCId)
+		})
+		return nil
+	}, &ci)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.DisassociateVPCFromHostedZoneOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}, nil
+}
+
+func (s *Service) EnableHostedZoneDNSSEC(
+	request *aws53.EnableHostedZoneDNSSECInput) (*aws53.EnableHostedZoneDNSSECOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	if hz.Config.PrivateZone {
+		return nil, ErrDNSSECNotSupported
+	}
+
+	ksks, err := s.dataStore.findKeySigningKeys(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(ksks, func(ksk KeySigningKeyData) bool { return ksk.Status == kskActive }) {
+		return nil, ErrNoActiveKeySigningKey
+	}
+
+	dnssec, err := s.dataStore.getDnssec(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if dnssec.WrappedZoneSigningKey == nil && dnssec.ZoneSigningKey == "" {
+		dnssec.WrappedZoneSigningKey, err = s.newZoneSigningKey(hz.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Change is complete.",
+	}
+
+	dnssec.ServeSignature = dnssecSigning
+
+	err = s.dataStore.putDnssec(dnssec, &ci)
+	if err != nil {
+		return nil, err
+	}
+	s.signers.invalidate(hz.Id)
+
+	return &aws53.EnableHostedZoneDNSSECOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}, nil
+}
+
+func (s *Service) GetHealthCheck(request *aws53.GetHealthCheckInput) (*HealthCheckData, error) {
+	return s.dataStore.getHealthCheck(aws.ToString(request.HealthCheckId))
+}
+
+func (s *Service) GetHealthCheckStatus(
+	request *aws53.GetHealthCheckStatusInput) (*aws53.GetHealthCheckStatusOutput, error) {
+
+	hc, err := s.dataStore.getHealthCheck(aws.ToString(request.HealthCheckId))
+	if err != nil {
+		return nil, err
+	}
+
+	// home-fern has a single prober, reported for the first configured region
+	region := awstypes.HealthCheckRegionUsEast1
+	if len(hc.HealthCheckConfig.Regions) > 0 {
+		region = hc.HealthCheckConfig.Regions[0]
+	}
+
+	report, checked := s.health.observation(hc.Id)
+
+	result := aws53.GetHealthCheckStatusOutput{
+		HealthCheckObservations: []awstypes.HealthCheckObservation{{
+			Region: region,
+			StatusReport: &awstypes.StatusReport{
+				Status:      aws.String(report),
+				CheckedTime: aws.Time(checked),
+			},
+		}},
+	}
+
+	return &result, nil
+}
+
+func (s *Service) GetHostedZoneCount() (*aws53.GetHostedZoneCountOutput, error) {
+
+	result, err := s.dataStore.getHostedZoneCount()
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.GetHostedZoneCountOutput{HostedZoneCount: aws.Int64(int64(result))}, nil
+}
+
+func (s *Service) GetHostedZone(
+	zone *aws53.GetHostedZoneInput) (*aws53.GetHostedZoneOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(zone.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	awshz := hz.toHostedZone()
+
+	result := &aws53.GetHostedZoneOutput{
+		HostedZone:    awshz,
+		DelegationSet: hz.DelegationSet.toDelegationSet(),
+		VPCs:          hz.VPCs,
+	}
+
+	return result, nil
+}
+
+func (s *Service) GetChange(
+	request *aws53.GetChangeInput) (*aws53.GetChangeOutput, error) {
+
+	ci, err := s.dataStore.getChange(aws.ToString(request.Id))
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			ci = &ChangeInfoData{
+				Id:          ChangeInfoPrefix + aws.ToString(request.Id),
+				Comment:     "Expired - ChangeInfo expires after ~90 days",
+				Status:      awstypes.ChangeStatusInsync,
+				SubmittedAt: time.Now().UTC().Add(-90 * 24 * time.Hour).Format(time.RFC3339),
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	result := &aws53.GetChangeOutput{
+		ChangeInfo: ci.toChangeInfo(),
+	}
+
+	return result, nil
+}
+
+func (s *Service) GetDNSSEC(request *aws53.GetDNSSECInput) (*aws53.GetDNSSECOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	dnssec, err := s.dataStore.getDnssec(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	ksks, err := s.dataStore.findKeySigningKeys(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := aws53.GetDNSSECOutput{
+		KeySigningKeys: []awstypes.KeySigningKey{},
+		Status: &awstypes.DNSSECStatus{
+			ServeSignature: aws.String(dnssec.ServeSignature),
+			StatusMessage:  core.StringOrNil(dnssec.StatusMessage),
+		},
+	}
+
+	for i := range ksks {
+		result.KeySigningKeys = append(result.KeySigningKeys, s.toKeySigningKey(hz, &ksks[i]))
+	}
+
+	return &result, nil
+}
+
+func (s *Service) GetQueryLoggingConfig(
+	request *aws53.GetQueryLoggingConfigInput) (*QueryLoggingConfigData, error) {
+
+	return s.dataStore.getQueryLoggingConfig(aws.ToString(request.Id))
+}
+
+func (s *Service) GetReusableDelegationSet(
+	request *aws53.GetReusableDelegationSetInput) (*DelegationSetData, error) {
+
+	return s.dataStore.getDelegationSet(aws.ToString(request.Id))
+}
+
+func (s *Service) GetTrafficPolicy(request *aws53.GetTrafficPolicyInput) (*TrafficPolicyData, error) {
+	return s.dataStore.getTrafficPolicy(aws.ToString(request.Id), aws.ToInt32(request.Version))
+}
+
+func (s *Service) GetTrafficPolicyInstance(
+	request *aws53.GetTrafficPolicyInstanceInput) (*TrafficPolicyInstanceData, error) {
+
+	return s.dataStore.getTrafficPolicyInstance(aws.ToString(request.Id))
+}
+
+func (s *Service) GetTrafficPolicyInstanceCount() (*aws53.GetTrafficPolicyInstanceCountOutput, error) {
+
+	instances, err := s.dataStore.findTrafficPolicyInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws53.GetTrafficPolicyInstanceCountOutput{
+		TrafficPolicyInstanceCount: aws.Int32(int32(len(instances))),
+	}, nil
+}
+
+func (s *Service) ListCidrBlocks(request *aws53.ListCidrBlocksInput) (*ListCidrBlocksOutput, error) {
+
+	coll, err := s.dataStore.getCidrCollection(aws.ToString(request.CollectionId))
+	if err != nil {
+		return nil, err
+	}
+
+	location := aws.ToString(request.LocationName)
+	if _, found := coll.Locations[location]; location != "" && !found {
+		return nil, ErrNoSuchCidrLocation
+	}
+
+	var blocks []CidrBlockSummaryData
+	for _, name := range slices.Sorted(maps.Keys(coll.Locations)) {
+		if location != "" && name != location {
+			continue
+		}
+		for _, block := range slices.Sorted(slices.Values(coll.Locations[name])) {
+			blocks = append(blocks, CidrBlockSummaryData{CidrBlock: block, LocationName: name})
+		}
+	}
+
+	// the token is the location and block of the first item of the next page
+	startIndex := len(blocks)
+	for i, block := range blocks {
+		if block.LocationName+" "+block.CidrBlock >= aws.ToString(request.NextToken) {
+			startIndex = i
+			break
+		}
+	}
+
+	paginatedBlocks, nextBlock := paginate(blocks[startIndex:], request.MaxResults)
+
+	result := ListCidrBlocksOutput{
+		CidrBlocks: paginatedBlocks,
+	}
+
+	if nextBlock != nil {
+		result.NextToken = aws.String(nextBlock.LocationName + " " + nextBlock.CidrBlock)
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListCidrCollections(request *aws53.ListCidrCollectionsInput) (*ListCidrCollectionsOutput, error) {
+
+	collections, err := s.dataStore.findCidrCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(collections, func(a, b CidrCollectionData) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	startIndex := len(collections)
+	for i, coll := range collections {
+		if coll.Id >= aws.ToString(request.NextToken) {
+			startIndex = i
+			break
+		}
+	}
+
+	paginatedCollections, nextCollection := paginate(collections[startIndex:], request.MaxResults)
+
+	result := ListCidrCollectionsOutput{
+		CidrCollections: paginatedCollections,
+	}
+
+	if nextCollection != nil {
+		result.NextToken = &nextCollection.Id
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListCidrLocations(request *aws53.ListCidrLocationsInput) (*ListCidrLocationsOutput, error) {
+
+	coll, err := s.dataStore.getCidrCollection(aws.ToString(request.CollectionId))
+	if err != nil {
+		return nil, err
+	}
+
+	names := slices.Sorted(maps.Keys(coll.Locations))
+
+	startIndex := len(names)
+	for i, name := range names {
+		if name >= aws.ToString(request.NextToken) {
+			startIndex = i
+			break
+		}
+	}
+
+	paginatedNames, nextName := paginate(names[startIndex:], request.MaxResults)
+
+	return &ListCidrLocationsOutput{
+		LocationNames: paginatedNames,
+		NextToken:     nextName,
+	}, nil
+}
+
+func (s *Service) ListHealthChecks(
+	request *aws53.ListHealthChecksInput) (*ListHealthChecksOutput, error) {
+
+	checks, err := s.dataStore.findHealthChecks()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(checks, func(a, b HealthCheckData) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	startIndex := len(checks)
+	for i, hc := range checks {
+		if hc.Id >= aws.ToString(request.Marker) {
+			startIndex = i
+			break
+		}
+	}
+
+	paginatedChecks, nextCheck := paginate(checks[startIndex:], request.MaxItems)
+
+	result := ListHealthChecksOutput{
+		HealthChecks: paginatedChecks,
+		Marker:       request.Marker,
+		MaxItems:     request.MaxItems,
+	}
+
+	if nextCheck != nil {
+		result.NextMarker = &nextCheck.Id
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListHostedZones(
+	request *aws53.ListHostedZonesInput) (*aws53.ListHostedZonesOutput, error) {
+
+	var keep func(hz *HostedZoneData) bool
+	if aws.ToString(request.DelegationSetId) != "" {
+		set, err := s.dataStore.getDelegationSet(aws.ToString(request.DelegationSetId))
+		if err != nil {
+			return nil, err
+		}
+		keep = func(hz *HostedZoneData) bool { return hz.DelegationSet.Id == set.Id }
+	}
+
+	zones, nextZone, err := s.dataStore.listHostedZones(aws.ToString(request.Marker), keep, pageSize(request.MaxItems))
+	if err != nil {
+		return nil, err
+	}
+
+	var nextMarker *string
+	if nextZone != nil {
+		nextMarker = &nextZone.Id
+	}
+
+	return &aws53.ListHostedZonesOutput{
+		HostedZones: toHostedZones(zones),
+		IsTruncated: nextZone != nil,
+		Marker:      request.Marker,
+		MaxItems:    request.MaxItems,
+		NextMarker:  nextMarker,
+	}, nil
+}
+
+func (s *Service) ListHostedZonesByName(
+	request *aws53.ListHostedZonesByNameInput) (*aws53.ListHostedZonesByNameOutput, error) {
+
+	if aws.ToString(request.HostedZoneId) != "" && aws.ToString(request.DNSName) == "" {
+		return nil, ErrInvalidInput
+	}
+
+	zones, nextZone, err := s.dataStore.listHostedZonesByName(
+		aws.ToString(request.DNSName), aws.ToString(request.HostedZoneId), pageSize(request.MaxItems))
+	if err != nil {
+		return nil, err
+	}
+
+	var nextDNSName, nextHostedZoneId *string
+	if nextZone != nil {
+		nextDNSName = &nextZone.Name
+		nextHostedZoneId = &nextZone.Id
+	}
+
+	return &aws53.ListHostedZonesByNameOutput{
+		HostedZones:      toHostedZones(zones),
+		IsTruncated:      nextZone != nil,
+		DNSName:          request.DNSName,
+		HostedZoneId:     request.HostedZoneId,
+		MaxItems:         request.MaxItems,
+		NextDNSName:      nextDNSName,
+		NextHostedZoneId: nextHostedZoneId,
+	}, nil
+}
+
+func (s *Service) ListHostedZonesByVPC(
+	request *aws53.ListHostedZonesByVPCInput) (*aws53.ListHostedZonesByVPCOutput, error) {
+
+	vpc, err := s.checkVPC(&awstypes.VPC{VPCId: request.VPCId, VPCRegion: request.VPCRegion})
+	if err != nil {
+		return nil, err
+	}
+
+	zones, nextZone, err := s.dataStore.listHostedZones(aws.ToString(request.NextToken),
+		func(hz *HostedZoneData) bool { return hasVPC(hz, vpc) }, pageSize(request.MaxItems))
+	if err != nil {
+		return nil, err
+	}
+
+	result := aws53.ListHostedZonesByVPCOutput{
+		HostedZoneSummaries: make([]awstypes.HostedZoneSummary, 0, len(zones)),
+		MaxItems:            request.MaxItems,
+	}
+
+	for _, hz := range zones {
+		result.HostedZoneSummaries = append(result.HostedZoneSummaries, awstypes.HostedZoneSummary{
+			HostedZoneId: aws.String(strings.TrimPrefix(hz.Id, HostedZonePrefix)),
+			Name:         aws.String(hz.Name),
+		})
+	}
+
+	if nextZone != nil {
+		result.NextToken = aws.String(nextZone.Id)
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListQueryLoggingConfigs(
+	request *aws53.ListQueryLoggingConfigsInput) (*ListQueryLoggingConfigsOutput, error) {
+
+	configs, err := s.dataStore.findQueryLoggingConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	if zoneId := aws.ToString(request.HostedZoneId); zoneId != "" {
+		hz, err := s.dataStore.getHostedZone(zoneId)
+		if err != nil {
+			return nil, err
+		}
+		configs = slices.DeleteFunc(configs, func(cfg QueryLoggingConfigData) bool {
+			return cfg.HostedZoneId != strings.TrimPrefix(hz.Id, HostedZonePrefix)
+		})
+	}
+
+	slices.SortFunc(configs, func(a, b QueryLoggingConfigData) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	startIndex := len(configs)
+	for i, cfg := range configs {
+		if cfg.Id >= aws.ToString(request.NextToken) {
+			startIndex = i
+			break
+		}
+	}
+
+	paginatedConfigs, nextConfig := paginate(configs[startIndex:], request.MaxResults)
+
+	result := ListQueryLoggingConfigsOutput{
+		QueryLoggingConfigs: paginatedConfigs,
+		MaxResults:          request.MaxResults,
+	}
+
+	if nextConfig != nil {
+		result.NextToken = &nextConfig.Id
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListResourceRecordSets(
+	request *aws53.ListResourceRecordSetsInput) (*ListRecordSetsOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	var start string
+	if name := aws.ToString(request.StartRecordName); name != "" {
+		start = recordSetKey(name, request.StartRecordType, aws.ToString(request.StartRecordIdentifier))
+	}
+
+	records, nextRecord, err := s.dataStore.listResourceRecordSets(hz.Id, start, pageSize(request.MaxItems))
+	if err != nil {
+		return nil, err
+	}
+
+	result := ListRecordSetsOutput{
+		Records: records,
+	}
+
+	if nextRecord != nil {
+		result.NextRecord = nextRecord.Name
+		result.NexType = nextRecord.Type
+		result.NextIdentifier = aws.ToString(nextRecord.SetIdentifier)
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListReusableDelegationSets(
+	request *aws53.ListReusableDelegationSetsInput) (*ListDelegationSetsOutput, error) {
+
+	sets, err := s.dataStore.findDelegationSets()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(sets, func(a, b DelegationSetData) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	marker := aws.ToString(request.Marker)
+	if marker != "" && !strings.HasPrefix(marker, DelegationSetPrefix) {
+		marker = DelegationSetPrefix + marker
+	}
+
+	startIndex := len(sets)
+	for i, set := range sets {
+		if set.Id >= marker {
+			startIndex = i
+			break
+		}
+	}
+
+	paginatedSets, nextSet := paginate(sets[startIndex:], request.MaxItems)
+
+	result := ListDelegationSetsOutput{
+		DelegationSets: paginatedSets,
+		Marker:         request.Marker,
+		MaxItems:       request.MaxItems,
+	}
+
+	if nextSet != nil {
+		result.NextMarker = &nextSet.Id
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListTagsForResource(
+	request *aws53.ListTagsForResourceInput) (*aws53.ListTagsForResourceOutput, error) {
+
+	var tags []core.ResourceTag
+
+	switch request.ResourceType {
+	case awstypes.TagResourceTypeHostedzone:
+		hz, err := s.dataStore.getHostedZone(aws.ToString(request.ResourceId))
+		if err != nil {
+			return nil, err
+		}
+		tags = hz.Tags
+
+	case awstypes.TagResourceTypeHealthcheck:
+		hc, err := s.dataStore.getHealthCheck(aws.ToString(request.ResourceId))
+		if err != nil {
+			return nil, err
+		}
+		tags = hc.Tags
+
+	default:
+		return nil, ErrInvalidInput
+	}
+
+	result := aws53.ListTagsForResourceOutput{
+		ResourceTagSet: &awstypes.ResourceTagSet{
+			ResourceId:   request.ResourceId,
+			ResourceType: request.ResourceType,
+			Tags:         make([]awstypes.Tag, 0),
+		},
+	}
+
+	for _, tag := range tags {
+
+		awstag := awstypes.Tag{
+			Key:   aws.String(tag.Key),
+			Value: aws.String(tag.Value),
+		}
+
+		result.ResourceTagSet.Tags = append(result.ResourceTagSet.Tags, awstag)
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListTrafficPolicies(
+	request *aws53.ListTrafficPoliciesInput) (*ListTrafficPoliciesOutput, error) {
+
+	policies, err := s.dataStore.findTrafficPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	// the summary takes the name and type of the latest version
+	var summaries []TrafficPolicySummaryData
+	slices.SortFunc(policies, compareTrafficPolicies)
+	for _, tp := range policies {
+		if len(summaries) == 0 || summaries[len(summaries)-1].Id != tp.Id {
+			summaries = append(summaries, TrafficPolicySummaryData{Id: tp.Id})
+		}
+
+		summary := &summaries[len(summaries)-1]
+		summary.Name = tp.Name
+		summary.Type = tp.Type
+		summary.LatestVersion = tp.Version
+		summary.TrafficPolicyCount++
+	}
+
+	startIndex := len(summaries)
+	for i, summary := range summaries {
+		if summary.Id >= aws.ToString(request.TrafficPolicyIdMarker) {
+			startIndex = i
+			break
+		}
+	}
+
+	paginatedSummaries, nextSummary := paginate(summaries[startIndex:], request.MaxItems)
+
+	result := ListTrafficPoliciesOutput{
+		TrafficPolicySummaries: paginatedSummaries,
+		MaxItems:               request.MaxItems,
+	}
+
+	if nextSummary != nil {
+		result.NextMarker = &nextSummary.Id
+	}
+
+	return &result, nil
+}
+
+func (s *Service) ListTrafficPolicyInstances(
+	request *aws53.ListTrafficPolicyInstancesInput) (*ListTrafficPolicyInstancesOutput, error) {
+
+	return s.listTrafficPolicyInstances(func(*TrafficPolicyInstanceData) bool { return true },
+		request.HostedZoneIdMarker, request.TrafficPolicyInstanceNameMarker, request.TrafficPolicyInstanceTypeMarker,
+		request.MaxItems)
+}
+
+func (s *Service) ListTrafficPolicyInstancesByHostedZone(
+	request *aws53.ListTrafficPolicyInstancesByHostedZoneInput) (*ListTrafficPolicyInstancesOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	zoneId := strings.TrimPrefix(hz.Id, HostedZonePrefix)
+	return s.listTrafficPolicyInstances(func(instance *TrafficPolicyInstanceData) bool {
+		return instance.HostedZoneId == zoneId
+	}, &zoneId, request.TrafficPolicyInstanceNameMarker, request.TrafficPolicyInstanceTypeMarker, request.MaxItems)
+}
+
+func (s *Service) ListTrafficPolicyInstancesByPolicy(
+	request *aws53.ListTrafficPolicyInstancesByPolicyInput) (*ListTrafficPolicyInstancesOutput, error) {
+
+	tp, err := s.dataStore.getTrafficPolicy(
+		aws.ToString(request.TrafficPolicyId), aws.ToInt32(request.TrafficPolicyVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listTrafficPolicyInstances(func(instance *TrafficPolicyInstanceData) bool {
+		return instance.TrafficPolicyId == tp.Id && instance.TrafficPolicyVersion == tp.Version
+	}, request.HostedZoneIdMarker, request.TrafficPolicyInstanceNameMarker, request.TrafficPolicyInstanceTypeMarker,
+		request.MaxItems)
+}
+
+func (s *Service) ListTrafficPolicyVersions(
+	request *aws53.ListTrafficPolicyVersionsInput) (*ListTrafficPolicyVersionsOutput, error) {
+
+	versions, err := s.trafficPolicyVersions(aws.ToString(request.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex := 0
+	if marker := aws.ToString(request.TrafficPolicyVersionMarker); marker != "" {
+		version, err := strconv.Atoi(marker)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+
+		startIndex = len(versions)
+		for i, tp := range versions {
+			if int(tp.Version) >= version {
+				startIndex = i
+				break
+			}
+		}
+	}
+
+	paginatedVersions, nextVersion := paginate(versions[startIndex:], request.MaxItems)
+
+	result := ListTrafficPolicyVersionsOutput{
+		TrafficPolicies: paginatedVersions,
+		MaxItems:        request.MaxItems,
+	}
+
+	if nextVersion != nil {
+		result.NextMarker = aws.String(strconv.Itoa(int(nextVersion.Version)))
+	}
+
+	return &result, nil
+}
+
+func (s *Service) LogKeys(writer io.Writer) error {
+	return s.dataStore.logKeys(writer)
+}
+
+// TestDNSAnswer resolves a record the way the DNS frontend would, within the given hosted zone.
+func (s *Service) TestDNSAnswer(request *aws53.TestDNSAnswerInput) (*aws53.TestDNSAnswerOutput, error) {
+
+	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	name := normalizeDnsName(aws.ToString(request.RecordName))
+	if aws.ToString(request.RecordName) == "" || request.RecordType == "" || !isSubdomain(name, hz.Name) {
+		return nil, ErrInvalidInput
+	}
+
+	zr, err := s.loadHostedZoneRecords(hz)
+	if err != nil {
+		return nil, err
+	}
+
+	query := DnsQuery{
+		Name:     name,
+		Type:     request.RecordType,
+		ClientIP: net.ParseIP(aws.ToString(request.EDNS0ClientSubnetIP)),
+	}
+	if query.ClientIP == nil {
+		query.ClientIP = net.ParseIP(aws.ToString(request.ResolverIP))
+	}
+
+	answer := DnsAnswer{
+		Rcode:         dns.RcodeSuccess,
+		Authoritative: true,
+		Zone:          hz,
+	}
+
+	err = s.resolveInZone(zr, name, &query, &answer, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := aws53.TestDNSAnswerOutput{
+		RecordName:   aws.String(name),
+		RecordType:   request.RecordType,
+		RecordData:   make([]string, 0),
+		ResponseCode: aws.String(dns.RcodeToString[answer.Rcode]),
+		Protocol:     aws.String("UDP"),
+	}
+
+	if len(hz.DelegationSet.NameServers) > 0 {
+		result.Nameserver = aws.String(hz.DelegationSet.NameServers[0])
+	}
+
+	for _, rr := range toDnsRRs(answer.Answer) {
+		result.RecordData = append(result.RecordData, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+
+	return &result, nil
+}
+
+func (s *Service) UpdateHealthCheck(request *UpdateHealthCheckRequest) (*HealthCheckData, error) {
+
+	hc, err := s.dataStore.getHealthCheck(request.HealthCheckId)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.HealthCheckVersion != nil && *request.HealthCheckVersion != hc.HealthCheckVersion {
+		return nil, ErrHealthCheckVersionMismatch
+	}
+
+	cfg := &hc.HealthCheckConfig
+
+	for _, element := range request.ResetElements {
+		switch element {
+		case awstypes.ResettableElementNameFullyQualifiedDomainName:
+			cfg.FullyQualifiedDomainName = nil
+		case awstypes.ResettableElementNameRegions:
+			cfg.Regions = nil
+		case awstypes.ResettableElementNameResourcePath:
+			cfg.ResourcePath = nil
+		case awstypes.ResettableElementNameChildHealthChecks:
+			cfg.ChildHealthChecks = nil
+		}
+	}
+
+	if request.IPAddress != nil {
+		cfg.IPAddress = request.IPAddress
+	}
+	if request.Port != nil {
+		cfg.Port = request.Port
+	}
+	if request.ResourcePath != nil {
+		cfg.ResourcePath = request.ResourcePath
+	}
+	if request.FullyQualifiedDomainName != nil {
+		cfg.FullyQualifiedDomainName = request.FullyQualifiedDomainName
+	}
+	if request.SearchString != nil {
+		cfg.SearchString = request.SearchString
+	}
+	if request.FailureThreshold != nil {
+		cfg.FailureThreshold = request.FailureThreshold
+	}
+	if request.Inverted != nil {
+		cfg.Inverted = request.Inverted
+	}
+	if request.Disabled != nil {
+		cfg.Disabled = request.Disabled
+	}
+	if request.HealthThreshold != nil {
+		cfg.HealthThreshold = request.HealthThreshold
+	}
+	if request.ChildHealthChecks != nil {
+		cfg.ChildHealthChecks = request.ChildHealthChecks
+	}
+	if request.EnableSNI != nil {
+		cfg.EnableSNI = request.EnableSNI
+	}
+	if request.Regions != nil {
+		cfg.Regions = request.Regions
+	}
+	if request.AlarmIdentifier != nil {
+		cfg.AlarmIdentifier = request.AlarmIdentifier
+	}
+	if request.InsufficientDataHealthStatus != "" {
+		cfg.InsufficientDataHealthStatus = request.InsufficientDataHealthStatus
+	}
+
+	if err := normalizeHealthCheckConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	hc.HealthCheckVersion++
 
-	result, err := s.dataStore.getHostedZoneCount()
+	err = s.dataStore.putHealthCheck(hc, true)
 	if err != nil {
 		return nil, err
 	}
 
-	return &aws53.GetHostedZoneCountOutput{HostedZoneCount: aws.Int64(int64(result))}, nil
+	return hc, nil
 }
 
-func (s *Service) GetHostedZone(
-	zone *aws53.GetHostedZoneInput) (*aws53.GetHostedZoneOutput, error) {
+func (s *Service) UpdateHostedZoneComment(
+	request *aws53.UpdateHostedZoneCommentInput) (*aws53.UpdateHostedZoneCommentOutput, error) {
 
-	hz, err := s.dataStore.getHostedZone(aws.ToString(zone.Id))
+	hz, err := s.dataStore.updateHostedZone(aws.ToString(request.Id), func(hz *HostedZoneData) error {
+		hz.Config.Comment = aws.ToString(request.Comment)
+		return nil
+	}, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	awshz, cerr := s.populateRecordCount(hz)
-	if cerr != nil {
-		return nil, cerr
-	}
-
-	result := &aws53.GetHostedZoneOutput{
-		HostedZone:    awshz,
-		DelegationSet: hz.DelegationSet.toDelegationSet(),
+	result := aws53.UpdateHostedZoneCommentOutput{
+		HostedZone: hz.toHostedZone(),
 	}
 
-	return result, nil
+	return &result, nil
 }
 
-func (s *Service) GetChange(
-	request *aws53.GetChangeInput) (*aws53.GetChangeOutput, error) {
+func (s *Service) UpdateTrafficPolicyComment(
+	request *aws53.UpdateTrafficPolicyCommentInput) (*TrafficPolicyData, error) {
 
-	ci, err := s.dataStore.getChange(aws.ToString(request.Id))
+	tp, err := s.dataStore.getTrafficPolicy(aws.ToString(request.Id), aws.ToInt32(request.Version))
 	if err != nil {
-		if errors.Is(err, core.ErrNotFound) {
-			ci = &ChangeInfoData{
-				Id:          ChangeInfoPrefix + aws.ToString(request.Id),
-				Comment:     "Expired - ChangeInfo expires after ~90 days",
-				Status:      awstypes.ChangeStatusInsync,
-				SubmittedAt: time.Now().UTC().Add(-90 * 24 * time.Hour).Format(time.RFC3339),
-			}
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
 
-	result := &aws53.GetChangeOutput{
-		ChangeInfo: ci.toChangeInfo(),
+	if len(aws.ToString(request.Comment)) > 1024 {
+		return nil, ErrInvalidInput
 	}
 
-	return result, nil
-}
-
-func (s *Service) ListHostedZones(
-	request *aws53.ListHostedZonesInput) (*aws53.ListHostedZonesOutput, error) {
+	tp.Comment = aws.ToString(request.Comment)
 
-	zones, err := s.dataStore.findHostedZones(nil)
+	err = s.dataStore.putTrafficPolicy(tp, true)
 	if err != nil {
 		return nil, err
 	}
 
-	slices.SortFunc(zones, func(a, b HostedZoneData) int {
-		return strings.Compare(a.Id, b.Id)
-	})
+	return tp, nil
+}
 
-	startIndex := s.findHostedZoneByMarker(zones, request.Marker)
-	paginatedZones, nextZone := paginate(zones[startIndex:], request.MaxItems)
+func (s *Service) UpdateTrafficPolicyInstance(
+	request *aws53.UpdateTrafficPolicyInstanceInput) (*TrafficPolicyInstanceData, error) {
 
-	awsZones, err := s.populateRecordCounts(paginatedZones)
+	instance, err := s.dataStore.getTrafficPolicyInstance(aws.ToString(request.Id))
 	if err != nil {
 		return nil, err
 	}
 
-	var nextMarker *string
-	if nextZone != nil {
-		nextMarker = &nextZone.Id
+	tp, err := s.dataStore.getTrafficPolicy(
+		aws.ToString(request.TrafficPolicyId), aws.ToInt32(request.TrafficPolicyVersion))
+	if err != nil {
+		return nil, err
 	}
 
-	return &aws53.ListHostedZonesOutput{
-		HostedZones: awsZones,
-		IsTruncated: nextZone != nil,
-		Marker:      request.Marker,
-		MaxItems:    request.MaxItems,
-		NextMarker:  nextMarker,
-	}, nil
-}
-
-func (s *Service) ListHostedZonesByName(
-	request *aws53.ListHostedZonesByNameInput) (*aws53.ListHostedZonesByNameOutput, error) {
+	if tp.Type != instance.TrafficPolicyType {
+		return nil, ErrConflictingTypes
+	}
 
-	if aws.ToString(request.HostedZoneId) != "" && aws.ToString(request.DNSName) == "" {
+	ttl := aws.ToInt64(request.TTL)
+	if request.TTL == nil || ttl < 0 || ttl > math.MaxInt32 {
 		return nil, ErrInvalidInput
 	}
 
-	zones, err := s.dataStore.findHostedZones(nil)
+	hz, err := s.dataStore.getHostedZone(instance.HostedZoneId)
 	if err != nil {
 		return nil, err
 	}
 
-	slices.SortFunc(zones, func(a, b HostedZoneData) int {
-		n := strings.Compare(a.Name, b.Name)
-		if n == 0 {
-			return strings.Compare(a.Id, b.Id)
-		}
-		return n
-	})
+	instance.TTL = ttl
+	instance.TrafficPolicyId = tp.Id
+	instance.TrafficPolicyVersion = tp.Version
 
-	startIndex := s.findHostedZoneByName(zones, request.DNSName, request.HostedZoneId)
-	paginatedZones, nextZone := paginate(zones[startIndex:], request.MaxItems)
-
-	awsZones, err := s.populateRecordCounts(paginatedZones)
+	err = s.applyTrafficPolicy(hz, instance, tp, true)
 	if err != nil {
 		return nil, err
 	}
 
-	var nextDNSName, nextHostedZoneId *string
-	if nextZone != nil {
-		nextDNSName = &nextZone.Name
-		nextHostedZoneId = &nextZone.Id
-	}
-
-	return &aws53.ListHostedZonesByNameOutput{
-		HostedZones:      awsZones,
-		IsTruncated:      nextZone != nil,
-		DNSName:          request.DNSName,
-		HostedZoneId:     request.HostedZoneId,
-		MaxItems:         request.MaxItems,
-		NextDNSName:      nextDNSName,
-		NextHostedZoneId: nextHostedZoneId,
-	}, nil
+	return instance, nil
 }
 
-func (s *Service) ListResourceRecordSets(
-	request *aws53.ListResourceRecordSetsInput) (*ListRecordSetsOutput, error) {
+func (s *Service) ExportHostedZones() ([]HostedZoneExport, error) {
 
-	hz, err := s.dataStore.getHostedZone(aws.ToString(request.HostedZoneId))
+	zones, err := s.dataStore.findHostedZones(nil)
 	if err != nil {
 		return nil, err
 	}
 
-	records, err := s.dataStore.getResourceRecordSets(hz.Id)
-	if err != nil {
-		return nil, err
-	}
+	var result []HostedZoneExport
+
+	for _, zone := range zones {
 
-	slices.SortFunc(records, func(a, b ResourceRecordSetData) int {
-		n := strings.Compare(a.Name, b.Name)
-		if n == 0 {
-			return strings.Compare(string(a.Type), string(b.Type))
+		records, err := s.dataStore.getResourceRecordSets(zone.Id)
+		if err != nil {
+			return nil, err
 		}
-		return n
-	})
 
-	startIndex := s.findRecordSetIndex(records, request.StartRecordName, request.StartRecordType)
-	paginatedRecords, nextRecord := paginate(records[startIndex:], request.MaxItems)
+		result = append(result, HostedZoneExport{
+			HostedZone: zone,
+			RecordSets: records,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *Service) ExportZoneFile(zoneId string, w io.Writer) (string, error) {
 
-	result := ListRecordSetsOutput{
-		Records: paginatedRecords,
+	hz, err := s.dataStore.getHostedZone(zoneId)
+	if err != nil {
+		return "", err
 	}
 
-	if nextRecord != nil {
-		result.NextRecord = nextRecord.Name
-		result.NexType = nextRecord.Type
+	records, err := s.dataStore.getResourceRecordSets(hz.Id)
+	if err != nil {
+		return "", err
 	}
 
-	return &result, nil
+	return hz.Name, writeZoneFile(w, hz, records)
 }
 
-func (s *Service) ListTagsForResource(
-	request *aws53.ListTagsForResourceInput) (*aws53.ListTagsForResourceOutput, error) {
+func (s *Service) ImportZoneFile(r io.Reader, origin string, overwrite bool) ([]string, error) {
 
-	if request.ResourceType != awstypes.TagResourceTypeHostedzone {
-		return nil, ErrInvalidInput
+	name, records, err := parseZoneFile(r, origin)
+	if err != nil {
+		return nil, err
 	}
 
-	hz, err := s.dataStore.getHostedZone(aws.ToString(request.ResourceId))
+	zones, err := s.dataStore.findHostedZones(nil)
 	if err != nil {
 		return nil, err
 	}
 
-	result := aws53.ListTagsForResourceOutput{
-		ResourceTagSet: &awstypes.ResourceTagSet{
-			ResourceId:   request.ResourceId,
-			ResourceType: request.ResourceType,
-			Tags:         make([]awstypes.Tag, 0),
+	export := HostedZoneExport{
+		HostedZone: HostedZoneData{
+			CallerReference: "zonefile-" + time.Now().UTC().Format(time.RFC3339),
+			Id:              HostedZonePrefix + core.GenerateRandomString(14),
+			Name:            name,
+			DelegationSet: DelegationSetData{
+				NameServers: s.nsRecords,
+			},
 		},
+		RecordSets: records,
 	}
 
-	for _, tag := range hz.Tags {
-
-		awstag := awstypes.Tag{
-			Key:   aws.String(tag.Key),
-			Value: aws.String(tag.Value),
+	// a private zone may have the name of the public one, the public zone is imported into
+	var match *HostedZoneData
+	for i := range zones {
+		if zones[i].Name == name && (match == nil || match.Config.PrivateZone) {
+			match = &zones[i]
 		}
-
-		result.ResourceTagSet.Tags = append(result.ResourceTagSet.Tags, awstag)
+	}
+	if match != nil {
+		export.HostedZone = *match
 	}
 
-	return &result, nil
-}
-
-func (s *Service) LogKeys(writer io.Writer) error {
-	return s.dataStore.logKeys(writer)
+	return s.ImportHostedZones([]HostedZoneExport{export}, overwrite)
 }
 
-func (s *Service) UpdateHostedZoneComment(
-	request *aws53.UpdateHostedZoneCommentInput) (*aws53.UpdateHostedZoneCommentOutput, error) {
+// ListZoneChanges returns a hosted zone's change history, oldest first. Each change holds its
+// change batch and the record sets it removed and added, their values before and after.
+func (s *Service) ListZoneChanges(zoneId string) ([]ChangeInfoData, error) {
 
-	hz, err := s.dataStore.getHostedZone(aws.ToString(request.Id))
+	hz, err := s.dataStore.getHostedZone(zoneId)
 	if err != nil {
 		return nil, err
 	}
 
-	hz.Config.Comment = aws.ToString(request.Comment)
+	return s.dataStore.findZoneChanges(hz.Id)
+}
 
-	err = s.dataStore.updateHostedZone(hz)
-	if err != nil {
-		return nil, err
-	}
+// ZoneNameServers returns the name servers of the public hosted zone named name, the ones a
+// registrar delegates the domain to.
+func (s *Service) ZoneNameServers(name string) ([]string, error) {
 
-	count, err := s.dataStore.getRecordCount(hz.Id)
+	zones, err := s.dataStore.findHostedZones(nil)
 	if err != nil {
 		return nil, err
 	}
 
-	result := aws53.UpdateHostedZoneCommentOutput{
-		HostedZone: hz.toHostedZone(count),
+	name = normalizeDnsName(name)
+	for _, zone := range zones {
+		if zone.Name == name && !zone.Config.PrivateZone {
+			return zone.DelegationSet.NameServers, nil
+		}
 	}
 
-	return &result, nil
+	return nil, ErrNoSuchHostedZone
 }
 
-func (s *Service) ExportHostedZones() ([]HostedZoneExport, error) {
+// RollbackHostedZone returns a hosted zone's record sets to how they were right after
+// the change, by applying the inverse of the later changes as one new change.
+func (s *Service) RollbackHostedZone(zoneId string, changeId string) (*ChangeInfoData, error) {
 
-	zones, err := s.dataStore.findHostedZones(nil)
+	hz, err := s.dataStore.getHostedZone(zoneId)
 	if err != nil {
 		return nil, err
 	}
 
-	var result []HostedZoneExport
-
-	for _, zone := range zones {
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     "Rollback to " + strings.TrimPrefix(changeId, ChangeInfoPrefix),
+	}
 
-		records, err := s.dataStore.getResourceRecordSets(zone.Id)
-		if err != nil {
-			return nil, err
-		}
+	reverseZones, err := s.dataStore.rollbackRecordSets(hz, changeId, &ci)
+	if err != nil {
+		return nil, err
+	}
 
-		result = append(result, HostedZoneExport{
-			HostedZone: zone,
-			RecordSets: records,
-		})
+	s.zoneChanged(hz)
+	for i := range reverseZones {
+		s.zoneChanged(&reverseZones[i])
 	}
 
-	return result, nil
+	return &ci, nil
 }
 
 func (s *Service) DeleteAllData() error {
+	defer s.queryLog.configsChanged()
 	return s.dataStore.deleteAll()
 }
 
@@ -480,15 +2086,15 @@ func (s *Service) ImportHostedZones(
 
 	var failures []string
 
-	ci := ChangeInfoData{
-		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
-		Status:      awstypes.ChangeStatusInsync,
-		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
-		Comment:     "Import is complete.",
-	}
-
 	for _, zone := range zones {
 
+		ci := ChangeInfoData{
+			Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+			Status:      awstypes.ChangeStatusInsync,
+			SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+			Comment:     "Import is complete.",
+		}
+
 		var rsetChanges []ChangeData
 		for _, record := range zone.RecordSets {
 
@@ -504,137 +2110,239 @@ func (s *Service) ImportHostedZones(
 		if overwrite {
 			// if overwrite is true, we update the hosted zone
 			// and upsert the records
-			err := s.dataStore.updateHostedZone(&zone.HostedZone)
+			reverseZones, err := s.dataStore.importHostedZone(&zone.HostedZone, rsetChanges, &ci)
 			if err != nil {
 				log.Println("Error importing zone:", err)
 				failures = append(failures, zone.HostedZone.Name)
 				continue
 			}
 
-			ci.Id = ChangeInfoPrefix + core.GenerateRandomString(14)
-			err = s.dataStore.putRecordSets(&zone.HostedZone, rsetChanges, &ci)
-			if err != nil {
-				log.Println("Error importing records:", err)
-				failures = append(failures, zone.HostedZone.Name)
-				continue
+			s.zoneChanged(&zone.HostedZone)
+			for i := range reverseZones {
+				s.zoneChanged(&reverseZones[i])
 			}
 
 		} else {
 			// if overwrite is false, we try to create the hosted zone
 			// if it fails, we add it to failures
-			ci.Id = ChangeInfoPrefix + core.GenerateRandomString(14)
-			err := s.dataStore.putHostedZone(&zone.HostedZone, rsetChanges, &ci)
+			var err error
+			if len(rsetChanges) > 0 {
+				err = validateChangeBatch(&zone.HostedZone, nil, rsetChanges)
+			}
+			if err == nil {
+				err = s.dataStore.putHostedZone(&zone.HostedZone, rsetChanges, &ci)
+			}
 			if err != nil {
 				log.Println("Error importing zone:", err)
 				failures = append(failures, zone.HostedZone.Name)
 				continue
 			}
+
+			s.zoneChanged(&zone.HostedZone)
 		}
 	}
 
 	return failures, nil
 }
 
-func (s *Service) populateRecordCounts(zones []HostedZoneData) ([]awstypes.HostedZone, error) {
+// onZoneChanged registers fn to be called after the records of a hosted zone changed.
+func (s *Service) onZoneChanged(fn func(hz *HostedZoneData)) {
+	s.zoneListeners = append(s.zoneListeners, fn)
+}
 
-	awsZones := make([]awstypes.HostedZone, 0, len(zones))
-	for _, hz := range zones {
-		awshz, cerr := s.populateRecordCount(&hz)
-		if cerr != nil {
-			return nil, cerr
-		}
-		awsZones = append(awsZones, *awshz)
+func (s *Service) zoneChanged(hz *HostedZoneData) {
+	for _, fn := range s.zoneListeners {
+		fn(hz)
 	}
-
-	return awsZones, nil
 }
 
-func (s *Service) populateRecordCount(hz *HostedZoneData) (*awstypes.HostedZone, error) {
+// applyTrafficPolicy replaces the record sets of an instance with the ones its traffic policy
+// creates, as one change to the hosted zone, and stores the instance.
+func (s *Service) applyTrafficPolicy(
+	hz *HostedZoneData, instance *TrafficPolicyInstanceData, tp *TrafficPolicyData, overwrite bool) error {
 
-	count, cerr := s.dataStore.getRecordCount(hz.Id)
-	if cerr != nil {
-		return nil, cerr
+	doc, err := parseTrafficPolicyDocument(tp.Document)
+	if err != nil {
+		return err
 	}
 
-	return hz.toHostedZone(count), nil
-}
-
-func (s *Service) findHostedZoneByMarker(zones []HostedZoneData, marker *string) int {
-	if marker == nil {
-		return 0
+	keyOf := func(rrset *ResourceRecordSetData) string {
+		header, err := convertToKey(hz.Name, rrset.Name, rrset.Type, rrset.SetIdentifier)
+		if err != nil {
+			return ""
+		}
+		return header.rrkey
 	}
 
-	m := aws.ToString(marker)
-	if !strings.HasPrefix(m, HostedZonePrefix) {
-		m = HostedZonePrefix + m
+	created := doc.recordSets(hz, instance)
+	wanted := make(map[string]bool, len(created))
+	for i := range created {
+		wanted[keyOf(&created[i])] = true
 	}
 
-	for i, zone := range zones {
-		if zone.Id >= m {
-			return i
+	// the changes are made from, and validated against, the record sets they're applied to
+	change := func(records []ResourceRecordSetData) ([]ChangeData, error) {
+		var changes []ChangeData
+		owned := make(map[string]bool)
+		for _, rrset := range records {
+			if aws.ToString(rrset.TrafficPolicyInstanceId) != instance.Id {
+				continue
+			}
+			owned[keyOf(&rrset)] = true
+			if !wanted[keyOf(&rrset)] {
+				changes = append(changes, ChangeData{Action: awstypes.ChangeActionDelete, ResourceRecordSet: &rrset})
+			}
+		}
+
+		for i := range created {
+			action := awstypes.ChangeActionCreate
+			if owned[keyOf(&created[i])] {
+				action = awstypes.ChangeActionUpsert
+			}
+			changes = append(changes, ChangeData{Action: action, ResourceRecordSet: &created[i]})
 		}
+
+		return changes, validateChangeBatch(hz, records, changes)
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     fmt.Sprintf("Traffic policy %s version %d", tp.Id, tp.Version),
+	}
+
+	err = s.dataStore.putTrafficPolicyInstance(instance, overwrite, hz, change, &ci)
+	if err != nil {
+		return err
 	}
-	return len(zones)
+
+	s.zoneChanged(hz)
+	return nil
 }
 
-func (s *Service) findHostedZoneByName(
-	zones []HostedZoneData, dnsName *string, hostedZoneId *string) int {
-	if dnsName == nil {
-		return 0
+// trafficPolicyVersions returns the versions of a traffic policy, oldest first.
+func (s *Service) trafficPolicyVersions(id string) ([]TrafficPolicyData, error) {
+
+	policies, err := s.dataStore.findTrafficPolicies()
+	if err != nil {
+		return nil, err
 	}
 
-	name := strings.ToLower(aws.ToString(dnsName))
-	if !strings.HasSuffix(name, ".") {
-		name += "."
+	policies = slices.DeleteFunc(policies, func(tp TrafficPolicyData) bool { return tp.Id != id })
+	if len(policies) == 0 {
+		return nil, ErrNoSuchTrafficPolicy
 	}
 
-	id := aws.ToString(hostedZoneId)
-	if id != "" && !strings.HasPrefix(id, HostedZonePrefix) {
-		id = HostedZonePrefix + id
+	slices.SortFunc(policies, compareTrafficPolicies)
+	return policies, nil
+}
+
+// listTrafficPolicyInstances pages through the instances keep accepts, ordered by hosted zone,
+// name and type. The markers are those of the first instance of the page.
+func (s *Service) listTrafficPolicyInstances(keep func(*TrafficPolicyInstanceData) bool, zoneMarker *string,
+	nameMarker *string, typeMarker awstypes.RRType, maxItems *int32) (*ListTrafficPolicyInstancesOutput, error) {
+
+	instances, err := s.dataStore.findTrafficPolicyInstances()
+	if err != nil {
+		return nil, err
 	}
 
-	for i, zone := range zones {
-		if zone.Name > name {
-			return i
-		}
-		if zone.Name == name {
-			if id == "" || zone.Id >= id {
-				return i
-			}
+	instances = slices.DeleteFunc(instances, func(instance TrafficPolicyInstanceData) bool { return !keep(&instance) })
+	slices.SortFunc(instances, compareTrafficPolicyInstances)
+
+	marker := TrafficPolicyInstanceData{
+		HostedZoneId:      strings.TrimPrefix(aws.ToString(zoneMarker), HostedZonePrefix),
+		Name:              aws.ToString(nameMarker),
+		TrafficPolicyType: typeMarker,
+	}
+	if marker.Name != "" {
+		marker.Name = normalizeDnsName(marker.Name)
+	}
+
+	startIndex := len(instances)
+	for i, instance := range instances {
+		if compareTrafficPolicyInstances(instance, marker) >= 0 {
+			startIndex = i
+			break
 		}
 	}
-	return len(zones)
+
+	paginatedInstances, nextInstance := paginate(instances[startIndex:], maxItems)
+
+	return &ListTrafficPolicyInstancesOutput{
+		TrafficPolicyInstances: paginatedInstances,
+		MaxItems:               maxItems,
+		Next:                   nextInstance,
+	}, nil
 }
 
-func (s *Service) findRecordSetIndex(
-	records []ResourceRecordSetData, startName *string, startType awstypes.RRType) int {
-	if startName == nil || *startName == "" {
-		return 0
+func compareTrafficPolicies(a, b TrafficPolicyData) int {
+	return cmp.Or(strings.Compare(a.Id, b.Id), cmp.Compare(a.Version, b.Version))
+}
+
+func compareTrafficPolicyInstances(a, b TrafficPolicyInstanceData) int {
+	return cmp.Or(strings.Compare(a.HostedZoneId, b.HostedZoneId), strings.Compare(a.Name, b.Name),
+		strings.Compare(string(a.TrafficPolicyType), string(b.TrafficPolicyType)))
+}
+
+// nsGroupFor returns the configured name servers for a new delegation set, see core.DnsDelegationSet.
+func (s *Service) nsGroupFor(callerReference string) []string {
+
+	for _, group := range s.nsGroups {
+		if callerReference == group.Name || strings.HasPrefix(callerReference, group.Name+"-") {
+			return group.NameServers
+		}
 	}
 
-	name := strings.ToLower(aws.ToString(startName))
-	if !strings.HasSuffix(name, ".") {
-		name += "."
+	return s.nsRecords
+}
+
+// changeTags applies a ChangeTagsForResource request, an added key replaces the existing tag.
+func changeTags(tags []core.ResourceTag, request *aws53.ChangeTagsForResourceInput) []core.ResourceTag {
+
+	for _, tag := range request.RemoveTagKeys {
+		tags = slices.DeleteFunc(tags, func(mytag core.ResourceTag) bool {
+			return mytag.Key == tag
+		})
 	}
 
-	for i, rr := range records {
-		if rr.Name > name {
-			return i
-		}
-		if rr.Name == name {
-			if startType == "" || string(rr.Type) >= string(startType) {
-				return i
-			}
+	for _, tag := range request.AddTags {
+
+		newtag := core.ResourceTag{
+			Key:   aws.ToString(tag.Key),
+			Value: aws.ToString(tag.Value),
 		}
+
+		tags = slices.DeleteFunc(tags, func(mytag core.ResourceTag) bool {
+			return mytag.Key == newtag.Key
+		})
+		tags = append(tags, newtag)
 	}
-	return len(records)
+
+	return tags
 }
 
-func paginate[T any](items []T, maxItems *int32) ([]T, *T) {
-	limit := 100
+func toHostedZones(zones []HostedZoneData) []awstypes.HostedZone {
+
+	awsZones := make([]awstypes.HostedZone, 0, len(zones))
+	for _, hz := range zones {
+		awsZones = append(awsZones, *hz.toHostedZone())
+	}
+
+	return awsZones
+}
+
+// pageSize returns the number of items a list request asks for, 100 by default.
+func pageSize(maxItems *int32) int {
 	if maxItems != nil {
-		limit = int(*maxItems)
+		return int(*maxItems)
 	}
+	return 100
+}
+
+func paginate[T any](items []T, maxItems *int32) ([]T, *T) {
+	limit := pageSize(maxItems)
 
 	if len(items) > limit {
 		return items[:limit], &items[limit]
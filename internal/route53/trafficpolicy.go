@@ -0,0 +1,405 @@
+package route53
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"home-fern/internal/core"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+const (
+	trafficPolicyFormatVersion = "2015-10-01"
+
+	trafficPolicyEndpointValue = "value"
+
+	trafficPolicyInstanceApplied = "Applied"
+)
+
+// trafficPolicyRecordTypes are the DNS types a traffic policy can create.
+var trafficPolicyRecordTypes = []awstypes.RRType{
+	awstypes.RRTypeA, awstypes.RRTypeAaaa, awstypes.RRTypeCaa, awstypes.RRTypeCname, awstypes.RRTypeMx,
+	awstypes.RRTypeNaptr, awstypes.RRTypePtr, awstypes.RRTypeSpf, awstypes.RRTypeSrv, awstypes.RRTypeTxt,
+}
+
+// trafficPolicyLabel is what's left of a rule ID in the names of its record sets.
+var trafficPolicyLabel = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// policyBool accepts the true and "true" the traffic policy editor writes alike.
+type policyBool bool
+
+func (b *policyBool) UnmarshalJSON(data []byte) error {
+
+	value, err := strconv.ParseBool(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return fmt.Errorf("%s isn't true or false", data)
+	}
+
+	*b = policyBool(value)
+	return nil
+}
+
+type trafficPolicyDocument struct {
+	AWSPolicyFormatVersion string
+	RecordType             awstypes.RRType
+	StartEndpoint          string
+	StartRule              string
+	Endpoints              map[string]trafficPolicyEndpoint
+	Rules                  map[string]trafficPolicyRule
+}
+
+type trafficPolicyEndpoint struct {
+	Type   string
+	Region string
+	Value  string
+}
+
+type trafficPolicyRule struct {
+	RuleType              string
+	Primary               *trafficPolicyItem
+	Secondary             *trafficPolicyItem
+	Items                 []trafficPolicyItem
+	Locations             []trafficPolicyItem
+	Regions               []trafficPolicyItem
+	GeoproximityLocations []trafficPolicyItem
+}
+
+// trafficPolicyItem is one choice of a rule, only the fields of the rule's type are used.
+type trafficPolicyItem struct {
+	EndpointReference    string
+	RuleReference        string
+	EvaluateTargetHealth *policyBool
+	HealthCheck          string
+	Weight               json.Number
+	IsDefault            policyBool
+	Continent            string
+	Country              string
+	Subdivision          string
+	Region               string
+	Latitude             json.Number
+	Longitude            json.Number
+	Bias                 json.Number
+}
+
+// routedItem is a rule's item with the set identifier of its record set.
+type routedItem struct {
+	trafficPolicyItem
+	ruleType      string
+	setIdentifier string
+	failover      awstypes.ResourceRecordSetFailover
+}
+
+// parseTrafficPolicyDocument parses and checks a traffic policy document. Endpoints other
+// than values would be aliases to AWS resources, which home-fern can't answer for.
+func parseTrafficPolicyDocument(document string) (*trafficPolicyDocument, error) {
+
+	var doc trafficPolicyDocument
+	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTrafficPolicyDocument, err.Error())
+	}
+
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidTrafficPolicyDocument, fmt.Sprintf(format, args...))
+	}
+
+	if doc.AWSPolicyFormatVersion != trafficPolicyFormatVersion {
+		return nil, fail("AWSPolicyFormatVersion must be %s", trafficPolicyFormatVersion)
+	}
+
+	if !slices.Contains(trafficPolicyRecordTypes, doc.RecordType) {
+		return nil, fail("RecordType %q isn't supported by traffic policies", doc.RecordType)
+	}
+
+	switch {
+	case (doc.StartEndpoint == "") == (doc.StartRule == ""):
+		return nil, fail("the document needs exactly one of StartEndpoint and StartRule")
+	case doc.StartEndpoint != "":
+		if _, found := doc.Endpoints[doc.StartEndpoint]; !found {
+			return nil, fail("StartEndpoint %s isn't one of the Endpoints", doc.StartEndpoint)
+		}
+	default:
+		if _, found := doc.Rules[doc.StartRule]; !found {
+			return nil, fail("StartRule %s isn't one of the Rules", doc.StartRule)
+		}
+	}
+
+	for id, endpoint := range doc.Endpoints {
+		if endpoint.Type != trafficPolicyEndpointValue {
+			return nil, fail("endpoint %s has Type %q, only %q endpoints are supported", id, endpoint.Type,
+				trafficPolicyEndpointValue)
+		}
+		if problem := validateRecordValue(id, doc.RecordType, endpoint.Value); problem != "" {
+			return nil, fail("endpoint %s has the invalid %s value %q: %s", id, doc.RecordType, endpoint.Value, problem)
+		}
+	}
+
+	for id := range doc.Rules {
+		items, err := doc.ruleItems(id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			switch {
+			case (item.EndpointReference == "") == (item.RuleReference == ""):
+				return nil, fail("rule %s needs exactly one of EndpointReference and RuleReference for %s",
+					id, item.setIdentifier)
+			case item.EndpointReference != "":
+				if _, found := doc.Endpoints[item.EndpointReference]; !found {
+					return nil, fail("rule %s references the unknown endpoint %s", id, item.EndpointReference)
+				}
+			default:
+				if _, found := doc.Rules[item.RuleReference]; !found {
+					return nil, fail("rule %s references the unknown rule %s", id, item.RuleReference)
+				}
+				if item.ruleType == "multivalue" {
+					return nil, fail("multivalue rule %s can only reference endpoints", id)
+				}
+			}
+		}
+	}
+
+	// rules must form a tree below the start rule
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		if slices.Contains(path, id) {
+			return fail("rule %s references itself through %s", id, strings.Join(path, ", "))
+		}
+
+		items, _ := doc.ruleItems(id)
+		for _, item := range items {
+			if item.RuleReference != "" {
+				if err := visit(item.RuleReference, append(path, id)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if doc.StartRule != "" {
+		if err := visit(doc.StartRule, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return &doc, nil
+}
+
+// ruleItems returns a rule's items in the document's order with their set identifiers.
+func (doc *trafficPolicyDocument) ruleItems(id string) ([]routedItem, error) {
+
+	rule := doc.Rules[id]
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidTrafficPolicyDocument, fmt.Sprintf(format, args...))
+	}
+
+	var result []routedItem
+	list := func(items []trafficPolicyItem, name string) ([]routedItem, error) {
+		if len(items) == 0 {
+			return nil, fail("%s rule %s needs at least one item in %s", rule.RuleType, id, name)
+		}
+		for i, item := range items {
+			result = append(result, routedItem{
+				trafficPolicyItem: item,
+				ruleType:          rule.RuleType,
+				setIdentifier:     fmt.Sprintf("%s-%s-%d", id, rule.RuleType, i+1),
+			})
+		}
+		return result, nil
+	}
+
+	switch rule.RuleType {
+	case "failover":
+		if rule.Primary == nil || rule.Secondary == nil {
+			return nil, fail("failover rule %s needs a Primary and a Secondary", id)
+		}
+		return []routedItem{{
+			trafficPolicyItem: *rule.Primary,
+			ruleType:          rule.RuleType,
+			setIdentifier:     id + "-primary",
+			failover:          awstypes.ResourceRecordSetFailoverPrimary,
+		}, {
+			trafficPolicyItem: *rule.Secondary,
+			ruleType:          rule.RuleType,
+			setIdentifier:     id + "-secondary",
+			failover:          awstypes.ResourceRecordSetFailoverSecondary,
+		}}, nil
+
+	case "weighted":
+		if _, err := list(rule.Items, "Items"); err != nil {
+			return nil, err
+		}
+		for i := range result {
+			weight, err := strconv.Atoi(result[i].Weight.String())
+			if err != nil || weight < 0 || weight > 255 {
+				return nil, fail("weighted rule %s has the invalid Weight %q, it must be between 0 and 255",
+					id, result[i].Weight)
+			}
+		}
+		return result, nil
+
+	case "multivalue":
+		return list(rule.Items, "Items")
+
+	case "geo":
+		if _, err := list(rule.Locations, "Locations"); err != nil {
+			return nil, err
+		}
+		for _, item := range result {
+			if !item.IsDefault && item.Continent == "" && item.Country == "" {
+				return nil, fail("geo rule %s needs a Continent, a Country or IsDefault for each location", id)
+			}
+		}
+		return result, nil
+
+	case "latency":
+		if _, err := list(rule.Regions, "Regions"); err != nil {
+			return nil, err
+		}
+		for _, item := range result {
+			if item.Region == "" {
+				return nil, fail("latency rule %s needs a Region for each item", id)
+			}
+		}
+		return result, nil
+
+	case "geoproximity":
+		if _, err := list(rule.GeoproximityLocations, "GeoproximityLocations"); err != nil {
+			return nil, err
+		}
+		for _, item := range result {
+			if item.Region == "" && (item.Latitude == "" || item.Longitude == "") {
+				return nil, fail("geoproximity rule %s needs a Region or a Latitude and Longitude for each item", id)
+			}
+		}
+		return result, nil
+	}
+
+	return nil, fail("rule %s has the unknown RuleType %q", id, rule.RuleType)
+}
+
+// recordSets returns the record sets an instance of the policy creates. The start rule's record
+// sets get the instance's name, rules referenced by another rule get a name of their own below it
+// which the referencing record sets alias.
+func (doc *trafficPolicyDocument) recordSets(hz *HostedZoneData, instance *TrafficPolicyInstanceData) []ResourceRecordSetData {
+
+	name := strings.ToLower(dns.Fqdn(instance.Name))
+	base := ResourceRecordSetData{
+		Type:                    doc.RecordType,
+		TrafficPolicyInstanceId: aws.String(instance.Id),
+	}
+
+	if doc.StartEndpoint != "" {
+		rrset := base
+		rrset.Name = name
+		doc.setEndpoint(&rrset, doc.StartEndpoint, instance.TTL)
+		return []ResourceRecordSetData{rrset}
+	}
+
+	var result []ResourceRecordSetData
+	names := map[string]string{doc.StartRule: name}
+	pending := []string{doc.StartRule}
+
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+
+		items, _ := doc.ruleItems(id)
+		for _, item := range items {
+
+			rrset := base
+			rrset.Name = names[id]
+			rrset.SetIdentifier = aws.String(item.setIdentifier)
+			if item.HealthCheck != "" {
+				rrset.HealthCheckId = aws.String(item.HealthCheck)
+			}
+			item.route(&rrset)
+
+			if item.EndpointReference != "" {
+				doc.setEndpoint(&rrset, item.EndpointReference, instance.TTL)
+				result = append(result, rrset)
+				continue
+			}
+
+			target, found := names[item.RuleReference]
+			if !found {
+				target = fmt.Sprintf("_%s.%s", ruleLabel(item.RuleReference, len(names)), name)
+				names[item.RuleReference] = target
+				pending = append(pending, item.RuleReference)
+			}
+
+			// a rule only answers while one of its choices is healthy
+			evaluate := item.EvaluateTargetHealth == nil || bool(*item.EvaluateTargetHealth)
+			rrset.AliasTarget = &awstypes.AliasTarget{
+				DNSName:              aws.String(target),
+				HostedZoneId:         aws.String(strings.TrimPrefix(hz.Id, HostedZonePrefix)),
+				EvaluateTargetHealth: evaluate,
+			}
+			result = append(result, rrset)
+		}
+	}
+
+	return result
+}
+
+func (doc *trafficPolicyDocument) setEndpoint(rrset *ResourceRecordSetData, id string, ttl int64) {
+	rrset.TTL = aws.Int64(ttl)
+	rrset.ResourceRecords = []awstypes.ResourceRecord{{Value: aws.String(doc.Endpoints[id].Value)}}
+}
+
+// route sets the routing policy of the item's rule on its record set.
+func (item *routedItem) route(rrset *ResourceRecordSetData) {
+
+	switch item.ruleType {
+	case "failover":
+		rrset.Failover = item.failover
+
+	case "weighted":
+		weight, _ := strconv.ParseInt(item.Weight.String(), 10, 64)
+		rrset.Weight = aws.Int64(weight)
+
+	case "multivalue":
+		rrset.MultiValueAnswer = aws.Bool(true)
+
+	case "geo":
+		rrset.GeoLocation = &awstypes.GeoLocation{CountryCode: aws.String("*")}
+		if !item.IsDefault {
+			rrset.GeoLocation = &awstypes.GeoLocation{
+				ContinentCode:   core.StringOrNil(item.Continent),
+				CountryCode:     core.StringOrNil(item.Country),
+				SubdivisionCode: core.StringOrNil(item.Subdivision),
+			}
+		}
+
+	case "latency":
+		rrset.Region = awstypes.ResourceRecordSetRegion(item.Region)
+
+	case "geoproximity":
+		location := awstypes.GeoProximityLocation{AWSRegion: core.StringOrNil(item.Region)}
+		if item.Region == "" {
+			location.Coordinates = &awstypes.Coordinates{
+				Latitude:  aws.String(item.Latitude.String()),
+				Longitude: aws.String(item.Longitude.String()),
+			}
+		}
+		if bias, err := strconv.Atoi(item.Bias.String()); err == nil {
+			location.Bias = aws.Int32(int32(bias))
+		}
+		rrset.GeoProximityLocation = &location
+	}
+}
+
+// ruleLabel turns a rule ID into a DNS label, n keeps labels of similar IDs apart.
+func ruleLabel(id string, n int) string {
+
+	label := strings.Trim(trafficPolicyLabel.ReplaceAllString(strings.ToLower(id), "-"), "-")
+	return fmt.Sprintf("%.56s-%d", label, n)
+}
@@ -9,10 +9,16 @@ import (
 )
 
 type ChangeInfoData struct {
-	Comment     string
-	Id          string
-	Status      awstypes.ChangeStatus
-	SubmittedAt string
+	Comment      string
+	Id           string
+	Status       awstypes.ChangeStatus
+	SubmittedAt  string
+	HostedZoneId string                  `json:",omitempty"`
+	Serial       uint32                  `json:",omitempty"`
+	Sequence     uint64                  `json:",omitempty"`
+	Removed      []ResourceRecordSetData `json:",omitempty"`
+	Added        []ResourceRecordSetData `json:",omitempty"`
+	ChangeBatch  *ChangeBatchWrapper     `json:",omitempty"`
 }
 
 func (ci *ChangeInfoData) toChangeInfo() *awstypes.ChangeInfo {
@@ -36,9 +42,9 @@ type HostedZoneConfigData struct {
 }
 
 type DelegationSetData struct {
-	NameServers     []string
-	CallerReference string `json:"CallerReference,omitempty"`
-	Id              string `json:"Id,omitempty"`
+	NameServers     []string `xml:"NameServers>NameServer"`
+	CallerReference string   `xml:",omitempty" json:"CallerReference,omitempty"`
+	Id              string   `xml:",omitempty" json:"Id,omitempty"`
 }
 
 func (ds *DelegationSetData) toDelegationSet() *awstypes.DelegationSet {
@@ -61,9 +67,13 @@ type HostedZoneData struct {
 	Id              string
 	Name            string
 	Tags            []core.ResourceTag `json:",omitempty"`
+	VPCs            []awstypes.VPC     `json:",omitempty"`
+
+	// ResourceRecordSetCount is kept up to date by every change to the zone's record sets
+	ResourceRecordSetCount int64
 }
 
-func (hz *HostedZoneData) toHostedZone(rrcount int) *awstypes.HostedZone {
+func (hz *HostedZoneData) toHostedZone() *awstypes.HostedZone {
 
 	result := awstypes.HostedZone{
 		CallerReference: aws.String(hz.CallerReference),
@@ -74,7 +84,7 @@ func (hz *HostedZoneData) toHostedZone(rrcount int) *awstypes.HostedZone {
 			PrivateZone: hz.Config.PrivateZone,
 		},
 		LinkedService:          nil,
-		ResourceRecordSetCount: aws.Int64(int64(rrcount)),
+		ResourceRecordSetCount: aws.Int64(max(2, hz.ResourceRecordSetCount)),
 	}
 
 	return &result
@@ -99,9 +109,10 @@ type ResourceRecordSetData struct {
 }
 
 type ListRecordSetsOutput struct {
-	Records    []ResourceRecordSetData
-	NextRecord string
-	NexType    awstypes.RRType
+	Records        []ResourceRecordSetData
+	NextRecord     string
+	NexType        awstypes.RRType
+	NextIdentifier string
 }
 
 type ChangeData struct {
@@ -123,3 +134,193 @@ type HostedZoneExport struct {
 	HostedZone HostedZoneData
 	RecordSets []ResourceRecordSetData
 }
+
+type HealthCheckConfigData struct {
+	IPAddress                    *string                               `json:",omitempty"`
+	Port                         *int32                                `json:",omitempty"`
+	Type                         awstypes.HealthCheckType              `json:",omitempty"`
+	ResourcePath                 *string                               `json:",omitempty"`
+	FullyQualifiedDomainName     *string                               `json:",omitempty"`
+	SearchString                 *string                               `json:",omitempty"`
+	RequestInterval              *int32                                `json:",omitempty"`
+	FailureThreshold             *int32                                `json:",omitempty"`
+	MeasureLatency               *bool                                 `json:",omitempty"`
+	Inverted                     *bool                                 `json:",omitempty"`
+	Disabled                     *bool                                 `json:",omitempty"`
+	HealthThreshold              *int32                                `json:",omitempty"`
+	ChildHealthChecks            []string                              `xml:"ChildHealthChecks>ChildHealthCheck" json:",omitempty"`
+	EnableSNI                    *bool                                 `json:",omitempty"`
+	Regions                      []awstypes.HealthCheckRegion          `xml:"Regions>Region" json:",omitempty"`
+	AlarmIdentifier              *awstypes.AlarmIdentifier             `json:",omitempty"`
+	InsufficientDataHealthStatus awstypes.InsufficientDataHealthStatus `xml:",omitempty" json:",omitempty"`
+	RoutingControlArn            *string                               `json:",omitempty"`
+}
+
+type HealthCheckData struct {
+	Id                 string
+	CallerReference    string
+	HealthCheckConfig  HealthCheckConfigData
+	HealthCheckVersion int64
+	Tags               []core.ResourceTag `xml:"-" json:",omitempty"`
+}
+
+type CreateHealthCheckRequest struct {
+	CallerReference   string
+	HealthCheckConfig HealthCheckConfigData
+}
+
+type UpdateHealthCheckRequest struct {
+	HealthCheckId                string `xml:"-"`
+	HealthCheckVersion           *int64
+	IPAddress                    *string
+	Port                         *int32
+	ResourcePath                 *string
+	FullyQualifiedDomainName     *string
+	SearchString                 *string
+	FailureThreshold             *int32
+	Inverted                     *bool
+	Disabled                     *bool
+	HealthThreshold              *int32
+	ChildHealthChecks            []string `xml:"ChildHealthChecks>ChildHealthCheck"`
+	EnableSNI                    *bool
+	Regions                      []awstypes.HealthCheckRegion `xml:"Regions>Region"`
+	AlarmIdentifier              *awstypes.AlarmIdentifier
+	InsufficientDataHealthStatus awstypes.InsufficientDataHealthStatus
+	ResetElements                []awstypes.ResettableElementName `xml:"ResetElements>ResettableElementName"`
+}
+
+// DnssecData is a hosted zone's signing status. The zone signing key is
+// generated by home-fern, PKCS #8 DER encoded and wrapped by the KMS root key.
+// Earlier versions stored it unwrapped and base64 encoded in ZoneSigningKey.
+type DnssecData struct {
+	HostedZoneId          string
+	ServeSignature        string
+	StatusMessage         string `json:",omitempty"`
+	ZoneSigningKey        string `json:",omitempty"`
+	WrappedZoneSigningKey []byte `json:",omitempty"`
+}
+
+// KeySigningKeyData is a key signing key, its key pair lives in the kms package.
+type KeySigningKeyData struct {
+	HostedZoneId     string
+	Name             string
+	KmsArn           string
+	Status           string
+	CreatedDate      time.Time
+	LastModifiedDate time.Time
+}
+
+type TrafficPolicyData struct {
+	Id       string
+	Version  int32
+	Name     string
+	Type     awstypes.RRType
+	Document string
+	Comment  string `xml:",omitempty" json:",omitempty"`
+}
+
+type TrafficPolicySummaryData struct {
+	Id                 string
+	Name               string
+	Type               awstypes.RRType
+	LatestVersion      int32
+	TrafficPolicyCount int32
+}
+
+type TrafficPolicyInstanceData struct {
+	Id                   string
+	HostedZoneId         string
+	Name                 string
+	TTL                  int64
+	State                string
+	Message              string
+	TrafficPolicyId      string
+	TrafficPolicyVersion int32
+	TrafficPolicyType    awstypes.RRType
+}
+
+type QueryLoggingConfigData struct {
+	Id                        string
+	HostedZoneId              string
+	CloudWatchLogsLogGroupArn string
+}
+
+// CidrCollectionData keeps the collection's locations with it, each location names its CIDR blocks.
+type CidrCollectionData struct {
+	Arn             string
+	Id              string
+	Name            string
+	Version         int64
+	CallerReference string              `xml:"-"`
+	Locations       map[string][]string `xml:"-" json:",omitempty"`
+}
+
+type CidrBlockSummaryData struct {
+	CidrBlock    string
+	LocationName string
+}
+
+type ChangeCidrCollectionRequest struct {
+	Id                string `xml:"-"`
+	CollectionVersion *int64
+	Changes           []CidrCollectionChangeData `xml:"Changes>member"`
+}
+
+type CidrCollectionChangeData struct {
+	Action       awstypes.CidrCollectionChangeAction
+	LocationName string
+	CidrList     []string `xml:"CidrList>Cidr"`
+}
+
+type ListCidrCollectionsOutput struct {
+	CidrCollections []CidrCollectionData
+	NextToken       *string
+}
+
+type ListCidrBlocksOutput struct {
+	CidrBlocks []CidrBlockSummaryData
+	NextToken  *string
+}
+
+type ListCidrLocationsOutput struct {
+	LocationNames []string
+	NextToken     *string
+}
+
+type ListQueryLoggingConfigsOutput struct {
+	QueryLoggingConfigs []QueryLoggingConfigData
+	MaxResults          *int32
+	NextToken           *string
+}
+
+type ListDelegationSetsOutput struct {
+	DelegationSets []DelegationSetData
+	Marker         *string
+	MaxItems       *int32
+	NextMarker     *string
+}
+
+type ListHealthChecksOutput struct {
+	HealthChecks []HealthCheckData
+	Marker       *string
+	MaxItems     *int32
+	NextMarker   *string
+}
+
+type ListTrafficPoliciesOutput struct {
+	TrafficPolicySummaries []TrafficPolicySummaryData
+	MaxItems               *int32
+	NextMarker             *string
+}
+
+type ListTrafficPolicyVersionsOutput struct {
+	TrafficPolicies []TrafficPolicyData
+	MaxItems        *int32
+	NextMarker      *string
+}
+
+type ListTrafficPolicyInstancesOutput struct {
+	TrafficPolicyInstances []TrafficPolicyInstanceData
+	MaxItems               *int32
+	Next                   *TrafficPolicyInstanceData
+}
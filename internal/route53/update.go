@@ -0,0 +1,436 @@
+package route53
+
+import (
+	"errors"
+	"fmt"
+	"home-fern/internal/core"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+// updateRRset is the working copy of a record set while an UPDATE is applied.
+type updateRRset struct {
+	original *ResourceRecordSetData
+	rrs      []dns.RR
+	ttl      uint32
+	touched  bool
+}
+
+// serveUpdate applies an RFC 2136 UPDATE signed with one of the zone's updateKeys
+// as a single ChangeResourceRecordSets batch.
+func (d *DnsServer) serveUpdate(w dns.ResponseWriter, r *dns.Msg) {
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		m.SetRcode(r, dns.RcodeFormatError)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	zoneName := normalizeDnsName(r.Question[0].Name)
+	ip := remoteIP(w.RemoteAddr())
+
+	rcode, err := d.service.applyUpdate(zoneName, ip, r, w.TsigStatus())
+
+	if err != nil {
+		log.Println("Error:", err)
+		rcode = dns.RcodeServerFailure
+	}
+
+	log.Printf("DNS update of %s from %s: %s", zoneName, ip, dns.RcodeToString[rcode])
+
+	m.Rcode = rcode
+	d.writeMsg(w, r, m)
+}
+
+// applyUpdate evaluates the prerequisites against the zone and applies the update section in
+// one transaction, so the zone can't change in between.
+func (s *Service) applyUpdate(zoneName string, clientIP net.IP, r *dns.Msg, tsigStatus error) (int, error) {
+
+	zone, err := s.findVisibleZone(zoneName, clientIP)
+	if err != nil {
+		return dns.RcodeServerFailure, err
+	}
+
+	if zone == nil || zone.Name != zoneName {
+		return dns.RcodeNotAuth, nil
+	}
+
+	cfg, found := s.zoneConfigs[zoneName]
+	if !found || !s.tsigKeyAllowed(cfg.UpdateKeys, r, tsigStatus) {
+		return dns.RcodeRefused, nil
+	}
+
+	for _, rr := range r.Ns {
+		if rcode := prescanUpdate(zoneName, rr); rcode != dns.RcodeSuccess {
+			return rcode, nil
+		}
+	}
+
+	ci := ChangeInfoData{
+		Id:          ChangeInfoPrefix + core.GenerateRandomString(14),
+		Status:      awstypes.ChangeStatusInsync,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		Comment:     fmt.Sprintf("DNS UPDATE signed by %s", normalizeDnsName(r.IsTsig().Hdr.Name)),
+	}
+
+	rcode := dns.RcodeSuccess
+	changed := false
+	reverseZones, err := s.dataStore.changeRecordSets(zone, &ci, func(records []ResourceRecordSetData) ([]ChangeData, error) {
+		var changes []ChangeData
+		changes, rcode = updateChanges(newZoneRecords(zone, records), r)
+		changed = len(changes) > 0
+		if !changed {
+			return nil, nil
+		}
+		return changes, validateChangeBatch(zone, records, changes)
+	})
+	if errors.Is(err, ErrNoSuchHostedZone) {
+		return dns.RcodeNotAuth, nil
+	}
+	if errors.Is(err, ErrInvalidChangeBatch) {
+		return dns.RcodeRefused, nil
+	}
+	if err != nil {
+		return dns.RcodeServerFailure, err
+	}
+
+	if changed {
+		s.zoneChanged(zone)
+		for i := range reverseZones {
+			s.zoneChanged(&reverseZones[i])
+		}
+	}
+
+	return rcode, nil
+}
+
+// updateChanges returns the changes the update section of r makes to the zone when the
+// prerequisites hold, and the rcode of the update.
+func updateChanges(zr *zoneRecords, r *dns.Msg) ([]ChangeData, int) {
+
+	if rcode := checkPrerequisites(zr, r.Answer); rcode != dns.RcodeSuccess {
+		return nil, rcode
+	}
+
+	rrsets := make(map[string]*updateRRset)
+	for _, rr := range r.Ns {
+		applyUpdateRR(zr, rrsets, rr)
+	}
+
+	var changes []ChangeData
+	for _, rrset := range rrsets {
+
+		if !rrset.touched {
+			continue
+		}
+
+		// record sets of traffic policy instances are changed through the instance only
+		if rrset.original != nil && owned(*rrset.original) {
+			return nil, dns.RcodeRefused
+		}
+
+		if len(rrset.rrs) == 0 {
+			if rrset.original != nil {
+				changes = append(changes, ChangeData{
+					Action:            awstypes.ChangeActionDelete,
+					ResourceRecordSet: rrset.original,
+				})
+			}
+			continue
+		}
+
+		hdr := rrset.rrs[0].Header()
+		data := ResourceRecordSetData{
+			Name: normalizeDnsName(hdr.Name),
+			Type: awstypes.RRType(dns.TypeToString[hdr.Rrtype]),
+			TTL:  aws.Int64(int64(rrset.ttl)),
+		}
+		for _, rr := range rrset.rrs {
+			data.ResourceRecords = append(data.ResourceRecords, awstypes.ResourceRecord{
+				Value: aws.String(strings.TrimPrefix(rr.String(), rr.Header().String())),
+			})
+		}
+
+		changes = append(changes, ChangeData{
+			Action:            awstypes.ChangeActionUpsert,
+			ResourceRecordSet: &data,
+		})
+	}
+
+	return changes, dns.RcodeSuccess
+}
+
+// checkPrerequisites evaluates the prerequisite section, RFC 2136 section 3.2.
+func checkPrerequisites(zr *zoneRecords, prereqs []dns.RR) int {
+
+	// value dependent prerequisites are compared as whole record sets
+	required := make(map[string][]dns.RR)
+
+	for _, rr := range prereqs {
+
+		hdr := rr.Header()
+		name := normalizeDnsName(hdr.Name)
+
+		if hdr.Ttl != 0 || !isSubdomain(name, zr.zone.Name) {
+			if hdr.Ttl != 0 {
+				return dns.RcodeFormatError
+			}
+			return dns.RcodeNotZone
+		}
+
+		rrsets := simpleRRsets(zr.names[name])
+
+		switch hdr.Class {
+		case dns.ClassANY:
+			if hdr.Rrtype == dns.TypeANY {
+				if len(rrsets) == 0 {
+					return dns.RcodeNameError
+				}
+			} else if !slices.ContainsFunc(rrsets, isRRType(hdr.Rrtype)) {
+				return dns.RcodeNXRrset
+			}
+
+		case dns.ClassNONE:
+			if hdr.Rrtype == dns.TypeANY {
+				if len(rrsets) > 0 {
+					return dns.RcodeYXDomain
+				}
+			} else if slices.ContainsFunc(rrsets, isRRType(hdr.Rrtype)) {
+				return dns.RcodeYXRrset
+			}
+
+		case dns.ClassINET:
+			key := name + " " + dns.TypeToString[hdr.Rrtype]
+			required[key] = append(required[key], rr)
+
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for _, rrs := range required {
+
+		hdr := rrs[0].Header()
+		var current []dns.RR
+		for _, rrset := range simpleRRsets(zr.names[normalizeDnsName(hdr.Name)]) {
+			if isRRType(hdr.Rrtype)(rrset) {
+				current = toDnsRRs([]ResourceRecordSetData{rrset})
+			}
+		}
+
+		if !sameRRs(current, rrs) {
+			return dns.RcodeNXRrset
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// prescanUpdate validates an update section record, RFC 2136 section 3.4.1.
+func prescanUpdate(zoneName string, rr dns.RR) int {
+
+	hdr := rr.Header()
+	if !isSubdomain(normalizeDnsName(hdr.Name), zoneName) {
+		return dns.RcodeNotZone
+	}
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		if hdr.Rrtype == dns.TypeANY || hdr.Rrtype == dns.TypeAXFR || hdr.Rrtype == dns.TypeIXFR {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassANY:
+		if hdr.Ttl != 0 || hdr.Rdlength != 0 || hdr.Rrtype == dns.TypeAXFR || hdr.Rrtype == dns.TypeIXFR {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassNONE:
+		if hdr.Ttl != 0 || hdr.Rrtype == dns.TypeANY || hdr.Rrtype == dns.TypeAXFR || hdr.Rrtype == dns.TypeIXFR {
+			return dns.RcodeFormatError
+		}
+	default:
+		return dns.RcodeFormatError
+	}
+
+	return dns.RcodeSuccess
+}
+
+// applyUpdateRR applies one update section record to the working record sets,
+// RFC 2136 section 3.4.2. The apex SOA and NS records can't be removed this way.
+func applyUpdateRR(zr *zoneRecords, rrsets map[string]*updateRRset, rr dns.RR) {
+
+	hdr := rr.Header()
+	name := normalizeDnsName(hdr.Name)
+	apex := name == zr.zone.Name
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		rrset := workingRRset(zr, rrsets, name, hdr.Rrtype)
+
+		if hdr.Rrtype == dns.TypeSOA {
+			if !apex || len(rrset.rrs) == 0 || !serialGreater(rr.(*dns.SOA).Serial, rrset.rrs[0].(*dns.SOA).Serial) {
+				return
+			}
+			rrset.rrs = nil
+		}
+
+		// CNAMEs don't mix with other data at the same name
+		cname := workingRRset(zr, rrsets, name, dns.TypeCNAME)
+		if hdr.Rrtype == dns.TypeCNAME {
+			for _, rrsetType := range namedTypes(zr, rrsets, name) {
+				if rrsetType != dns.TypeCNAME {
+					return
+				}
+			}
+			rrset.rrs = nil
+		} else if len(cname.rrs) > 0 {
+			return
+		}
+
+		for _, existing := range rrset.rrs {
+			if dns.IsDuplicate(existing, rr) {
+				return
+			}
+		}
+
+		rr.Header().Name = name
+		rrset.rrs = append(rrset.rrs, rr)
+		rrset.ttl = hdr.Ttl
+		rrset.touched = true
+
+	case dns.ClassANY:
+		types := []uint16{hdr.Rrtype}
+		if hdr.Rrtype == dns.TypeANY {
+			types = namedTypes(zr, rrsets, name)
+		}
+
+		for _, rrtype := range types {
+			if apex && (rrtype == dns.TypeSOA || rrtype == dns.TypeNS) {
+				continue
+			}
+			rrset := workingRRset(zr, rrsets, name, rrtype)
+			rrset.rrs = nil
+			rrset.touched = true
+		}
+
+	case dns.ClassNONE:
+		if apex && hdr.Rrtype == dns.TypeSOA {
+			return
+		}
+
+		// compare as class IN, the record to delete is sent with class NONE
+		target := dns.Copy(rr)
+		target.Header().Class = dns.ClassINET
+
+		rrset := workingRRset(zr, rrsets, name, hdr.Rrtype)
+		remaining := slices.DeleteFunc(slices.Clone(rrset.rrs), func(existing dns.RR) bool {
+			return dns.IsDuplicate(existing, target)
+		})
+
+		if apex && hdr.Rrtype == dns.TypeNS && len(remaining) == 0 {
+			return
+		}
+
+		if len(remaining) != len(rrset.rrs) {
+			rrset.rrs = remaining
+			rrset.touched = true
+		}
+	}
+}
+
+func workingRRset(zr *zoneRecords, rrsets map[string]*updateRRset, name string, rrtype uint16) *updateRRset {
+
+	key := name + " " + dns.TypeToString[rrtype]
+	if rrset, found := rrsets[key]; found {
+		return rrset
+	}
+
+	result := updateRRset{}
+	for _, rrset := range simpleRRsets(zr.names[name]) {
+		if isRRType(rrtype)(rrset) {
+			result.original = &rrset
+			result.rrs = toDnsRRs([]ResourceRecordSetData{rrset})
+			result.ttl = uint32(aws.ToInt64(rrset.TTL))
+		}
+	}
+
+	rrsets[key] = &result
+	return &result
+}
+
+// namedTypes lists the record types currently present at name, including pending updates.
+func namedTypes(zr *zoneRecords, rrsets map[string]*updateRRset, name string) []uint16 {
+
+	var result []uint16
+	for _, rrset := range simpleRRsets(zr.names[name]) {
+		rrtype := dns.StringToType[string(rrset.Type)]
+		if len(workingRRset(zr, rrsets, name, rrtype).rrs) > 0 {
+			result = append(result, rrtype)
+		}
+	}
+
+	for key, rrset := range rrsets {
+		rrtype := dns.StringToType[strings.TrimPrefix(key, name+" ")]
+		if strings.HasPrefix(key, name+" ") && len(rrset.rrs) > 0 && !slices.Contains(result, rrtype) {
+			result = append(result, rrtype)
+		}
+	}
+
+	return result
+}
+
+// simpleRRsets skips record sets with a routing policy or alias target, UPDATE can't express them.
+func simpleRRsets(rrsets []ResourceRecordSetData) []ResourceRecordSetData {
+
+	var result []ResourceRecordSetData
+	for _, rrset := range rrsets {
+		if rrset.SetIdentifier == nil && rrset.AliasTarget == nil {
+			result = append(result, rrset)
+		}
+	}
+
+	return result
+}
+
+func isRRType(rrtype uint16) func(rrset ResourceRecordSetData) bool {
+	return func(rrset ResourceRecordSetData) bool {
+		return rrset.Type == awstypes.RRType(dns.TypeToString[rrtype])
+	}
+}
+
+func sameRRs(a []dns.RR, b []dns.RR) bool {
+
+	contains := func(rrs []dns.RR, rr dns.RR) bool {
+		return slices.ContainsFunc(rrs, func(other dns.RR) bool {
+			return dns.IsDuplicate(other, rr)
+		})
+	}
+
+	for _, rr := range a {
+		if !contains(b, rr) {
+			return false
+		}
+	}
+
+	for _, rr := range b {
+		if !contains(a, rr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// serialGreater compares SOA serials using RFC 1982 serial number arithmetic.
+func serialGreater(a uint32, b uint32) bool {
+	return a != b && (a-b) < (1<<31)
+}
@@ -0,0 +1,79 @@
+package route53
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+func TestCheckPrerequisites(t *testing.T) {
+	rrset := func(name string, rrtype awstypes.RRType, values ...string) ResourceRecordSetData {
+		result := ResourceRecordSetData{Name: name, Type: rrtype, TTL: aws.Int64(300)}
+		for _, value := range values {
+			result.ResourceRecords = append(result.ResourceRecords, awstypes.ResourceRecord{Value: aws.String(value)})
+		}
+		return result
+	}
+
+	weighted := rrset("weighted.example.com.", awstypes.RRTypeA, "192.0.2.9")
+	weighted.SetIdentifier = aws.String("one")
+	weighted.Weight = aws.Int64(1)
+
+	zr := newZoneRecords(&HostedZoneData{Id: "/hostedzone/Z1", Name: "example.com."}, []ResourceRecordSetData{
+		rrset("example.com.", awstypes.RRTypeSoa, "ns-1.example.com. admin.example.com. 1 3600 180 604800 1800"),
+		rrset("example.com.", awstypes.RRTypeNs, "ns-1.example.com.", "ns-2.example.com."),
+		rrset("www.example.com.", awstypes.RRTypeA, "192.0.2.1", "192.0.2.2"),
+		rrset("www.example.com.", awstypes.RRTypeTxt, `"hello"`),
+		weighted,
+	})
+
+	// header only records, as sent for the name and record set prerequisites
+	header := func(name string, class uint16, rrtype uint16) dns.RR {
+		return &dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: rrtype, Class: class}}
+	}
+	value := func(text string) dns.RR {
+		rr, err := dns.NewRR(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rr
+	}
+
+	tests := []struct {
+		name    string
+		prereqs []dns.RR
+		want    int
+	}{
+		{"none", nil, dns.RcodeSuccess},
+		{"name in use", []dns.RR{header("www.example.com.", dns.ClassANY, dns.TypeANY)}, dns.RcodeSuccess},
+		{"name not in use", []dns.RR{header("ftp.example.com.", dns.ClassANY, dns.TypeANY)}, dns.RcodeNameError},
+		{"name of weighted record sets only", []dns.RR{header("weighted.example.com.", dns.ClassANY, dns.TypeANY)}, dns.RcodeNameError},
+		{"record set exists", []dns.RR{header("www.example.com.", dns.ClassANY, dns.TypeA)}, dns.RcodeSuccess},
+		{"record set doesn't exist", []dns.RR{header("www.example.com.", dns.ClassANY, dns.TypeAAAA)}, dns.RcodeNXRrset},
+		{"name is not used", []dns.RR{header("ftp.example.com.", dns.ClassNONE, dns.TypeANY)}, dns.RcodeSuccess},
+		{"name is used", []dns.RR{header("WWW.example.com.", dns.ClassNONE, dns.TypeANY)}, dns.RcodeYXDomain},
+		{"record set is not used", []dns.RR{header("www.example.com.", dns.ClassNONE, dns.TypeAAAA)}, dns.RcodeSuccess},
+		{"record set is used", []dns.RR{header("www.example.com.", dns.ClassNONE, dns.TypeTXT)}, dns.RcodeYXRrset},
+		{"same values", []dns.RR{value("www.example.com. 0 IN A 192.0.2.2"), value("www.example.com. 0 IN A 192.0.2.1")}, dns.RcodeSuccess},
+		{"fewer values", []dns.RR{value("www.example.com. 0 IN A 192.0.2.1")}, dns.RcodeNXRrset},
+		{"more values", []dns.RR{value("www.example.com. 0 IN A 192.0.2.1"), value("www.example.com. 0 IN A 192.0.2.2"),
+			value("www.example.com. 0 IN A 192.0.2.3")}, dns.RcodeNXRrset},
+		{"values of a missing record set", []dns.RR{value("ftp.example.com. 0 IN A 192.0.2.1")}, dns.RcodeNXRrset},
+		{"values of two record sets", []dns.RR{value("www.example.com. 0 IN TXT hello"), value("example.com. 0 IN NS ns-1.example.com."),
+			value("example.com. 0 IN NS ns-2.example.com.")}, dns.RcodeSuccess},
+		{"all must hold", []dns.RR{header("www.example.com.", dns.ClassANY, dns.TypeA), header("www.example.com.", dns.ClassNONE, dns.TypeA)}, dns.RcodeYXRrset},
+		{"ttl", []dns.RR{value("www.example.com. 300 IN A 192.0.2.1")}, dns.RcodeFormatError},
+		{"outside the zone", []dns.RR{header("www.example.org.", dns.ClassANY, dns.TypeANY)}, dns.RcodeNotZone},
+		{"other class", []dns.RR{header("www.example.com.", dns.ClassCHAOS, dns.TypeA)}, dns.RcodeFormatError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPrerequisites(zr, tt.prereqs); got != tt.want {
+				t.Errorf("checkPrerequisites() = %s, want %s", dns.RcodeToString[got], dns.RcodeToString[tt.want])
+			}
+		})
+	}
+}
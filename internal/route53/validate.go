@@ -0,0 +1,330 @@
+package route53
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+// validateChangeBatch checks a change batch against the zone's current record sets the way
+// Route53 does and returns every problem found as an InvalidChangeBatchError.
+func validateChangeBatch(hz *HostedZoneData, records []ResourceRecordSetData, changes []ChangeData) error {
+
+	var messages []string
+	fail := func(format string, args ...any) {
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}
+
+	current := make(map[string]ResourceRecordSetData, len(records))
+	for _, rrset := range records {
+		if header, err := convertToKey(hz.Name, rrset.Name, rrset.Type, rrset.SetIdentifier); err == nil {
+			current[header.rrkey] = rrset
+		}
+	}
+	soaKey, nsKey := apexKey(hz.Name, awstypes.RRTypeSoa), apexKey(hz.Name, awstypes.RRTypeNs)
+	_, hadSoa := current[soaKey]
+	_, hadNs := current[nsKey]
+
+	if len(changes) == 0 {
+		fail("The request doesn't contain any changes")
+	}
+
+	seen := make(map[string]awstypes.ChangeAction)
+	for i, change := range changes {
+
+		rrset := change.ResourceRecordSet
+		if rrset == nil {
+			fail("Change %d doesn't contain a ResourceRecordSet", i+1)
+			continue
+		}
+
+		name := strings.ToLower(dns.Fqdn(rrset.Name))
+		header, err := convertToKey(hz.Name, rrset.Name, rrset.Type, rrset.SetIdentifier)
+		if err != nil {
+			fail("RRSet with DNS name %s is not permitted in zone %s", name, hz.Name)
+			continue
+		}
+
+		// deleting and then recreating a record set is the only repetition allowed
+		if previous, found := seen[header.rrkey]; found &&
+			(previous != awstypes.ChangeActionDelete || change.Action == awstypes.ChangeActionDelete) {
+			fail("The request contains an invalid set of changes for a resource record set '%s %s'", rrset.Type, name)
+			continue
+		}
+		seen[header.rrkey] = change.Action
+
+		for _, msg := range validateRecordSet(hz, name, change) {
+			fail("%s", msg)
+		}
+
+		existing, found := current[header.rrkey]
+		if found {
+			if msg := ownerConflict(name, &existing, rrset); msg != "" {
+				fail("%s", msg)
+				continue
+			}
+		}
+
+		switch change.Action {
+		case awstypes.ChangeActionCreate:
+			if found {
+				fail("Tried to create resource record set %s but it already exists", describeRecordSet(name, rrset))
+				continue
+			}
+			current[header.rrkey] = *rrset
+
+		case awstypes.ChangeActionUpsert:
+			current[header.rrkey] = *rrset
+
+		case awstypes.ChangeActionDelete:
+			if !found {
+				fail("Tried to delete resource record set %s but it was not found", describeRecordSet(name, rrset))
+				continue
+			}
+			if !sameRecordSet(existing, *rrset) {
+				fail("Tried to delete resource record set %s but the values provided do not match the current values",
+					describeRecordSet(name, rrset))
+				continue
+			}
+			delete(current, header.rrkey)
+
+		default:
+			fail("Invalid request: Action %q is not one of [CREATE, DELETE, UPSERT]", change.Action)
+		}
+	}
+
+	// a CNAME can't share its name with other record sets, whichever came first
+	rrtypes := make(map[string]map[awstypes.RRType]bool)
+	for _, rrset := range current {
+		name := strings.ToLower(dns.Fqdn(rrset.Name))
+		if rrtypes[name] == nil {
+			rrtypes[name] = make(map[awstypes.RRType]bool)
+		}
+		rrtypes[name][rrset.Type] = true
+	}
+
+	for _, change := range changes {
+
+		rrset := change.ResourceRecordSet
+		if rrset == nil || change.Action == awstypes.ChangeActionDelete {
+			continue
+		}
+
+		name := strings.ToLower(dns.Fqdn(rrset.Name))
+		if !rrtypes[name][awstypes.RRTypeCname] || len(rrtypes[name]) == 1 {
+			continue
+		}
+
+		if rrset.Type == awstypes.RRTypeCname {
+			fail("RRSet of type CNAME with DNS name %s is not permitted as it conflicts with other records "+
+				"with the same DNS name in zone %s", name, hz.Name)
+		} else {
+			fail("RRSet of type %s with DNS name %s is not permitted because a conflicting RRSet of type CNAME "+
+				"with the same DNS name already exists in zone %s", rrset.Type, name, hz.Name)
+		}
+	}
+
+	// CIDR routed record sets of a name and type share a collection, with one record set per location
+	collections := make(map[string]string)
+	locations := make(map[string]bool)
+	for _, rrset := range current {
+		cfg := rrset.CidrRoutingConfig
+		if cfg == nil {
+			continue
+		}
+
+		name := strings.ToLower(dns.Fqdn(rrset.Name))
+		key := name + "/" + string(rrset.Type)
+		if other, found := collections[key]; found && other != aws.ToString(cfg.CollectionId) {
+			fail("RRSet with DNS name %s, type %s can't use more than one CIDR collection", name, rrset.Type)
+		}
+		collections[key] = aws.ToString(cfg.CollectionId)
+
+		if locations[key+"/"+aws.ToString(cfg.LocationName)] {
+			fail("RRSet with DNS name %s, type %s has more than one record set for CIDR location %s",
+				name, rrset.Type, aws.ToString(cfg.LocationName))
+		}
+		locations[key+"/"+aws.ToString(cfg.LocationName)] = true
+	}
+
+	if _, found := current[soaKey]; hadSoa && !found {
+		fail("A HostedZone must contain exactly one SOA record.")
+	}
+	if _, found := current[nsKey]; hadNs && !found {
+		fail("A HostedZone must contain at least one NS record for the zone itself.")
+	}
+
+	if len(messages) > 0 {
+		return &InvalidChangeBatchError{Messages: messages}
+	}
+	return nil
+}
+
+// validateRecordSet checks the parts of a record set which don't depend on the zone's contents.
+func validateRecordSet(hz *HostedZoneData, name string, change ChangeData) []string {
+
+	rrset := change.ResourceRecordSet
+	var messages []string
+	fail := func(format string, args ...any) {
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}
+
+	expectOne := func(found string) {
+		fail("Invalid request: Expected exactly one of [AliasTarget, all of [TTL, and ResourceRecords], "+
+			"or TrafficPolicyInstanceId], but found %s in Change with [Action=%s, Name=%s, Type=%s, SetIdentifier=%s]",
+			found, change.Action, name, rrset.Type, aws.ToString(rrset.SetIdentifier))
+	}
+
+	hasRecords := rrset.TTL != nil || len(rrset.ResourceRecords) > 0
+	if rrset.AliasTarget != nil && hasRecords {
+		expectOne("more than one")
+		return messages
+	}
+	if rrset.AliasTarget == nil && (rrset.TTL == nil || len(rrset.ResourceRecords) == 0) {
+		expectOne("none")
+		return messages
+	}
+
+	if cfg := rrset.CidrRoutingConfig; cfg != nil &&
+		(rrset.SetIdentifier == nil || aws.ToString(cfg.CollectionId) == "" || aws.ToString(cfg.LocationName) == "") {
+		fail("Invalid request: CidrRoutingConfig needs a SetIdentifier, a CollectionId and a LocationName "+
+			"in Change with [Action=%s, Name=%s, Type=%s]", change.Action, name, rrset.Type)
+	}
+
+	if rrset.Type == awstypes.RRTypeCname && name == hz.Name {
+		fail("RRSet of type CNAME with DNS name %s is not permitted at apex in zone %s", name, hz.Name)
+	}
+
+	if rrset.AliasTarget != nil {
+		if aws.ToString(rrset.AliasTarget.DNSName) == "" || aws.ToString(rrset.AliasTarget.HostedZoneId) == "" {
+			fail("Invalid request: AliasTarget needs a DNSName and a HostedZoneId")
+		}
+		return messages
+	}
+
+	if ttl := aws.ToInt64(rrset.TTL); ttl < 0 || ttl > math.MaxInt32 {
+		fail("Invalid request: Value '%d' at 'resourceRecordSet.tTL' failed to satisfy constraint: "+
+			"Member must have value between 0 and %d", ttl, math.MaxInt32)
+	}
+
+	switch rrset.Type {
+	case awstypes.RRTypeCname, awstypes.RRTypeSoa:
+		if len(rrset.ResourceRecords) > 1 {
+			fail("RRSet of type %s with DNS name %s has too many values, only one is permitted", rrset.Type, name)
+		}
+	}
+
+	for _, rr := range rrset.ResourceRecords {
+		if problem := validateRecordValue(name, rrset.Type, aws.ToString(rr.Value)); problem != "" {
+			fail("Invalid Resource Record: 'FATAL problem: %s encountered with '%s''", problem, aws.ToString(rr.Value))
+		}
+	}
+
+	return messages
+}
+
+// validateRecordValue returns the Route53 problem code for a malformed record value, or "".
+func validateRecordValue(name string, rrtype awstypes.RRType, value string) string {
+
+	switch rrtype {
+	case awstypes.RRTypeA:
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil || strings.Contains(value, ":") {
+			return "ARRDATAIllegalIPv4Address (Value is not a valid IPv4 address)"
+		}
+		return ""
+
+	case awstypes.RRTypeAaaa:
+		if ip := net.ParseIP(value); ip == nil || !strings.Contains(value, ":") {
+			return "AAAARRDATAIllegalIPv6Address (Value is not a valid IPv6 address)"
+		}
+		return ""
+
+	case awstypes.RRTypeMx:
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return "MXRRDATANotTwoFields (Value must contain a priority and a domain name)"
+		}
+		if _, err := strconv.ParseUint(fields[0], 10, 16); err != nil {
+			return "MXRRDATAIllegalPriority (Priority must be an integer between 0 and 65535)"
+		}
+
+	case awstypes.RRTypeTxt, awstypes.RRTypeSpf:
+		if !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) || len(value) < 2 {
+			return "InvalidCharacterString (Value should be enclosed in quotation marks)"
+		}
+	}
+
+	rr, err := dns.NewRR(fmt.Sprintf("%s 300 IN %s %s", name, rrtype, value))
+	if err != nil || rr == nil {
+		return "InvalidRDATA (Value is not valid for the record type)"
+	}
+
+	if txt, ok := rr.(*dns.TXT); ok {
+		for _, s := range txt.Txt {
+			if len(s) > 255 {
+				return "CharacterStringTooLong (Value is too long)"
+			}
+		}
+	}
+
+	return ""
+}
+
+// sameRecordSet compares a DELETE against the stored record set, values are compared as DNS records.
+func sameRecordSet(stored ResourceRecordSetData, deleted ResourceRecordSetData) bool {
+
+	if aws.ToInt64(stored.TTL) != aws.ToInt64(deleted.TTL) ||
+		aws.ToInt64(stored.Weight) != aws.ToInt64(deleted.Weight) ||
+		stored.Failover != deleted.Failover ||
+		stored.Region != deleted.Region ||
+		aws.ToBool(stored.MultiValueAnswer) != aws.ToBool(deleted.MultiValueAnswer) ||
+		aws.ToString(stored.HealthCheckId) != aws.ToString(deleted.HealthCheckId) {
+		return false
+	}
+
+	if (stored.AliasTarget == nil) != (deleted.AliasTarget == nil) {
+		return false
+	}
+	if stored.AliasTarget != nil {
+		return normalizeDnsName(aws.ToString(stored.AliasTarget.DNSName)) ==
+			normalizeDnsName(aws.ToString(deleted.AliasTarget.DNSName)) &&
+			strings.TrimPrefix(aws.ToString(stored.AliasTarget.HostedZoneId), HostedZonePrefix) ==
+				strings.TrimPrefix(aws.ToString(deleted.AliasTarget.HostedZoneId), HostedZonePrefix) &&
+			stored.AliasTarget.EvaluateTargetHealth == deleted.AliasTarget.EvaluateTargetHealth
+	}
+
+	return len(stored.ResourceRecords) == len(deleted.ResourceRecords) &&
+		sameRRs(toDnsRRs([]ResourceRecordSetData{stored}), toDnsRRs([]ResourceRecordSetData{deleted}))
+}
+
+// ownerConflict returns the problem with changing existing to rrset when they belong to different
+// traffic policy instances, or "". Record sets of an instance are only changed through the instance,
+// whose id is set by the traffic policy code, never taken from a request.
+func ownerConflict(name string, existing *ResourceRecordSetData, rrset *ResourceRecordSetData) string {
+
+	owner := aws.ToString(existing.TrafficPolicyInstanceId)
+	if owner == aws.ToString(rrset.TrafficPolicyInstanceId) {
+		return ""
+	}
+
+	if owner != "" {
+		return fmt.Sprintf("Tried to change resource record set %s but it was created by traffic policy instance %s",
+			describeRecordSet(name, rrset), owner)
+	}
+	return fmt.Sprintf("Tried to create resource record set %s but it already exists", describeRecordSet(name, rrset))
+}
+
+// describeRecordSet formats a record set the way Route53 names it in error messages.
+func describeRecordSet(name string, rrset *ResourceRecordSetData) string {
+
+	if rrset.SetIdentifier != nil {
+		return fmt.Sprintf("[name='%s', type='%s', set-identifier='%s']", name, rrset.Type, *rrset.SetIdentifier)
+	}
+	return fmt.Sprintf("[name='%s', type='%s']", name, rrset.Type)
+}
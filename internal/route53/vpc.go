@@ -0,0 +1,69 @@
+package route53
+
+import (
+	"home-fern/internal/core"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// checkVPC returns the VPC as stored with a hosted zone when it's one of the configured VPCs.
+func (s *Service) checkVPC(vpc *awstypes.VPC) (awstypes.VPC, error) {
+
+	if vpc == nil {
+		return awstypes.VPC{}, ErrInvalidVPCId
+	}
+
+	cfg, found := s.vpcs[aws.ToString(vpc.VPCId)]
+	if !found || (cfg.Region != "" && vpc.VPCRegion != "" && cfg.Region != string(vpc.VPCRegion)) {
+		return awstypes.VPC{}, ErrInvalidVPCId
+	}
+
+	result := awstypes.VPC{VPCId: aws.String(cfg.Id), VPCRegion: vpc.VPCRegion}
+	if result.VPCRegion == "" {
+		result.VPCRegion = awstypes.VPCRegion(cfg.Region)
+	}
+
+	return result, nil
+}
+
+// zoneVisible is true for public zones, and for private zones when the client is in
+// one of the zone's VPCs.
+func (s *Service) zoneVisible(hz *HostedZoneData, clientIP net.IP) bool {
+
+	if !hz.Config.PrivateZone {
+		return true
+	}
+
+	for _, vpc := range hz.VPCs {
+		if cfg, found := s.vpcs[aws.ToString(vpc.VPCId)]; found && core.IPInList(cfg.Cidrs, clientIP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostedZonesOverlap is true when two zones of the same name would answer the same clients:
+// two public zones, or two private zones sharing a VPC.
+func hostedZonesOverlap(a *HostedZoneData, b *HostedZoneData) bool {
+
+	if !strings.EqualFold(a.Name, b.Name) || a.Config.PrivateZone != b.Config.PrivateZone {
+		return false
+	}
+
+	if !a.Config.PrivateZone {
+		return true
+	}
+
+	return slices.ContainsFunc(a.VPCs, func(vpc awstypes.VPC) bool { return hasVPC(b, vpc) })
+}
+
+func hasVPC(hz *HostedZoneData, vpc awstypes.VPC) bool {
+	return slices.ContainsFunc(hz.VPCs, func(other awstypes.VPC) bool {
+		return aws.ToString(other.VPCId) == aws.ToString(vpc.VPCId)
+	})
+}
@@ -0,0 +1,338 @@
+package route53
+
+import (
+	"home-fern/internal/core"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+const (
+	// transferChunk is the number of records written per zone transfer message.
+	transferChunk  = 100
+	notifyAttempts = 3
+	notifyTimeout  = 5 * time.Second
+)
+
+func (d *DnsServer) serveTransfer(w dns.ResponseWriter, r *dns.Msg) {
+
+	q := r.Question[0]
+	name := normalizeDnsName(q.Name)
+	ip := remoteIP(w.RemoteAddr())
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	zr, err := d.service.loadZoneRecords(name, ip)
+	if err != nil {
+		log.Println("Error:", err)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	if zr == nil || zr.zone.Name != name {
+		m.SetRcode(r, dns.RcodeNotAuth)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	if !d.service.transferAllowed(zr.zone.Name, ip, r, w.TsigStatus()) {
+		log.Printf("Zone transfer of %s to %s refused", zr.zone.Name, ip)
+		m.SetRcode(r, dns.RcodeRefused)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	var since *uint32
+	if q.Qtype == dns.TypeIXFR {
+		for _, rr := range r.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				since = &soa.Serial
+			}
+		}
+
+		if since == nil {
+			m.SetRcode(r, dns.RcodeFormatError)
+			d.writeMsg(w, r, m)
+			return
+		}
+	}
+
+	rrs, err := d.service.transferRecords(zr, since)
+	if err != nil {
+		log.Println("Error:", err)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); udp {
+
+		// transfers need TCP; the current SOA tells an IXFR client to retry over TCP
+		if q.Qtype == dns.TypeAXFR {
+			m.SetRcode(r, dns.RcodeRefused)
+		} else {
+			m.Authoritative = true
+			m.Answer = rrs[:1]
+		}
+		d.writeMsg(w, r, m)
+		return
+	}
+
+	log.Printf("%s of %s to %s", dns.TypeToString[q.Qtype], zr.zone.Name, ip)
+
+	ch := make(chan *dns.Envelope, len(rrs)/transferChunk+1)
+	for i := 0; i < len(rrs); i += transferChunk {
+		ch <- &dns.Envelope{RR: rrs[i:min(i+transferChunk, len(rrs))]}
+	}
+	close(ch)
+
+	tr := new(dns.Transfer)
+	if err := tr.Out(w, r, ch); err != nil {
+		log.Printf("Zone transfer of %s to %s failed: %v", zr.zone.Name, ip, err)
+	}
+
+	w.Close()
+}
+
+// transferRecords returns the records of an AXFR, or of an IXFR when since is set
+// and the stored changes cover every serial after it (RFC 1995).
+func (s *Service) transferRecords(zr *zoneRecords, since *uint32) ([]dns.RR, error) {
+
+	soa, err := s.zoneSoa(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	if since != nil {
+
+		if !serialGreater(soa.Serial, *since) {
+			return []dns.RR{soa}, nil
+		}
+
+		rrs, err := s.incrementalRecords(zr.zone, *since, soa)
+		if err != nil {
+			return nil, err
+		}
+		if rrs != nil {
+			return rrs, nil
+		}
+	}
+
+	names := make([]string, 0, len(zr.names))
+	for name := range zr.names {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	result := []dns.RR{soa}
+	for _, name := range names {
+
+		var rrsets []ResourceRecordSetData
+		for _, rrset := range zr.names[name] {
+			if rrset.Type != awstypes.RRTypeSoa {
+				rrsets = append(rrsets, rrset)
+			}
+		}
+
+		result = append(result, toDnsRRs(rrsets)...)
+	}
+
+	return append(result, soa), nil
+}
+
+// incrementalRecords returns nil when the change history has a gap after since.
+func (s *Service) incrementalRecords(hz *HostedZoneData, since uint32, current *dns.SOA) ([]dns.RR, error) {
+
+	changes, err := s.dataStore.findZoneChanges(hz.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the changes after since, walking back from the newest one, serials can go down or wrap around
+	first := len(changes)
+	for first > 0 && serialGreater(changes[first-1].Serial, since) {
+		first--
+	}
+
+	serial := since
+	result := []dns.RR{current}
+
+	for _, ci := range changes[first:] {
+
+		oldSoa, removed := splitSoa(ci.Removed)
+		newSoa, added := splitSoa(ci.Added)
+		if oldSoa == nil || newSoa == nil || oldSoa.Serial != serial {
+			return nil, nil
+		}
+
+		result = append(result, oldSoa)
+		result = append(result, toDnsRRs(removed)...)
+		result = append(result, newSoa)
+		result = append(result, toDnsRRs(added)...)
+
+		serial = newSoa.Serial
+	}
+
+	if serial != current.Serial {
+		return nil, nil
+	}
+
+	return append(result, current), nil
+}
+
+func (s *Service) zoneSoa(zr *zoneRecords) (*dns.SOA, error) {
+
+	for _, rrset := range zr.names[zr.zone.Name] {
+		if rrset.Type != awstypes.RRTypeSoa || len(rrset.ResourceRecords) == 0 {
+			continue
+		}
+
+		soa, err := parseSoa(zr.zone.Name, aws.ToString(rrset.ResourceRecords[0].Value))
+		if err != nil {
+			return nil, err
+		}
+		soa.Hdr.Ttl = uint32(aws.ToInt64(rrset.TTL))
+		return soa, nil
+	}
+
+	soa, err := parseSoa(zr.zone.Name, s.soaDefault)
+	if err != nil {
+		return nil, err
+	}
+	soa.Hdr.Ttl = 900
+	return soa, nil
+}
+
+func splitSoa(rrsets []ResourceRecordSetData) (*dns.SOA, []ResourceRecordSetData) {
+
+	var soa *dns.SOA
+	var others []ResourceRecordSetData
+
+	for _, rrset := range rrsets {
+		if rrset.Type != awstypes.RRTypeSoa {
+			others = append(others, rrset)
+			continue
+		}
+
+		for _, rr := range toDnsRRs([]ResourceRecordSetData{rrset}) {
+			soa, _ = rr.(*dns.SOA)
+		}
+	}
+
+	return soa, others
+}
+
+// transferAllowed checks the zone's allowTransfer addresses and, when
+// transferKeys are configured, that the request carries a valid TSIG from one of them.
+func (s *Service) transferAllowed(zoneName string, ip net.IP, r *dns.Msg, tsigStatus error) bool {
+
+	cfg, found := s.zoneConfigs[zoneName]
+	if !found {
+		return false
+	}
+
+	if len(cfg.AllowTransfer) > 0 && !core.IPInList(cfg.AllowTransfer, ip) {
+		return false
+	}
+
+	if len(cfg.TransferKeys) == 0 {
+		return len(cfg.AllowTransfer) > 0
+	}
+
+	return s.tsigKeyAllowed(cfg.TransferKeys, r, tsigStatus)
+}
+
+func (s *Service) tsigKeyAllowed(keyNames []string, r *dns.Msg, tsigStatus error) bool {
+
+	tsig := r.IsTsig()
+	if tsig == nil || tsigStatus != nil {
+		return false
+	}
+
+	name := normalizeDnsName(tsig.Hdr.Name)
+	key, found := s.tsigKeys[name]
+	if !found || !strings.EqualFold(tsigAlgorithm(key.Algorithm), tsig.Algorithm) {
+		return false
+	}
+
+	return slices.ContainsFunc(keyNames, func(keyName string) bool {
+		return normalizeDnsName(keyName) == name
+	})
+}
+
+func (s *Service) tsigSecrets() map[string]string {
+
+	result := make(map[string]string, len(s.tsigKeys))
+	for name, key := range s.tsigKeys {
+		result[name] = key.Secret
+	}
+
+	return result
+}
+
+func (d *DnsServer) notifyZone(hz *HostedZoneData) {
+
+	cfg, found := d.service.zoneConfigs[hz.Name]
+	if !found {
+		return
+	}
+
+	for _, target := range cfg.Notify {
+		go d.sendNotify(hz.Name, target, cfg.TransferKeys)
+	}
+}
+
+// sendNotify tells a secondary that the zone changed (RFC 1996),
+// signing with the zone's first transfer key when one is configured.
+func (d *DnsServer) sendNotify(zoneName string, target string, keyNames []string) {
+
+	addr := target
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		addr = net.JoinHostPort(target, "53")
+	}
+
+	m := new(dns.Msg)
+	m.SetNotify(zoneName)
+
+	client := dns.Client{Timeout: notifyTimeout}
+	if len(keyNames) > 0 {
+		if key, found := d.service.tsigKeys[normalizeDnsName(keyNames[0])]; found {
+			client.TsigSecret = map[string]string{normalizeDnsName(key.Name): key.Secret}
+			m.SetTsig(normalizeDnsName(key.Name), tsigAlgorithm(key.Algorithm), 300, time.Now().Unix())
+		}
+	}
+
+	for attempt := 1; attempt <= notifyAttempts; attempt++ {
+
+		resp, _, err := client.Exchange(m, addr)
+		if err == nil && resp.Rcode == dns.RcodeSuccess {
+			log.Printf("Sent NOTIFY for %s to %s", zoneName, addr)
+			return
+		}
+
+		if err != nil {
+			log.Printf("NOTIFY for %s to %s failed: %v", zoneName, addr, err)
+		} else {
+			log.Printf("NOTIFY for %s to %s rejected: %s", zoneName, addr, dns.RcodeToString[resp.Rcode])
+		}
+
+		time.Sleep(time.Duration(attempt) * notifyTimeout)
+	}
+}
+
+func tsigAlgorithm(algorithm string) string {
+
+	if algorithm == "" {
+		return dns.HmacSHA256
+	}
+
+	return dns.Fqdn(strings.ToLower(algorithm))
+}
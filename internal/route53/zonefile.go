@@ -0,0 +1,254 @@
+package route53
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+// defaultZoneFileTtl is written as $TTL and applies to records without an explicit TTL.
+const defaultZoneFileTtl = 300
+
+// route53Comment starts the comments which hold what a master file has no form for: the routing
+// policy of a record set, next to each of its records, and alias record sets, on lines of their own.
+const route53Comment = "; route53 "
+
+// writeZoneFile writes the record sets of a hosted zone as an RFC 1035 master file.
+// Owner names are relative to $ORIGIN; alias records and routing policies are written in
+// comments which parseZoneFile reads back.
+func writeZoneFile(w io.Writer, hz *HostedZoneData, records []ResourceRecordSetData) error {
+
+	slices.SortStableFunc(records, func(a, b ResourceRecordSetData) int {
+		return compareZoneFileOrder(hz.Name, a, b)
+	})
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "$ORIGIN %s\n", hz.Name)
+	fmt.Fprintf(bw, "$TTL %d\n", defaultZoneFileTtl)
+
+	for _, rrset := range records {
+
+		owner := relativeOwner(hz.Name, rrset.Name)
+
+		comment, err := recordSetComment(rrset)
+		if err != nil {
+			return err
+		}
+
+		if rrset.AliasTarget != nil {
+			fmt.Fprintf(bw, "%s%s %s %s\n", route53Comment, normalizeDnsName(rrset.Name), rrset.Type, comment)
+			continue
+		}
+		if comment != "" {
+			comment = "\t" + route53Comment + comment
+		}
+
+		for _, rr := range toDnsRRs([]ResourceRecordSetData{rrset}) {
+
+			if soa, ok := rr.(*dns.SOA); ok {
+				fmt.Fprintf(bw, "%s\t%d\tIN\tSOA\t%s %s (\n", owner, soa.Hdr.Ttl, soa.Ns, soa.Mbox)
+				fmt.Fprintf(bw, "\t\t\t\t%d\t; serial\n", soa.Serial)
+				fmt.Fprintf(bw, "\t\t\t\t%d\t; refresh\n", soa.Refresh)
+				fmt.Fprintf(bw, "\t\t\t\t%d\t; retry\n", soa.Retry)
+				fmt.Fprintf(bw, "\t\t\t\t%d\t; expire\n", soa.Expire)
+				fmt.Fprintf(bw, "\t\t\t\t%d )\t; minimum\n", soa.Minttl)
+				continue
+			}
+
+			rdata := strings.TrimPrefix(rr.String(), rr.Header().String())
+			fmt.Fprintf(bw, "%s\t%d\tIN\t%s\t%s%s\n", owner, rr.Header().Ttl, rrset.Type, rdata, comment)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// parseZoneFile reads an RFC 1035 master file into record sets. The zone name is taken
+// from origin or, when origin is empty, from the file's SOA record.
+func parseZoneFile(r io.Reader, origin string) (string, []ResourceRecordSetData, error) {
+
+	if origin != "" {
+		origin = normalizeDnsName(origin)
+	}
+
+	// the parser skips comment lines, the alias record sets in them are read afterwards
+	var raw bytes.Buffer
+	zp := dns.NewZoneParser(io.TeeReader(r, &raw), origin, "")
+	zp.SetDefaultTTL(defaultZoneFileTtl)
+
+	var result []ResourceRecordSetData
+	index := make(map[string]int)
+
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+
+		hdr := rr.Header()
+		if hdr.Class != dns.ClassINET {
+			return "", nil, fmt.Errorf("unsupported class %s for %s: %w",
+				dns.ClassToString[hdr.Class], hdr.Name, ErrInvalidInput)
+		}
+
+		name := normalizeDnsName(hdr.Name)
+		rrtype := awstypes.RRType(dns.TypeToString[hdr.Rrtype])
+
+		if soa, isSoa := rr.(*dns.SOA); isSoa {
+			if origin == "" {
+				origin = name
+			} else if origin != name {
+				return "", nil, fmt.Errorf("soa %s outside of %s: %w", soa.Hdr.Name, origin, ErrInvalidInput)
+			}
+		}
+
+		rrset := ResourceRecordSetData{}
+		if comment, found := strings.CutPrefix(zp.Comment(), route53Comment); found {
+			if err := json.Unmarshal([]byte(comment), &rrset); err != nil {
+				return "", nil, fmt.Errorf("invalid comment of %s %s: %v: %w", name, rrtype, err, ErrInvalidInput)
+			}
+		}
+		rrset.Name, rrset.Type, rrset.TTL = name, rrtype, aws.Int64(int64(hdr.Ttl))
+
+		key := name + " " + string(rrtype) + " " + aws.ToString(rrset.SetIdentifier)
+		i, found := index[key]
+		if !found {
+			i = len(result)
+			index[key] = i
+			result = append(result, rrset)
+		} else if aws.ToInt64(result[i].TTL) != int64(hdr.Ttl) {
+			return "", nil, fmt.Errorf("records of %s %s have different TTLs: %w", name, rrtype, ErrInvalidInput)
+		}
+
+		rdata := strings.TrimPrefix(rr.String(), hdr.String())
+		result[i].ResourceRecords = append(result[i].ResourceRecords,
+			awstypes.ResourceRecord{Value: aws.String(rdata)})
+	}
+
+	if err := zp.Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to parse zone file: %v: %w", err, ErrInvalidInput)
+	}
+
+	if origin == "" {
+		return "", nil, fmt.Errorf("zone file has no SOA record and no origin: %w", ErrInvalidInput)
+	}
+
+	aliases, err := parseAliasComments(&raw)
+	if err != nil {
+		return "", nil, err
+	}
+	result = append(result, aliases...)
+
+	for _, rrset := range result {
+		if !isSubdomain(rrset.Name, origin) {
+			return "", nil, fmt.Errorf("record %s outside of %s: %w", rrset.Name, origin, ErrInvalidInput)
+		}
+	}
+
+	return origin, result, nil
+}
+
+// recordSetComment returns the attributes of rrset besides its name, type, TTL and records as
+// JSON, or "" when it has no routing policy and is no alias.
+func recordSetComment(rrset ResourceRecordSetData) (string, error) {
+
+	if rrset.AliasTarget == nil && rrset.SetIdentifier == nil && rrset.TrafficPolicyInstanceId == nil {
+		return "", nil
+	}
+
+	rrset.TTL = nil
+	data, err := json.Marshal(rrset)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal record set: %w", err)
+	}
+
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return "", fmt.Errorf("failed to unmarshal record set: %w", err)
+	}
+	delete(attributes, "Name")
+	delete(attributes, "Type")
+	delete(attributes, "ResourceRecords")
+
+	data, err = json.Marshal(attributes)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal record set: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseAliasComments reads the alias record sets writeZoneFile wrote as comment lines.
+func parseAliasComments(r io.Reader) ([]ResourceRecordSetData, error) {
+
+	var result []ResourceRecordSetData
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+
+		line, found := strings.CutPrefix(strings.TrimSpace(scanner.Text()), route53Comment)
+		if !found {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid alias comment %q: %w", line, ErrInvalidInput)
+		}
+
+		var rrset ResourceRecordSetData
+		if err := json.Unmarshal([]byte(fields[2]), &rrset); err != nil || rrset.AliasTarget == nil {
+			return nil, fmt.Errorf("invalid alias comment %q: %w", line, ErrInvalidInput)
+		}
+		rrset.Name, rrset.Type = normalizeDnsName(fields[0]), awstypes.RRType(strings.ToUpper(fields[1]))
+
+		result = append(result, rrset)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read zone file: %w", err)
+	}
+	return result, nil
+}
+
+// compareZoneFileOrder puts the apex SOA and NS records first and
+// sorts the remaining record sets by name and type.
+func compareZoneFileOrder(zoneName string, a, b ResourceRecordSetData) int {
+
+	rank := func(rrset ResourceRecordSetData) int {
+		if normalizeDnsName(rrset.Name) != zoneName {
+			return 2
+		}
+		if rrset.Type == awstypes.RRTypeSoa {
+			return 0
+		}
+		if rrset.Type == awstypes.RRTypeNs {
+			return 1
+		}
+		return 2
+	}
+
+	if n := rank(a) - rank(b); n != 0 {
+		return n
+	}
+
+	if n := strings.Compare(normalizeDnsName(a.Name), normalizeDnsName(b.Name)); n != 0 {
+		return n
+	}
+
+	return strings.Compare(string(a.Type), string(b.Type))
+}
+
+func relativeOwner(zoneName string, name string) string {
+
+	owner := normalizeDnsName(name)
+	if owner == zoneName {
+		return "@"
+	}
+
+	return strings.TrimSuffix(owner, "."+zoneName)
+}
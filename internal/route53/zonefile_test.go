@@ -0,0 +1,79 @@
+package route53
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestZoneFileRoundTrip(t *testing.T) {
+	rrset := func(name string, rrtype awstypes.RRType, ttl int64, values ...string) ResourceRecordSetData {
+		result := ResourceRecordSetData{Name: name, Type: rrtype, TTL: aws.Int64(ttl)}
+		for _, value := range values {
+			result.ResourceRecords = append(result.ResourceRecords, awstypes.ResourceRecord{Value: aws.String(value)})
+		}
+		return result
+	}
+
+	heavy := rrset("w.example.com.", awstypes.RRTypeA, 60, "192.0.2.1", "192.0.2.2")
+	heavy.SetIdentifier, heavy.Weight, heavy.HealthCheckId = aws.String("heavy one"), aws.Int64(10), aws.String("hc-1")
+	light := rrset("w.example.com.", awstypes.RRTypeA, 60, "192.0.2.3")
+	light.SetIdentifier, light.Weight = aws.String("light"), aws.Int64(1)
+	primary := rrset("f.example.com.", awstypes.RRTypeCname, 30, "w.example.com.")
+	primary.SetIdentifier, primary.Failover = aws.String("primary"), awstypes.ResourceRecordSetFailoverPrimary
+	cidr := rrset("c.example.com.", awstypes.RRTypeA, 300, "192.0.2.4")
+	cidr.SetIdentifier = aws.String("lab")
+	cidr.CidrRoutingConfig = &awstypes.CidrRoutingConfig{CollectionId: aws.String("cc-1"), LocationName: aws.String("lab")}
+	alias := ResourceRecordSetData{Name: "www.example.com.", Type: awstypes.RRTypeA, AliasTarget: &awstypes.AliasTarget{
+		DNSName: aws.String("w.example.com."), HostedZoneId: aws.String("Z1"), EvaluateTargetHealth: true}}
+
+	hz := &HostedZoneData{Id: "/hostedzone/Z1", Name: "example.com."}
+	records := []ResourceRecordSetData{
+		rrset("example.com.", awstypes.RRTypeSoa, 900, "ns-1.example.com. admin.example.com. 1 3600 180 604800 1800"),
+		rrset("example.com.", awstypes.RRTypeNs, 172800, "ns-1.example.com.", "ns-2.example.com."),
+		rrset("example.com.", awstypes.RRTypeMx, 3600, "10 mail.example.com."),
+		rrset("mail.example.com.", awstypes.RRTypeA, 120, "192.0.2.25"),
+		rrset("mail.example.com.", awstypes.RRTypeTxt, 300, `"v=spf1 -all"`),
+		heavy, light, primary, cidr, alias,
+	}
+
+	var buf bytes.Buffer
+	if err := writeZoneFile(&buf, hz, slices.Clone(records)); err != nil {
+		t.Fatal(err)
+	}
+
+	name, parsed, err := parseZoneFile(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != hz.Name {
+		t.Errorf("zone name = %s, want %s", name, hz.Name)
+	}
+
+	order := func(a, b ResourceRecordSetData) int {
+		if n := compareZoneFileOrder(hz.Name, a, b); n != 0 {
+			return n
+		}
+		return strings.Compare(aws.ToString(a.SetIdentifier), aws.ToString(b.SetIdentifier))
+	}
+	slices.SortFunc(records, order)
+	slices.SortFunc(parsed, order)
+
+	if !reflect.DeepEqual(parsed, records) {
+		t.Errorf("parsed record sets differ\ngot  %+v\nwant %+v", parsed, records)
+	}
+}
+
+func TestParseZoneFileTtls(t *testing.T) {
+	file := "$ORIGIN example.com.\nwww 300 IN A 192.0.2.1\nwww 60 IN A 192.0.2.2\n"
+
+	if _, _, err := parseZoneFile(strings.NewReader(file), "example.com."); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("parseZoneFile() error = %v, want %v", err, ErrInvalidInput)
+	}
+}